@@ -0,0 +1,57 @@
+package ast
+
+import (
+	"bytes"
+	"monkey/token"
+)
+
+/*** Type Annotation ***/
+
+// Declared type of a let, function parameter or function result. The kind of type is told by the first token:
+// an Identifier names a type such as int, [ starts an array type [T], { a hash type {K: V}
+// and fn a function type fn(A, B) -> R whose result is optional
+type TypeAnnotation struct {
+	Token  token.Token
+	Name   string            // Named types
+	Elem   *TypeAnnotation   // Array types
+	Key    *TypeAnnotation   // Hash types
+	Value  *TypeAnnotation   // Hash types
+	Params []*TypeAnnotation // Function types
+	Result *TypeAnnotation   // Function types, nil when not given
+}
+
+// Named types an annotation may use
+var TypeNames = []string{"int", "bool", "string", "null", "any"}
+
+func (ta *TypeAnnotation) TokenLiteral() string { return ta.Token.Literal }
+func (ta *TypeAnnotation) Pos() token.Position  { return ta.Token.Position }
+func (ta *TypeAnnotation) String() string {
+	var out bytes.Buffer
+
+	switch ta.Token.Type {
+	case token.LBRACKET:
+		out.WriteString("[" + ta.Elem.String() + "]")
+
+	case token.LBRACE:
+		out.WriteString("{" + ta.Key.String() + ": " + ta.Value.String() + "}")
+
+	case token.FUNCTION:
+		out.WriteString("fn(")
+		for idx, param := range ta.Params {
+			if idx > 0 {
+				out.WriteString(", ")
+			}
+			out.WriteString(param.String())
+		}
+		out.WriteString(")")
+
+		if ta.Result != nil {
+			out.WriteString(" -> " + ta.Result.String())
+		}
+
+	default:
+		out.WriteString(ta.Name)
+	}
+
+	return out.String()
+}
@@ -1,10 +1,14 @@
 package ast
 
-import "bytes"
+import (
+	"bytes"
+	"monkey/token"
+)
 
 type Node interface {
 	String() string
 	TokenLiteral() string
+	Pos() token.Position // Position of the node's token in the source
 }
 
 // Interface to distinguish the Expression/Statement types
@@ -23,6 +27,7 @@ type Expression interface {
 // Each statement must properly nest any Expressions to capture the semantics of the source code
 type Program struct {
 	Statements []Statement
+	Comments   []*Comment // Comments of the source in order, they do not affect evaluation
 }
 
 func (p *Program) TokenLiteral() string {
@@ -33,14 +38,31 @@ func (p *Program) TokenLiteral() string {
 	return p.Statements[0].TokenLiteral()
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) == 0 {
+		return token.Position{}
+	}
+
+	return p.Statements[0].Pos()
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
+	// Each statement is terminated, so the output parses back into the same statements
 	for _, stmt := range p.Statements {
 		out.WriteString(stmt.String())
-		out.WriteString("\n")
-		out.WriteString("\n")
+		out.WriteString(";\n")
 	}
 
 	return out.String()
 }
+
+// Line comment in the source, kept beside the tree rather than in it
+type Comment struct {
+	Token    token.Token // COMMENT token, its Literal is the text including the leading //
+	Trailing bool        // Follows code on the same line, rather than standing on a line of its own
+}
+
+func (c *Comment) Text() string        { return c.Token.Literal }
+func (c *Comment) Pos() token.Position { return c.Token.Position }
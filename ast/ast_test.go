@@ -0,0 +1,115 @@
+package ast_test
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"testing"
+)
+
+// Sources covering every node type
+var corpus = []string{
+	`let x = 5; x`,
+	`return 1 + 2 * 3`,
+	`throw "bad"`,
+	`-a * b; !true == false; a - (b - c); (a - b) - c`,
+	`f(1, "two", [3, 4])(5)`,
+	`fn(x) { x }(1); fn() {}; fn(a, b) { let c = a + b; return c }`,
+	`arr[1 + 2][0]; (-a)[0]; -a[0]`,
+	`regex.match("a", "b"); h.key.inner`,
+	`{"a": 1, 2: fn(x) { x }, true: [1]}; {}`,
+	`if (x < y) { x } else { y }; if (x) { a; b }; if (x) {}`,
+	`try { risky() } catch (e) { e.message } finally { cleanup() }; try { 1 } finally { 2 }`,
+	`let f = fn(n) { if (n == 0) { 1 } else { n * f(n - 1) } }; f(5)`,
+	`let x: int = 1; let f = fn(a: string, b: [int], c: {string: fn(int) -> bool}) -> fn() { fn() { a } }`,
+}
+
+func parse(t testing.TB, src string) (*ast.Program, []string) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+
+	return program, p.Errors()
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let x = 5`, "let x = 5;\n"},
+		{`"str"`, "\"str\";\n"},
+		{`[1, 2]`, "[1, 2];\n"},
+		{`{"b": 1, "a": 2}`, "{\"b\": 1, \"a\": 2};\n"},
+		{`a.b[c]`, "((a.b)[c]);\n"},
+		{`-a * b`, "((-a) * b);\n"},
+		{`add(1, mul(2, 3))`, "add(1, mul(2, 3));\n"},
+		{`if (a) { b; c } else { d }`, "if (a) { b; c } else { d };\n"},
+		{`fn(x, y) { return x }`, "fn(x, y) { return x };\n"},
+		{`fn() {}`, "fn() {};\n"},
+		{`try { a } catch (e) { b } finally { c }`, "try { a } catch (e) { b } finally { c };\n"},
+		{`throw 1; x`, "throw 1;\nx;\n"},
+	}
+
+	for _, tt := range tests {
+		program, errs := parse(t, tt.input)
+		if len(errs) > 0 {
+			t.Fatalf("Parser errors for %q: %v", tt.input, errs)
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("Wrong String() of %q.\nGot=%q\nexpected=%q", tt.input, program.String(), tt.expected)
+		}
+	}
+}
+
+// Nodes which the parser never produces still print as source
+func TestStringOfRewrittenNodes(t *testing.T) {
+	infix := &ast.InfixExpression{
+		Token:    token.Token{Type: token.ASTERISK, Literal: "*"},
+		Left:     &ast.IntLiteral{Value: -4},
+		Operator: "*",
+		Right:    &ast.IntLiteral{Value: 2},
+	}
+
+	if infix.String() != "((-4) * 2)" {
+		t.Errorf("Wrong String(). Got=%q", infix.String())
+	}
+
+	assertRoundTrip(t, infix.String())
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	for _, src := range corpus {
+		assertRoundTrip(t, src)
+	}
+}
+
+func FuzzStringRoundTrip(f *testing.F) {
+	for _, src := range corpus {
+		f.Add(src)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		if _, errs := parse(t, src); len(errs) > 0 {
+			t.Skip()
+		}
+
+		assertRoundTrip(t, src)
+	})
+}
+
+// The String() of a parsed program parses back into a program with the same String()
+func assertRoundTrip(t testing.TB, src string) {
+	program, _ := parse(t, src)
+	printed := program.String()
+
+	reparsed, errs := parse(t, printed)
+	if len(errs) > 0 {
+		t.Fatalf("String() of %q does not parse.\nString()=%q\nerrors=%v", src, printed, errs)
+	}
+
+	if reparsed.String() != printed {
+		t.Fatalf("String() of %q changed after parsing it.\nGot=%q\nexpected=%q", src, reparsed.String(), printed)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"monkey/token"
+	"sort"
 )
 
 /*** Integer Literal ***/
@@ -14,8 +15,16 @@ type IntLiteral struct {
 }
 
 func (i *IntLiteral) expression()          {}
-func (i *IntLiteral) String() string       { return fmt.Sprintf("%d", i.Value) }
 func (i *IntLiteral) TokenLiteral() string { return i.Token.Literal }
+func (i *IntLiteral) Pos() token.Position  { return i.Token.Position }
+func (i *IntLiteral) String() string {
+	// Negative values only come from rewritten trees, the source spells them as a prefix expression
+	if i.Value < 0 {
+		return fmt.Sprintf("(%d)", i.Value)
+	}
+
+	return fmt.Sprintf("%d", i.Value)
+}
 
 /*** Boolean Literal ***/
 
@@ -27,6 +36,7 @@ type BoolLiteral struct {
 func (b *BoolLiteral) expression()          {}
 func (b *BoolLiteral) String() string       { return fmt.Sprintf("%v", b.Value) }
 func (b *BoolLiteral) TokenLiteral() string { return b.Token.Literal }
+func (b *BoolLiteral) Pos() token.Position  { return b.Token.Position }
 
 /*** String Literal ***/
 
@@ -36,26 +46,26 @@ type StringLiteral struct {
 }
 
 func (s *StringLiteral) expression()          {}
-func (s *StringLiteral) String() string       { return s.Value }
+func (s *StringLiteral) String() string       { return `"` + s.Value + `"` }
 func (s *StringLiteral) TokenLiteral() string { return s.Token.Literal }
+func (s *StringLiteral) Pos() token.Position  { return s.Token.Position }
 
 /*** Array Literal ***/
 
 type ArrayLiteral struct {
 	Token    token.Token // [
 	Elements []Expression
+	Rbracket token.Position // Position of the closing ]
 }
 
 func (a *ArrayLiteral) expression()          {}
 func (a *ArrayLiteral) TokenLiteral() string { return a.Token.Literal }
+func (a *ArrayLiteral) Pos() token.Position  { return a.Token.Position }
 func (a *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("[")
-	for _, elem := range a.Elements {
-		out.WriteString(elem.String())
-		out.WriteString(",")
-	}
+	writeList(&out, a.Elements)
 	out.WriteString("]")
 
 	return out.String()
@@ -64,18 +74,46 @@ func (a *ArrayLiteral) String() string {
 /*** Hash Literal ***/
 
 type HashLiteral struct {
-	Token token.Token // {
-	Pairs map[Expression]Expression
+	Token  token.Token // {
+	Pairs  map[Expression]Expression
+	Rbrace token.Position // Position of the closing }
 }
 
 func (h *HashLiteral) expression()          {}
 func (h *HashLiteral) TokenLiteral() string { return h.Token.Literal }
+func (h *HashLiteral) Pos() token.Position  { return h.Token.Position }
+
+// Keys of the pairs in source order, or ordered by their text for keys without positions
+func (h *HashLiteral) Keys() []Expression {
+	keys := make([]Expression, 0, len(h.Pairs))
+	for key := range h.Pairs {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		left, right := keys[i].Pos(), keys[j].Pos()
+		if left.Offset != right.Offset {
+			return left.Offset < right.Offset
+		}
+
+		return keys[i].String() < keys[j].String()
+	})
+
+	return keys
+}
+
 func (h *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("{")
-	for key, val := range h.Pairs {
-		out.WriteString(fmt.Sprintf("   %s: %s,\n", key.String(), val.String()))
+	for idx, key := range h.Keys() {
+		if idx > 0 {
+			out.WriteString(", ")
+		}
+
+		out.WriteString(key.String())
+		out.WriteString(": ")
+		out.WriteString(h.Pairs[key].String())
 	}
 	out.WriteString("}")
 
@@ -87,27 +125,52 @@ func (h *HashLiteral) String() string {
 type Identifier struct {
 	Token token.Token
 	Value string
+	Addr  *Address        // Local variable the identifier refers to, nil for globals and builtins which are looked up by name
+	Type  *TypeAnnotation // Annotation of a function parameter, as in fn(x: int)
+}
+
+// Location of a local variable, assigned by the resolver.
+// Depth counts the frames (function calls and catch blocks) to walk outwards from the current one
+type Address struct {
+	Depth int
+	Slot  int
 }
 
 func (i *Identifier) expression()          {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
-func (i *Identifier) String() string       { return i.Value }
+func (i *Identifier) Pos() token.Position  { return i.Token.Position }
+func (i *Identifier) String() string {
+	if i.Type != nil {
+		return i.Value + ": " + i.Type.String()
+	}
+
+	return i.Value
+}
 
 /*** Index Expression ***/
 
 type IndexExpression struct {
-	Token token.Token //[
-	Left  Expression  // Array or Hash
+	Token token.Token // [ or . for member access
+	Left  Expression  // Array, Hash or Module
 	Index Expression
 }
 
 func (i *IndexExpression) expression()          {}
 func (i *IndexExpression) TokenLiteral() string { return i.Token.Literal }
+func (i *IndexExpression) Pos() token.Position  { return i.Token.Position }
 func (i *IndexExpression) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("(")
 	out.WriteString(i.Left.String())
+
+	if member, ok := i.Index.(*StringLiteral); ok && i.Token.Type == token.DOT {
+		out.WriteString(".")
+		out.WriteString(member.Value)
+		out.WriteString(")")
+		return out.String()
+	}
+
 	out.WriteString("[")
 	out.WriteString(i.Index.String())
 	out.WriteString("])")
@@ -127,6 +190,7 @@ func (pe *PrefixExpression) expression() {}
 func (pe *PrefixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Position }
 
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
@@ -151,6 +215,7 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expression()          {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() token.Position  { return ie.Token.Position }
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -176,33 +241,74 @@ type IfExpression struct {
 
 func (i *IfExpression) expression()          {}
 func (i *IfExpression) TokenLiteral() string { return i.Token.Literal }
+func (i *IfExpression) Pos() token.Position  { return i.Token.Position }
 func (i *IfExpression) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("if(")
+	out.WriteString("if (")
 	out.WriteString(i.Condition.String())
-	out.WriteString(")")
+	out.WriteString(") ")
 	out.WriteString(i.Consequence.String())
 
 	// else block is optional
 	if i.Alternative != nil {
-		out.WriteString("else")
+		out.WriteString(" else ")
 		out.WriteString(i.Alternative.String())
 	}
 
 	return out.String()
 }
 
+/*** Try Expression ***/
+
+// try is an expression, producing the value of its block or of the catch block when an error was caught
+type TryExpression struct {
+	Token   token.Token
+	Block   *BlockStatement
+	Param   *Identifier     // Bound to the caught error in the catch block
+	Catch   *BlockStatement // Optional when there is a finally block
+	Finally *BlockStatement // Always executed, its value is discarded
+
+	CatchLocals []string // Names of Param and the variables declared in Catch by slot, assigned by the resolver
+}
+
+func (t *TryExpression) expression()          {}
+func (t *TryExpression) TokenLiteral() string { return t.Token.Literal }
+func (t *TryExpression) Pos() token.Position  { return t.Token.Position }
+func (t *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(t.Block.String())
+
+	if t.Catch != nil {
+		out.WriteString(" catch (")
+		out.WriteString(t.Param.String())
+		out.WriteString(") ")
+		out.WriteString(t.Catch.String())
+	}
+
+	if t.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(t.Finally.String())
+	}
+
+	return out.String()
+}
+
 /*** Function Literal ***/
 
 type FnLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
+	Result     *TypeAnnotation // Optional annotation of the result, as in fn(x) -> int
 	Body       *BlockStatement
+	Locals     []string // Names of the parameters and variables declared in Body by slot, assigned by the resolver
 }
 
 func (fl *FnLiteral) expression()          {}
 func (fl *FnLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FnLiteral) Pos() token.Position  { return fl.Token.Position }
 func (fl *FnLiteral) String() string {
 	var out bytes.Buffer
 
@@ -214,7 +320,10 @@ func (fl *FnLiteral) String() string {
 		}
 	}
 
-	out.WriteString(")")
+	out.WriteString(") ")
+	if fl.Result != nil {
+		out.WriteString("-> " + fl.Result.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
@@ -223,23 +332,33 @@ func (fl *FnLiteral) String() string {
 /*** Call Expression ***/
 
 type CallExpression struct {
-	Token token.Token // ( token
-	Fn    Expression  // Identifier or FnLiteral
-	Args  []Expression
+	Token  token.Token // ( token
+	Fn     Expression  // Identifier or FnLiteral
+	Args   []Expression
+	Rparen token.Position // Position of the closing )
 }
 
 func (ce *CallExpression) expression()          {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return ce.Token.Position }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("fn")
+	out.WriteString(ce.Fn.String())
 	out.WriteString("(")
-	for _, arg := range ce.Args {
-		out.WriteString(arg.String())
-		out.WriteString(",")
-	}
+	writeList(&out, ce.Args)
 	out.WriteString(")")
 
 	return out.String()
 }
+
+// Write the expressions separated by commas
+func writeList(out *bytes.Buffer, exprs []Expression) {
+	for idx, expr := range exprs {
+		if idx > 0 {
+			out.WriteString(", ")
+		}
+
+		out.WriteString(expr.String())
+	}
+}
@@ -0,0 +1,682 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"monkey/lexer"
+	"monkey/token"
+	"reflect"
+	"slices"
+)
+
+// JSON form of a node. Every node has its kind (the Go type name) and position,
+// the other fields are set depending on the kind
+type jsonNode struct {
+	Kind string        `json:"kind"`
+	Pos  *jsonPosition `json:"pos,omitempty"`
+
+	Name     *jsonNode       `json:"name,omitempty"`
+	Type     *jsonType       `json:"type,omitempty"`  // Annotation of a let or parameter
+	Value    json.RawMessage `json:"value,omitempty"` // Literal value, identifier name, or child of a statement
+	Operator string          `json:"operator,omitempty"`
+
+	Left      *jsonNode `json:"left,omitempty"`
+	Right     *jsonNode `json:"right,omitempty"`
+	Operand   *jsonNode `json:"operand,omitempty"`
+	Index     *jsonNode `json:"index,omitempty"`
+	Condition *jsonNode `json:"condition,omitempty"`
+	Function  *jsonNode `json:"function,omitempty"`
+
+	Elements   []*jsonNode `json:"elements,omitempty"`
+	Pairs      []jsonPair  `json:"pairs,omitempty"`
+	Parameters []*jsonNode `json:"parameters,omitempty"`
+	Arguments  []*jsonNode `json:"arguments,omitempty"`
+	Statements []*jsonNode `json:"statements,omitempty"`
+
+	Consequence *jsonNode `json:"consequence,omitempty"`
+	Alternative *jsonNode `json:"alternative,omitempty"`
+	Block       *jsonNode `json:"block,omitempty"`
+	Param       *jsonNode `json:"param,omitempty"`
+	Catch       *jsonNode `json:"catch,omitempty"`
+	Finally     *jsonNode `json:"finally,omitempty"`
+	Body        *jsonNode `json:"body,omitempty"`
+	Result      *jsonType `json:"result,omitempty"`
+
+	End      *jsonPosition `json:"end,omitempty"` // Closing bracket of a block, array, hash or call
+	Comments []jsonComment `json:"comments,omitempty"`
+}
+
+type jsonPosition struct {
+	Filename string `json:"file,omitempty"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Offset   int    `json:"offset"`
+}
+
+// JSON form of a type annotation, whose kind is "name", "array", "hash" or "fn"
+type jsonType struct {
+	Kind   string        `json:"kind"`
+	Pos    *jsonPosition `json:"pos,omitempty"`
+	Name   string        `json:"name,omitempty"`
+	Elem   *jsonType     `json:"elem,omitempty"`
+	Key    *jsonType     `json:"key,omitempty"`
+	Value  *jsonType     `json:"value,omitempty"`
+	Params []*jsonType   `json:"params,omitempty"`
+	Result *jsonType     `json:"result,omitempty"`
+}
+
+type jsonPair struct {
+	Key   *jsonNode `json:"key"`
+	Value *jsonNode `json:"value"`
+}
+
+type jsonComment struct {
+	Text     string        `json:"text"`
+	Pos      *jsonPosition `json:"pos,omitempty"`
+	Trailing bool          `json:"trailing,omitempty"`
+}
+
+// Operators the parser accepts before an operand and between two
+var (
+	prefixOperators = map[token.TokenType]bool{token.BANG: true, token.MINUS: true}
+	infixOperators  = map[token.TokenType]bool{
+		token.PLUS: true, token.MINUS: true, token.ASTERISK: true, token.SLASH: true,
+		token.LT: true, token.GT: true, token.EQUALITY: true, token.NOTEQUAL: true,
+	}
+)
+
+// Encode a node, and everything below it, as JSON
+func MarshalJSON(node Node) ([]byte, error) {
+	j, err := toJSON(node)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(j)
+}
+
+// Decode a node encoded by MarshalJSON.
+// The tree is rebuilt with the tokens the parser would have produced, so it evaluates like a parsed one
+func UnmarshalJSON(data []byte) (Node, error) {
+	var j jsonNode
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+
+	return fromJSON(&j)
+}
+
+/*** Encoding ***/
+
+func toJSON(astNode Node) (*jsonNode, error) {
+	if isNil(astNode) {
+		return nil, fmt.Errorf("Unable to encode a missing node")
+	}
+
+	j := &jsonNode{Pos: toJSONPosition(astNode.Pos())}
+
+	var err error
+	child := func(node Node) *jsonNode {
+		if err != nil || isNil(node) {
+			return nil
+		}
+
+		var c *jsonNode
+		c, err = toJSON(node)
+		return c
+	}
+
+	value := func(val any) json.RawMessage {
+		if err != nil {
+			return nil
+		}
+
+		var raw json.RawMessage
+		raw, err = json.Marshal(val)
+		return raw
+	}
+
+	switch node := astNode.(type) {
+	case *Program:
+		j.Kind = "Program"
+		j.Statements = toJSONStatements(node.Statements, child)
+		for _, comment := range node.Comments {
+			j.Comments = append(j.Comments, jsonComment{Text: comment.Text(), Pos: toJSONPosition(comment.Pos()), Trailing: comment.Trailing})
+		}
+
+	case *BlockStatement:
+		j.Kind = "BlockStatement"
+		j.Statements = toJSONStatements(node.Statements, child)
+		j.End = toJSONPosition(node.Rbrace)
+
+	case *LetStatement:
+		j.Kind = "LetStatement"
+		j.Name = child(node.Name)
+		j.Type = toJSONType(node.Type)
+		j.Value = value(child(node.Value))
+
+	case *ReturnStatement:
+		j.Kind = "ReturnStatement"
+		j.Value = value(child(node.Value))
+
+	case *ThrowStatement:
+		j.Kind = "ThrowStatement"
+		j.Value = value(child(node.Value))
+
+	case *ExpressionStatement:
+		j.Kind = "ExpressionStatement"
+		j.Value = value(child(node.Expr))
+
+	case *Identifier:
+		j.Kind = "Identifier"
+		j.Value = value(node.Value)
+		j.Type = toJSONType(node.Type)
+
+	case *IntLiteral:
+		j.Kind = "IntLiteral"
+		j.Value = value(node.Value)
+
+	case *StringLiteral:
+		j.Kind = "StringLiteral"
+		j.Value = value(node.Value)
+
+	case *BoolLiteral:
+		j.Kind = "BoolLiteral"
+		j.Value = value(node.Value)
+
+	case *ArrayLiteral:
+		j.Kind = "ArrayLiteral"
+		j.Elements = toJSONExpressions(node.Elements, child)
+		j.End = toJSONPosition(node.Rbracket)
+
+	case *HashLiteral:
+		j.Kind = "HashLiteral"
+		for _, key := range node.Keys() {
+			j.Pairs = append(j.Pairs, jsonPair{Key: child(key), Value: child(node.Pairs[key])})
+		}
+		j.End = toJSONPosition(node.Rbrace)
+
+	case *IndexExpression:
+		j.Kind = "IndexExpression"
+		j.Left = child(node.Left)
+		j.Index = child(node.Index)
+		if node.Token.Type == token.DOT {
+			j.Operator = "."
+		}
+
+	case *PrefixExpression:
+		j.Kind = "PrefixExpression"
+		j.Operator = node.Operator
+		j.Operand = child(node.Operand)
+
+	case *InfixExpression:
+		j.Kind = "InfixExpression"
+		j.Operator = node.Operator
+		j.Left = child(node.Left)
+		j.Right = child(node.Right)
+
+	case *IfExpression:
+		j.Kind = "IfExpression"
+		j.Condition = child(node.Condition)
+		j.Consequence = child(node.Consequence)
+		j.Alternative = child(node.Alternative)
+
+	case *TryExpression:
+		j.Kind = "TryExpression"
+		j.Block = child(node.Block)
+		if node.Catch != nil {
+			j.Param = child(node.Param)
+			j.Catch = child(node.Catch)
+		}
+		j.Finally = child(node.Finally)
+
+	case *FnLiteral:
+		j.Kind = "FnLiteral"
+		j.Parameters = []*jsonNode{}
+		for _, param := range node.Parameters {
+			j.Parameters = append(j.Parameters, child(param))
+		}
+		j.Result = toJSONType(node.Result)
+		j.Body = child(node.Body)
+
+	case *CallExpression:
+		j.Kind = "CallExpression"
+		j.Function = child(node.Fn)
+		j.Arguments = toJSONExpressions(node.Args, child)
+		j.End = toJSONPosition(node.Rparen)
+
+	default:
+		return nil, fmt.Errorf("Unable to encode node of type %T", astNode)
+	}
+
+	return j, err
+}
+
+func toJSONStatements(stmts []Statement, child func(Node) *jsonNode) []*jsonNode {
+	nodes := []*jsonNode{}
+	for _, stmt := range stmts {
+		nodes = append(nodes, child(stmt))
+	}
+
+	return nodes
+}
+
+func toJSONExpressions(exprs []Expression, child func(Node) *jsonNode) []*jsonNode {
+	nodes := []*jsonNode{}
+	for _, expr := range exprs {
+		nodes = append(nodes, child(expr))
+	}
+
+	return nodes
+}
+
+func toJSONPosition(pos token.Position) *jsonPosition {
+	if !pos.IsValid() {
+		return nil
+	}
+
+	return &jsonPosition{Filename: pos.Filename, Line: pos.Line, Column: pos.Column, Offset: pos.Offset}
+}
+
+func toJSONType(ta *TypeAnnotation) *jsonType {
+	if ta == nil {
+		return nil
+	}
+
+	j := &jsonType{Pos: toJSONPosition(ta.Pos())}
+	switch ta.Token.Type {
+	case token.LBRACKET:
+		j.Kind = "array"
+		j.Elem = toJSONType(ta.Elem)
+
+	case token.LBRACE:
+		j.Kind = "hash"
+		j.Key, j.Value = toJSONType(ta.Key), toJSONType(ta.Value)
+
+	case token.FUNCTION:
+		j.Kind = "fn"
+		j.Params = []*jsonType{}
+		for _, param := range ta.Params {
+			j.Params = append(j.Params, toJSONType(param))
+		}
+		j.Result = toJSONType(ta.Result)
+
+	default:
+		j.Kind = "name"
+		j.Name = ta.Name
+	}
+
+	return j
+}
+
+// Missing nodes, including interfaces holding a nil pointer as left behind in trees of programs with parser errors
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+
+	val := reflect.ValueOf(node)
+	return val.Kind() == reflect.Pointer && val.IsNil()
+}
+
+/*** Decoding ***/
+
+func fromJSON(j *jsonNode) (Node, error) {
+	if j == nil {
+		return nil, fmt.Errorf("Missing node")
+	}
+
+	pos := fromJSONPosition(j.Pos)
+	tok := func(tokType token.TokenType, literal string) token.Token {
+		return token.Token{Type: tokType, Literal: literal, Position: pos}
+	}
+
+	d := &decoder{kind: j.Kind}
+
+	switch j.Kind {
+	case "Program":
+		program := &Program{Statements: d.statements(j.Statements)}
+		for _, comment := range j.Comments {
+			program.Comments = append(program.Comments, &Comment{
+				Token:    token.Token{Type: token.COMMENT, Literal: comment.Text, Position: fromJSONPosition(comment.Pos)},
+				Trailing: comment.Trailing,
+			})
+		}
+		return program, d.err
+
+	case "BlockStatement":
+		block := d.blockStatement(j)
+		return block, d.err
+
+	case "LetStatement":
+		let := &LetStatement{Token: tok(token.LET, "let"), Name: d.identifier("name", j.Name), Type: d.typeAnnotation(j.Type), Value: d.child("value", j.Value)}
+		return let, d.err
+
+	case "ReturnStatement":
+		ret := &ReturnStatement{Token: tok(token.RETURN, "return"), Value: d.child("value", j.Value)}
+		return ret, d.err
+
+	case "ThrowStatement":
+		throw := &ThrowStatement{Token: tok(token.THROW, "throw"), Value: d.child("value", j.Value)}
+		return throw, d.err
+
+	case "ExpressionStatement":
+		// The token of the statement is the first of its expression, only its position is kept
+		stmt := &ExpressionStatement{Token: token.Token{Position: pos}, Expr: d.child("value", j.Value)}
+		return stmt, d.err
+
+	case "Identifier":
+		var name string
+		d.value(j.Value, &name)
+		if d.err == nil && lexeme(name).Type != token.IDENTIFIER {
+			d.fail("has invalid name %q", name)
+		}
+		return &Identifier{Token: tok(token.IDENTIFIER, name), Value: name, Type: d.typeAnnotation(j.Type)}, d.err
+
+	case "IntLiteral":
+		var val int64
+		d.value(j.Value, &val)
+		return &IntLiteral{Token: tok(token.NUMBER, fmt.Sprintf("%d", val)), Value: val}, d.err
+
+	case "StringLiteral":
+		var val string
+		d.value(j.Value, &val)
+		return &StringLiteral{Token: tok(token.STRING, val), Value: val}, d.err
+
+	case "BoolLiteral":
+		var val bool
+		d.value(j.Value, &val)
+
+		tokType := token.TokenType(token.FALSE)
+		if val {
+			tokType = token.TRUE
+		}
+		return &BoolLiteral{Token: tok(tokType, fmt.Sprintf("%t", val)), Value: val}, d.err
+
+	case "ArrayLiteral":
+		return &ArrayLiteral{Token: tok(token.LBRACKET, "["), Elements: d.expressions("elements", j.Elements), Rbracket: fromJSONPosition(j.End)}, d.err
+
+	case "HashLiteral":
+		hash := &HashLiteral{Token: tok(token.LBRACE, "{"), Pairs: map[Expression]Expression{}, Rbrace: fromJSONPosition(j.End)}
+		for _, pair := range j.Pairs {
+			key, val := d.expression("key", pair.Key), d.expression("value", pair.Value)
+			if key != nil {
+				hash.Pairs[key] = val
+			}
+		}
+		return hash, d.err
+
+	case "IndexExpression":
+		index := &IndexExpression{Token: tok(token.LBRACKET, "["), Left: d.expression("left", j.Left), Index: d.expression("index", j.Index)}
+		if j.Operator == "." {
+			index.Token = tok(token.DOT, ".")
+
+			// A member is written as the name it holds
+			if member, ok := index.Index.(*StringLiteral); d.err == nil && (!ok || lexeme(member.Value).Type != token.IDENTIFIER) {
+				d.fail("requires a member name as index, Got=%s", index.Index)
+			}
+		}
+		return index, d.err
+
+	case "PrefixExpression":
+		prefix := &PrefixExpression{Token: tok(d.operator(j.Operator, prefixOperators), j.Operator), Operator: j.Operator, Operand: d.expression("operand", j.Operand)}
+		return prefix, d.err
+
+	case "InfixExpression":
+		infix := &InfixExpression{
+			Token:    tok(d.operator(j.Operator, infixOperators), j.Operator),
+			Operator: j.Operator,
+			Left:     d.expression("left", j.Left),
+			Right:    d.expression("right", j.Right),
+		}
+		return infix, d.err
+
+	case "IfExpression":
+		ifExpr := &IfExpression{Token: tok(token.IF, "if"), Condition: d.expression("condition", j.Condition), Consequence: d.block("consequence", j.Consequence)}
+		if j.Alternative != nil {
+			ifExpr.Alternative = d.block("alternative", j.Alternative)
+		}
+		return ifExpr, d.err
+
+	case "TryExpression":
+		tryExpr := &TryExpression{Token: tok(token.TRY, "try"), Block: d.block("block", j.Block)}
+		if j.Catch != nil {
+			tryExpr.Param = d.identifier("param", j.Param)
+			tryExpr.Catch = d.block("catch", j.Catch)
+		}
+		if j.Finally != nil {
+			tryExpr.Finally = d.block("finally", j.Finally)
+		}
+		if tryExpr.Catch == nil && tryExpr.Finally == nil {
+			d.fail("requires a catch or finally block")
+		}
+		return tryExpr, d.err
+
+	case "FnLiteral":
+		fn := &FnLiteral{Token: tok(token.FUNCTION, "fn"), Parameters: []*Identifier{}, Result: d.typeAnnotation(j.Result), Body: d.block("body", j.Body)}
+		for _, param := range j.Parameters {
+			fn.Parameters = append(fn.Parameters, d.identifier("parameters", param))
+		}
+		return fn, d.err
+
+	case "CallExpression":
+		call := &CallExpression{Token: tok(token.LPAREN, "("), Fn: d.expression("function", j.Function), Args: d.expressions("arguments", j.Arguments), Rparen: fromJSONPosition(j.End)}
+		return call, d.err
+
+	default:
+		return nil, fmt.Errorf("Unknown node kind %q", j.Kind)
+	}
+}
+
+// Decodes the children of a node, keeping the first error
+type decoder struct {
+	kind string
+	err  error
+}
+
+func (d *decoder) fail(format string, args ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%s %s", d.kind, fmt.Sprintf(format, args...))
+	}
+}
+
+func (d *decoder) node(field string, j *jsonNode) Node {
+	if d.err != nil {
+		return nil
+	}
+
+	if j == nil {
+		d.fail("is missing %s", field)
+		return nil
+	}
+
+	node, err := fromJSON(j)
+	if err != nil {
+		d.err = err
+		return nil
+	}
+
+	return node
+}
+
+func (d *decoder) expression(field string, j *jsonNode) Expression {
+	node := d.node(field, j)
+	if node == nil {
+		return nil
+	}
+
+	expr, ok := node.(Expression)
+	if !ok {
+		d.fail("requires an expression as %s, Got=%s", field, j.Kind)
+	}
+
+	return expr
+}
+
+func (d *decoder) expressions(field string, js []*jsonNode) []Expression {
+	exprs := []Expression{}
+	for _, j := range js {
+		exprs = append(exprs, d.expression(field, j))
+	}
+
+	return exprs
+}
+
+func (d *decoder) statements(js []*jsonNode) []Statement {
+	stmts := []Statement{}
+	for _, j := range js {
+		node := d.node("statements", j)
+		if node == nil {
+			continue
+		}
+
+		stmt, ok := node.(Statement)
+		if !ok {
+			d.fail("requires statements, Got=%s", j.Kind)
+			continue
+		}
+		stmts = append(stmts, stmt)
+	}
+
+	return stmts
+}
+
+func (d *decoder) identifier(field string, j *jsonNode) *Identifier {
+	node := d.node(field, j)
+	if node == nil {
+		return nil
+	}
+
+	ident, ok := node.(*Identifier)
+	if !ok {
+		d.fail("requires an Identifier as %s, Got=%s", field, j.Kind)
+	}
+
+	return ident
+}
+
+func (d *decoder) block(field string, j *jsonNode) *BlockStatement {
+	node := d.node(field, j)
+	if node == nil {
+		return nil
+	}
+
+	block, ok := node.(*BlockStatement)
+	if !ok {
+		d.fail("requires a BlockStatement as %s, Got=%s", field, j.Kind)
+	}
+
+	return block
+}
+
+// Decode an optional type annotation
+func (d *decoder) typeAnnotation(j *jsonType) *TypeAnnotation {
+	if j == nil || d.err != nil {
+		return nil
+	}
+
+	pos := fromJSONPosition(j.Pos)
+	tok := func(tokType token.TokenType, literal string) token.Token {
+		return token.Token{Type: tokType, Literal: literal, Position: pos}
+	}
+
+	// Every part of a compound type is required, except the result of a function type
+	part := func(field string, j *jsonType) *TypeAnnotation {
+		if j == nil {
+			d.fail("is missing the %s of its type", field)
+			return nil
+		}
+		return d.typeAnnotation(j)
+	}
+
+	switch j.Kind {
+	case "name":
+		if !slices.Contains(TypeNames, j.Name) {
+			d.fail("has unknown type %q", j.Name)
+			return nil
+		}
+		return &TypeAnnotation{Token: tok(token.IDENTIFIER, j.Name), Name: j.Name}
+
+	case "array":
+		return &TypeAnnotation{Token: tok(token.LBRACKET, "["), Elem: part("elem", j.Elem)}
+
+	case "hash":
+		return &TypeAnnotation{Token: tok(token.LBRACE, "{"), Key: part("key", j.Key), Value: part("value", j.Value)}
+
+	case "fn":
+		ta := &TypeAnnotation{Token: tok(token.FUNCTION, "fn"), Params: []*TypeAnnotation{}, Result: d.typeAnnotation(j.Result)}
+		for _, param := range j.Params {
+			ta.Params = append(ta.Params, part("params", param))
+		}
+		return ta
+	}
+
+	d.fail("has unknown type kind %q", j.Kind)
+	return nil
+}
+
+func (d *decoder) blockStatement(j *jsonNode) *BlockStatement {
+	return &BlockStatement{
+		Token:      token.Token{Type: token.LBRACE, Literal: "{", Position: fromJSONPosition(j.Pos)},
+		Statements: d.statements(j.Statements),
+		Rbrace:     fromJSONPosition(j.End),
+	}
+}
+
+// The child of a statement, which is held in its value
+func (d *decoder) child(field string, raw json.RawMessage) Expression {
+	if d.err != nil {
+		return nil
+	}
+
+	var j *jsonNode
+	if len(raw) > 0 {
+		d.value(raw, &j)
+	}
+
+	return d.expression(field, j)
+}
+
+func (d *decoder) value(raw json.RawMessage, val any) {
+	if d.err != nil {
+		return
+	}
+
+	if len(raw) == 0 {
+		d.fail("is missing value")
+		return
+	}
+
+	if err := json.Unmarshal(raw, val); err != nil {
+		d.fail("has an invalid value: %s", err)
+	}
+}
+
+// Token of an operator, which must be one of allowed
+func (d *decoder) operator(operator string, allowed map[token.TokenType]bool) token.TokenType {
+	tok := lexeme(operator)
+	if !allowed[tok.Type] {
+		d.fail("has unknown operator %q", operator)
+	}
+
+	return tok.Type
+}
+
+// The token src consists of, an ILLEGAL token unless src is a single token as the lexer reads it
+func lexeme(src string) token.Token {
+	l := lexer.New(src)
+	tok := l.NextToken()
+	if tok.Literal != src || l.NextToken().Type != token.EOF || len(l.Comments()) > 0 {
+		return token.Token{Type: token.ILLEGAL, Literal: src}
+	}
+
+	return tok
+}
+
+func fromJSONPosition(pos *jsonPosition) token.Position {
+	if pos == nil {
+		return token.Position{}
+	}
+
+	return token.Position{Filename: pos.Filename, Line: pos.Line, Column: pos.Column, Offset: pos.Offset}
+}
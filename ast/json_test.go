@@ -0,0 +1,137 @@
+package ast_test
+
+import (
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/object"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, src := range append(corpus, "// comment\nlet a = 1; // trailing") {
+		program, errs := parse(t, src)
+		if len(errs) > 0 {
+			t.Fatalf("Parser errors for %q: %v", src, errs)
+		}
+
+		data, err := ast.MarshalJSON(program)
+		if err != nil {
+			t.Fatalf("MarshalJSON(%q) failed: %v", src, err)
+		}
+
+		node, err := ast.UnmarshalJSON(data)
+		if err != nil {
+			t.Fatalf("UnmarshalJSON of %q failed: %v\njson=%s", src, err, data)
+		}
+
+		decoded, ok := node.(*ast.Program)
+		if !ok {
+			t.Fatalf("Decoded node is not *ast.Program. Got=%T", node)
+		}
+
+		if decoded.String() != program.String() {
+			t.Errorf("Decoding changed %q.\nGot=%q\nexpected=%q", src, decoded.String(), program.String())
+		}
+
+		again, err := ast.MarshalJSON(decoded)
+		if err != nil || string(again) != string(data) {
+			t.Errorf("Encoding the decoded %q differs.\nGot=%s\nexpected=%s", src, again, data)
+		}
+	}
+}
+
+// Decoded programs evaluate to the same values and errors, at the same positions, as parsed ones
+func TestJSONEvaluation(t *testing.T) {
+	inputs := []string{
+		"let f = fn(n) { if (n == 0) { 1 } else { n * f(n - 1) } }; f(10)",
+		`let h = {"a": [1, 2, 3]}; h.a[1] + len(h["a"])`,
+		`try { throw {"code": 7} } catch (e) { e.value.code } finally { 0 }`,
+		"let add = fn(a, b) { a + b };\nadd(1, true)",
+		"let g = fn() { 1 / 0 };\n\ng()",
+	}
+
+	for _, input := range inputs {
+		program, _ := parse(t, input)
+		expected := evaluator.Eval(program, evaluator.NewEnvironment(evaluator.Config{}))
+
+		data, err := ast.MarshalJSON(program)
+		if err != nil {
+			t.Fatalf("MarshalJSON(%q) failed: %v", input, err)
+		}
+
+		decoded, err := ast.UnmarshalJSON(data)
+		if err != nil {
+			t.Fatalf("UnmarshalJSON of %q failed: %v", input, err)
+		}
+
+		evaluated := evaluator.Eval(decoded, evaluator.NewEnvironment(evaluator.Config{}))
+		if evaluated.Inspect() != expected.Inspect() {
+			t.Errorf("Decoded %q evaluated differently. Got=%s, expected=%s", input, evaluated.Inspect(), expected.Inspect())
+		}
+
+		if errObj, ok := expected.(*object.Error); ok {
+			if evaluated.(*object.Error).Trace() != errObj.Trace() {
+				t.Errorf("Decoded %q reported the error differently.\nGot=%s\nexpected=%s", input, evaluated.(*object.Error).Trace(), errObj.Trace())
+			}
+		}
+	}
+}
+
+// Trees generated by tools, without positions
+func TestUnmarshalGenerated(t *testing.T) {
+	data := `{"kind": "Program", "statements": [
+		{"kind": "LetStatement", "name": {"kind": "Identifier", "value": "sq"}, "value":
+			{"kind": "FnLiteral", "parameters": [{"kind": "Identifier", "value": "x"}], "body":
+				{"kind": "BlockStatement", "statements": [{"kind": "ExpressionStatement", "value":
+					{"kind": "InfixExpression", "operator": "*", "left": {"kind": "Identifier", "value": "x"}, "right": {"kind": "Identifier", "value": "x"}}}]}}},
+		{"kind": "ExpressionStatement", "value": {"kind": "CallExpression", "function": {"kind": "Identifier", "value": "sq"}, "arguments": [{"kind": "IntLiteral", "value": 7}]}}
+	]}`
+
+	node, err := ast.UnmarshalJSON([]byte(data))
+	if err != nil {
+		t.Fatalf("UnmarshalJSON failed: %v", err)
+	}
+
+	evaluated := evaluator.Eval(node, evaluator.NewEnvironment(evaluator.Config{}))
+	if evaluated.Inspect() != "49" {
+		t.Errorf("Wrong result. Got=%s, expected=49", evaluated.Inspect())
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind": "Loop"}`, `Unknown node kind "Loop"`},
+		{`{"kind": "LetStatement", "value": {"kind": "IntLiteral", "value": 1}}`, "LetStatement is missing name"},
+		{`{"kind": "ReturnStatement"}`, "ReturnStatement is missing value"},
+		{`{"kind": "InfixExpression", "operator": "%", "left": {"kind": "IntLiteral", "value": 1}, "right": {"kind": "IntLiteral", "value": 2}}`, `InfixExpression has unknown operator "%"`},
+		{`{"kind": "CallExpression", "function": {"kind": "ReturnStatement", "value": {"kind": "IntLiteral", "value": 1}}}`, "CallExpression requires an expression as function, Got=ReturnStatement"},
+		{`{"kind": "IntLiteral", "value": "one"}`, "IntLiteral has an invalid value"},
+		{`{"kind": "TryExpression", "block": {"kind": "BlockStatement"}}`, "TryExpression requires a catch or finally block"},
+		{`{"kind": "FnLiteral", "parameters": [{"kind": "IntLiteral", "value": 1}], "body": {"kind": "BlockStatement"}}`, "FnLiteral requires an Identifier as parameters, Got=IntLiteral"},
+		{`{"kind": "Identifier", "value": "x", "type": {"kind": "name", "name": "integer"}}`, `Identifier has unknown type "integer"`},
+		{`{"kind": "Identifier", "value": "x", "type": {"kind": "array"}}`, "Identifier is missing the elem of its type"},
+		{`{"kind": "Identifier", "value": "x", "type": {"kind": "tuple"}}`, `Identifier has unknown type kind "tuple"`},
+		{`{"kind": "Identifier", "value": ""}`, `Identifier has invalid name ""`},
+		{`{"kind": "Identifier", "value": "let"}`, `Identifier has invalid name "let"`},
+		{`{"kind": "Identifier", "value": "two words"}`, `Identifier has invalid name "two words"`},
+		{`{"kind": "Identifier", "value": "x1"}`, `Identifier has invalid name "x1"`},
+		{`{"kind": "LetStatement", "name": {"kind": "Identifier", "value": "true"}, "value": {"kind": "IntLiteral", "value": 1}}`, `Identifier has invalid name "true"`},
+		{`{"kind": "PrefixExpression", "operator": "+", "operand": {"kind": "IntLiteral", "value": 1}}`, `PrefixExpression has unknown operator "+"`},
+		{`{"kind": "PrefixExpression", "operator": "", "operand": {"kind": "IntLiteral", "value": 1}}`, `PrefixExpression has unknown operator ""`},
+		{`{"kind": "InfixExpression", "operator": "!", "left": {"kind": "IntLiteral", "value": 1}, "right": {"kind": "IntLiteral", "value": 2}}`, `InfixExpression has unknown operator "!"`},
+		{`{"kind": "InfixExpression", "operator": " +", "left": {"kind": "IntLiteral", "value": 1}, "right": {"kind": "IntLiteral", "value": 2}}`, `InfixExpression has unknown operator " +"`},
+		{`{"kind": "IndexExpression", "operator": ".", "left": {"kind": "Identifier", "value": "h"}, "index": {"kind": "StringLiteral", "value": "a b"}}`, `IndexExpression requires a member name as index, Got="a b"`},
+		{`{"kind": "IndexExpression", "operator": ".", "left": {"kind": "Identifier", "value": "h"}, "index": {"kind": "IntLiteral", "value": 1}}`, `IndexExpression requires a member name as index, Got=1`},
+	}
+
+	for _, tt := range tests {
+		_, err := ast.UnmarshalJSON([]byte(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("Wrong error for %s. Got=%v, expected=%q", tt.input, err, tt.expected)
+		}
+	}
+}
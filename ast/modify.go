@@ -0,0 +1,152 @@
+package ast
+
+import "fmt"
+
+// Rewrite the tree rooted at node bottom up: the children of each node are modified first,
+// then the node is replaced by the result of calling fn with it. Returns the replacement of node.
+//
+// A statement replaced with nil is removed from its list. Any other replacement must fit where
+// the node was: an Expression for an expression, a Statement for a statement,
+// a *BlockStatement for a block, an *Identifier for a name and a *TypeAnnotation for an annotation,
+// Modify panics otherwise
+func Modify(node Node, fn func(Node) Node) Node {
+	switch n := node.(type) {
+	case *Program:
+		n.Statements = modifyStatements(n.Statements, fn)
+
+	case *BlockStatement:
+		n.Statements = modifyStatements(n.Statements, fn)
+
+	case *LetStatement:
+		n.Name = modifyIdentifier(n.Name, fn)
+		n.Type = modifyType(n.Type, fn)
+		n.Value = modifyExpression(n.Value, fn)
+
+	case *ReturnStatement:
+		n.Value = modifyExpression(n.Value, fn)
+
+	case *ThrowStatement:
+		n.Value = modifyExpression(n.Value, fn)
+
+	case *ExpressionStatement:
+		n.Expr = modifyExpression(n.Expr, fn)
+
+	case *Identifier:
+		n.Type = modifyType(n.Type, fn)
+
+	case *TypeAnnotation:
+		n.Elem = modifyType(n.Elem, fn)
+		n.Key = modifyType(n.Key, fn)
+		n.Value = modifyType(n.Value, fn)
+		for idx, param := range n.Params {
+			n.Params[idx] = modifyType(param, fn)
+		}
+		n.Result = modifyType(n.Result, fn)
+
+	case *ArrayLiteral:
+		for idx, elem := range n.Elements {
+			n.Elements[idx] = modifyExpression(elem, fn)
+		}
+
+	case *HashLiteral:
+		// Keys are the identity of their pairs, so the map is rebuilt with the modified keys
+		pairs := make(map[Expression]Expression, len(n.Pairs))
+		for _, key := range n.Keys() {
+			pairs[modifyExpression(key, fn)] = modifyExpression(n.Pairs[key], fn)
+		}
+		n.Pairs = pairs
+
+	case *IndexExpression:
+		n.Left = modifyExpression(n.Left, fn)
+		n.Index = modifyExpression(n.Index, fn)
+
+	case *PrefixExpression:
+		n.Operand = modifyExpression(n.Operand, fn)
+
+	case *InfixExpression:
+		n.Left = modifyExpression(n.Left, fn)
+		n.Right = modifyExpression(n.Right, fn)
+
+	case *IfExpression:
+		n.Condition = modifyExpression(n.Condition, fn)
+		n.Consequence = modifyBlock(n.Consequence, fn)
+		n.Alternative = modifyBlock(n.Alternative, fn)
+
+	case *TryExpression:
+		n.Block = modifyBlock(n.Block, fn)
+		n.Param = modifyIdentifier(n.Param, fn)
+		n.Catch = modifyBlock(n.Catch, fn)
+		n.Finally = modifyBlock(n.Finally, fn)
+
+	case *FnLiteral:
+		for idx, param := range n.Parameters {
+			n.Parameters[idx] = modifyIdentifier(param, fn)
+		}
+		n.Result = modifyType(n.Result, fn)
+		n.Body = modifyBlock(n.Body, fn)
+
+	case *CallExpression:
+		n.Fn = modifyExpression(n.Fn, fn)
+		for idx, arg := range n.Args {
+			n.Args[idx] = modifyExpression(arg, fn)
+		}
+	}
+
+	return fn(node)
+}
+
+func modifyStatements(stmts []Statement, fn func(Node) Node) []Statement {
+	modified := make([]Statement, 0, len(stmts))
+	for _, stmt := range stmts {
+		replaced := Modify(stmt, fn)
+		if isNil(replaced) {
+			continue
+		}
+
+		modified = append(modified, replacement[Statement](stmt, replaced))
+	}
+
+	return modified
+}
+
+func modifyExpression(expr Expression, fn func(Node) Node) Expression {
+	if isNil(expr) {
+		return expr
+	}
+
+	return replacement[Expression](expr, Modify(expr, fn))
+}
+
+func modifyBlock(block *BlockStatement, fn func(Node) Node) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+
+	return replacement[*BlockStatement](block, Modify(block, fn))
+}
+
+func modifyIdentifier(ident *Identifier, fn func(Node) Node) *Identifier {
+	if ident == nil {
+		return nil
+	}
+
+	return replacement[*Identifier](ident, Modify(ident, fn))
+}
+
+func modifyType(ta *TypeAnnotation, fn func(Node) Node) *TypeAnnotation {
+	if ta == nil {
+		return nil
+	}
+
+	return replacement[*TypeAnnotation](ta, Modify(ta, fn))
+}
+
+// Check the replacement of a node fits where the node was
+func replacement[T Node](original Node, replaced Node) T {
+	node, ok := replaced.(T)
+	if !ok {
+		panic(fmt.Sprintf("ast.Modify: %T cannot replace %T", replaced, original))
+	}
+
+	return node
+}
@@ -10,21 +10,29 @@ import (
 type BlockStatement struct {
 	Token      token.Token // Opening LBRACE of statement {
 	Statements []Statement
+	Rbrace     token.Position // Position of the closing }
 }
 
 func (bs *BlockStatement) statment()            {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Position }
 func (bs *BlockStatement) String() string {
+	if len(bs.Statements) == 0 {
+		return "{}"
+	}
+
 	var out bytes.Buffer
-	// out.WriteString("{")
+	out.WriteString("{ ")
 
-	if len(bs.Statements) > 0 {
-		for _, stmt := range bs.Statements {
-			out.WriteString(stmt.String())
+	// Separators keep a statement from continuing the expression before it
+	for idx, stmt := range bs.Statements {
+		if idx > 0 {
+			out.WriteString("; ")
 		}
+		out.WriteString(stmt.String())
 	}
 
-	// out.WriteString("}")
+	out.WriteString(" }")
 	return out.String()
 }
 
@@ -32,6 +40,7 @@ func (bs *BlockStatement) String() string {
 type LetStatement struct {
 	Token token.Token
 	Name  *Identifier
+	Type  *TypeAnnotation // Optional, as in let x: int = 5
 	Value Expression
 }
 
@@ -39,9 +48,14 @@ func (ls *LetStatement) statment() {}
 func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Position }
 
 func (ls *LetStatement) String() string {
-	letStr := fmt.Sprintf("let %s = %s;", ls.Name.String(), ls.Value.String())
+	if ls.Type != nil {
+		return fmt.Sprintf("let %s: %s = %s", ls.Name.String(), ls.Type.String(), ls.Value.String())
+	}
+
+	letStr := fmt.Sprintf("let %s = %s", ls.Name.String(), ls.Value.String())
 	return letStr
 }
 
@@ -55,6 +69,7 @@ func (rs *ReturnStatement) statment() {}
 func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Position }
 
 func (rs *ReturnStatement) String() string {
 	returnStr := fmt.Sprintf("return %s", rs.Value.String())
@@ -71,7 +86,24 @@ func (es *ExpressionStatement) statment() {}
 func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
+func (es *ExpressionStatement) Pos() token.Position { return es.Token.Position }
 
 func (es *ExpressionStatement) String() string {
 	return es.Expr.String()
 }
+
+/*** Throw Statement ***/
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression // Value to be thrown, caught by the nearest enclosing try
+}
+
+func (ts *ThrowStatement) statment() {}
+func (ts *ThrowStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+func (ts *ThrowStatement) Pos() token.Position { return ts.Token.Position }
+
+func (ts *ThrowStatement) String() string {
+	return fmt.Sprintf("throw %s", ts.Value.String())
+}
@@ -0,0 +1,124 @@
+package ast
+
+// Visitor's Visit is called for each node found by Walk.
+// When the returned Visitor is not nil, Walk visits the children of node with it, followed by a call of Visit(nil)
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Traverse the tree rooted at node in depth first order, children in source order.
+// Type annotations are children of the let, parameter or function they annotate.
+// Missing children, such as an absent else block, are skipped, as are the children of node types defined elsewhere
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	walk := func(child Node) {
+		if !isNil(child) {
+			Walk(v, child)
+		}
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, stmt := range n.Statements {
+			walk(stmt)
+		}
+
+	case *BlockStatement:
+		for _, stmt := range n.Statements {
+			walk(stmt)
+		}
+
+	case *LetStatement:
+		walk(n.Name)
+		walk(n.Type)
+		walk(n.Value)
+
+	case *ReturnStatement:
+		walk(n.Value)
+
+	case *ThrowStatement:
+		walk(n.Value)
+
+	case *ExpressionStatement:
+		walk(n.Expr)
+
+	case *Identifier:
+		walk(n.Type)
+
+	case *TypeAnnotation:
+		walk(n.Elem)
+		walk(n.Key)
+		walk(n.Value)
+		for _, param := range n.Params {
+			walk(param)
+		}
+		walk(n.Result)
+
+	case *ArrayLiteral:
+		for _, elem := range n.Elements {
+			walk(elem)
+		}
+
+	case *HashLiteral:
+		for _, key := range n.Keys() {
+			walk(key)
+			walk(n.Pairs[key])
+		}
+
+	case *IndexExpression:
+		walk(n.Left)
+		walk(n.Index)
+
+	case *PrefixExpression:
+		walk(n.Operand)
+
+	case *InfixExpression:
+		walk(n.Left)
+		walk(n.Right)
+
+	case *IfExpression:
+		walk(n.Condition)
+		walk(n.Consequence)
+		walk(n.Alternative)
+
+	case *TryExpression:
+		walk(n.Block)
+		walk(n.Param)
+		walk(n.Catch)
+		walk(n.Finally)
+
+	case *FnLiteral:
+		for _, param := range n.Parameters {
+			walk(param)
+		}
+		walk(n.Result)
+		walk(n.Body)
+
+	case *CallExpression:
+		walk(n.Fn)
+		for _, arg := range n.Args {
+			walk(arg)
+		}
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+
+	return nil
+}
+
+// Traverse the tree rooted at node like Walk, calling f for each node.
+// The children of a node are skipped when f returns false for it, after them f is called with nil
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
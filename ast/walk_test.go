@@ -0,0 +1,265 @@
+package ast_test
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/token"
+	"strings"
+	"testing"
+)
+
+func describe(node ast.Node) string {
+	kind := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	switch node := node.(type) {
+	case *ast.Identifier:
+		return kind + " " + node.Value
+	case *ast.IntLiteral:
+		return fmt.Sprintf("%s %d", kind, node.Value)
+	case *ast.TypeAnnotation:
+		return kind + " " + node.String()
+	}
+
+	return kind
+}
+
+func TestInspectOrder(t *testing.T) {
+	program, errs := parse(t, `let x = f(1, [a]); if (x) { -x } else { {"k": x} }; try { y } catch (e) { e }`)
+	if len(errs) > 0 {
+		t.Fatalf("Parser errors: %v", errs)
+	}
+
+	var got []string
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node != nil {
+			got = append(got, describe(node))
+		}
+		return true
+	})
+
+	expected := []string{
+		"Program",
+		"LetStatement", "Identifier x",
+		"CallExpression", "Identifier f", "IntLiteral 1", "ArrayLiteral", "Identifier a",
+		"ExpressionStatement", "IfExpression", "Identifier x",
+		"BlockStatement", "ExpressionStatement", "PrefixExpression", "Identifier x",
+		"BlockStatement", "ExpressionStatement", "HashLiteral", "StringLiteral", "Identifier x",
+		"ExpressionStatement", "TryExpression",
+		"BlockStatement", "ExpressionStatement", "Identifier y",
+		"Identifier e",
+		"BlockStatement", "ExpressionStatement", "Identifier e",
+	}
+
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Wrong visiting order.\nGot=%q\nexpected=%q", got, expected)
+	}
+}
+
+func TestInspectAnnotations(t *testing.T) {
+	program, errs := parse(t, `let f: fn(int) -> [string] = fn(x: int, y) -> {string: bool} { x }`)
+	if len(errs) > 0 {
+		t.Fatalf("Parser errors: %v", errs)
+	}
+
+	var got []string
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node != nil {
+			got = append(got, describe(node))
+		}
+		return true
+	})
+
+	expected := []string{
+		"Program",
+		"LetStatement", "Identifier f",
+		"TypeAnnotation fn(int) -> [string]", "TypeAnnotation int", "TypeAnnotation [string]", "TypeAnnotation string",
+		"FnLiteral", "Identifier x", "TypeAnnotation int", "Identifier y",
+		"TypeAnnotation {string: bool}", "TypeAnnotation string", "TypeAnnotation bool",
+		"BlockStatement", "ExpressionStatement", "Identifier x",
+	}
+
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Wrong visiting order.\nGot=%q\nexpected=%q", got, expected)
+	}
+}
+
+type foreign struct{}
+
+func (foreign) String() string       { return "foreign" }
+func (foreign) TokenLiteral() string { return "foreign" }
+func (foreign) Pos() token.Position  { return token.Position{} }
+
+// Node types defined outside the package are visited without children
+func TestWalkForeignNode(t *testing.T) {
+	var got []ast.Node
+	ast.Inspect(foreign{}, func(node ast.Node) bool {
+		got = append(got, node)
+		return true
+	})
+
+	if len(got) != 2 || got[0] != (foreign{}) || got[1] != nil {
+		t.Errorf("Wrong visits. Got=%v, expected=[foreign <nil>]", got)
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program, _ := parse(t, `let a = fn(b) { c }; d`)
+
+	var names []string
+	ast.Inspect(program, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			names = append(names, ident.Value)
+		}
+		_, isFn := node.(*ast.FnLiteral)
+		return !isFn
+	})
+
+	if strings.Join(names, " ") != "a d" {
+		t.Errorf("Wrong identifiers visited. Got=%v, expected=[a d]", names)
+	}
+}
+
+type depthVisitor struct {
+	depth    *int
+	maxDepth *int
+}
+
+func (v depthVisitor) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		*v.depth--
+		return nil
+	}
+
+	*v.depth++
+	*v.maxDepth = max(*v.maxDepth, *v.depth)
+	return v
+}
+
+// Every visited node is closed by a Visit(nil) once its children are done
+func TestWalkBalanced(t *testing.T) {
+	for _, src := range corpus {
+		program, errs := parse(t, src)
+		if len(errs) > 0 {
+			t.Fatalf("Parser errors for %q: %v", src, errs)
+		}
+
+		depth, maxDepth := 0, 0
+		ast.Walk(depthVisitor{&depth, &maxDepth}, program)
+
+		if depth != 0 {
+			t.Errorf("Unbalanced walk of %q. Got depth=%d", src, depth)
+		}
+		if maxDepth < 3 {
+			t.Errorf("Walk of %q did not descend. Got max depth=%d", src, maxDepth)
+		}
+	}
+}
+
+func TestModify(t *testing.T) {
+	one := func(node ast.Node) ast.Node {
+		if lit, ok := node.(*ast.IntLiteral); ok && lit.Value == 1 {
+			return &ast.IntLiteral{Token: lit.Token, Value: 2}
+		}
+		return node
+	}
+
+	rename := func(node ast.Node) ast.Node {
+		if ident, ok := node.(*ast.Identifier); ok && ident.Value == "a" {
+			return &ast.Identifier{Token: ident.Token, Value: "b"}
+		}
+		return node
+	}
+
+	retype := func(node ast.Node) ast.Node {
+		if ta, ok := node.(*ast.TypeAnnotation); ok && ta.Name == "int" {
+			return &ast.TypeAnnotation{Token: ta.Token, Name: "bool"}
+		}
+		return node
+	}
+
+	tests := []struct {
+		input    string
+		modifier func(ast.Node) ast.Node
+		expected string
+	}{
+		{`1`, one, `2`},
+		{`1 + 1`, one, `2 + 2`},
+		{`-1`, one, `-2`},
+		{`[1, 3, 1]`, one, `[2, 3, 2]`},
+		{`x[1][1]`, one, `x[2][2]`},
+		{`let x = 1; return 1; throw 1`, one, `let x = 2; return 2; throw 2`},
+		{`{1: 1, 3: 3}`, one, `{2: 2, 3: 3}`},
+		{`if (1) { 1 } else { 1 }`, one, `if (2) { 2 } else { 2 }`},
+		{`try { 1 } catch (e) { 1 } finally { 1 }`, one, `try { 2 } catch (e) { 2 } finally { 2 }`},
+		{`fn(x) { 1 }(1, 1)`, one, `fn(x) { 2 }(2, 2)`},
+		{`let a = fn(a) { a }; a(a)`, rename, `let b = fn(b) { b }; b(b)`},
+		{`try { a } catch (a) { a }`, rename, `try { b } catch (b) { b }`},
+		{`{a: a}`, rename, `{b: b}`},
+		{`let x: [int] = fn(y: int) -> fn(int) -> int { y }`, retype, `let x: [bool] = fn(y: bool) -> fn(bool) -> bool { y }`},
+	}
+
+	for _, tt := range tests {
+		program, errs := parse(t, tt.input)
+		if len(errs) > 0 {
+			t.Fatalf("Parser errors for %q: %v", tt.input, errs)
+		}
+		expected, _ := parse(t, tt.expected)
+
+		modified := ast.Modify(program, tt.modifier)
+		if modified.String() != expected.String() {
+			t.Errorf("Wrong modification of %q.\nGot=%q\nexpected=%q", tt.input, modified.String(), expected.String())
+		}
+	}
+}
+
+// Rebuilt hash literals are keyed by the modified keys
+func TestModifyHashKeys(t *testing.T) {
+	program, _ := parse(t, `{"a": 1}`)
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		if str, ok := node.(*ast.StringLiteral); ok {
+			return &ast.StringLiteral{Token: str.Token, Value: str.Value + "!"}
+		}
+		return node
+	})
+
+	hash := program.Statements[0].(*ast.ExpressionStatement).Expr.(*ast.HashLiteral)
+	for key, val := range hash.Pairs {
+		if key.(*ast.StringLiteral).Value != "a!" || val.(*ast.IntLiteral).Value != 1 {
+			t.Errorf("Wrong pair. Got=%s: %s", key, val)
+		}
+	}
+}
+
+func TestModifyRemovesStatements(t *testing.T) {
+	program, _ := parse(t, `a; 1; if (x) { 2; b }; fn() { 3 }`)
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		if stmt, ok := node.(*ast.ExpressionStatement); ok {
+			if _, ok := stmt.Expr.(*ast.IntLiteral); ok {
+				return nil
+			}
+		}
+		return node
+	})
+
+	expected, _ := parse(t, `a; if (x) { b }; fn() {}`)
+	if program.String() != expected.String() {
+		t.Errorf("Wrong program.\nGot=%q\nexpected=%q", program.String(), expected.String())
+	}
+}
+
+func TestModifyMismatch(t *testing.T) {
+	program, _ := parse(t, `fn(x) { x }`)
+
+	defer func() {
+		msg, _ := recover().(string)
+		if !strings.Contains(msg, "*ast.IntLiteral cannot replace *ast.Identifier") {
+			t.Errorf("Wrong panic. Got=%q", msg)
+		}
+	}()
+
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		if _, ok := node.(*ast.Identifier); ok {
+			return &ast.IntLiteral{Value: 0}
+		}
+		return node
+	})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"os"
+)
+
+// monk ast [--json] [file]
+// Print the syntax tree of the file, or stdin, fully parenthesized or as JSON
+func runAst(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("ast", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print the tree as JSON")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() > 1 {
+		return 2
+	}
+
+	name, src, err := readSource(flags.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "monk ast: %s\n", err)
+		return 1
+	}
+
+	p := parser.New(lexer.NewFile(name, string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(stderr, "Parser Error: %s\n", msg)
+		}
+		return 1
+	}
+
+	if !*asJSON {
+		fmt.Fprint(stdout, program.String())
+		return 0
+	}
+
+	data, err := ast.MarshalJSON(program)
+	if err != nil {
+		fmt.Fprintf(stderr, "monk ast: %s\n", err)
+		return 1
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		fmt.Fprintf(stderr, "monk ast: %s\n", err)
+		return 1
+	}
+	out.WriteString("\n")
+	stdout.Write(out.Bytes())
+
+	return 0
+}
+
+// Read the named file, or stdin when name is empty
+func readSource(name string, stdin io.Reader) (string, []byte, error) {
+	if name == "" {
+		src, err := io.ReadAll(stdin)
+		return "", src, err
+	}
+
+	src, err := os.ReadFile(name)
+	return name, src, err
+}
+
+// Write the parser errors of the named source among the findings of check and lint, nameless for stdin
+func reportParseErrors(w io.Writer, name string, errs []string) {
+	for _, msg := range errs {
+		if name != "" {
+			fmt.Fprintf(w, "%s: ", name)
+		}
+		fmt.Fprintf(w, "Parser Error: %s\n", msg)
+	}
+}
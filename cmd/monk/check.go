@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/resolver"
+	"monkey/typecheck"
+	"os"
+	"path/filepath"
+)
+
+// monk check [--types] [paths...]
+// Check the .monk files under the paths, the current directory when none are given, for syntax errors
+// and unknown identifiers, and with --types for type mismatches. Exits with 1 when anything is reported
+func runCheck(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	types := flags.Bool("types", false, "also infer types and report mismatches")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	var files []string
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			// Files named explicitly are checked whatever their extension
+			if name == path && !entry.IsDir() || !entry.IsDir() && filepath.Ext(name) == ".monk" {
+				files = append(files, name)
+			}
+			return nil
+		})
+
+		if err != nil {
+			fmt.Fprintf(stderr, "monk check: %s\n", err)
+			return 1
+		}
+	}
+
+	builtins := evaluator.DefaultRegistry(evaluator.Config{})
+	defined := builtins.Defined()
+
+	status := 0
+	for _, name := range files {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "monk check: %s\n", err)
+			status = 1
+			continue
+		}
+
+		p := parser.New(lexer.NewFile(name, string(src)))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			reportParseErrors(stdout, name, p.Errors())
+			status = 1
+			continue
+		}
+
+		for _, err := range resolver.Resolve(program, func(name string) bool { return defined[name] }) {
+			fmt.Fprintf(stdout, "%s: Unknown Identifier %s\n", err.Position, err.Name)
+			status = 1
+		}
+
+		if !*types {
+			continue
+		}
+
+		_, errs := typecheck.Check(program, builtins)
+		for _, err := range errs {
+			fmt.Fprintf(stdout, "%s: %s\n", err.Position, err.Message)
+			status = 1
+		}
+	}
+
+	return status
+}
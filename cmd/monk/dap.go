@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey/dap"
+	"monkey/evaluator"
+	"time"
+)
+
+// monk dap
+// Serve the Debug Adapter Protocol over stdin and stdout, for editors to debug .monk files
+func runDap(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("dap", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	adapter := dap.NewAdapter(evaluator.Config{Seed: time.Now().UnixNano()})
+	if err := adapter.Serve(stdin, stdout); err != nil {
+		fmt.Fprintf(stderr, "monk dap: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"monkey/debugger"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const debugHelp = `Commands:
+  break N, b N     Set a breakpoint at line N
+  delete N, d N    Remove the breakpoint at line N
+  breakpoints      List the breakpoints
+  continue, c      Run until a breakpoint
+  step, s          Step to the next line, into calls
+  next, n          Step to the next line, over calls
+  out, o           Step out of the current call
+  stack, bt        Show the call stack
+  frame N, f N     Select frame N of the stack for vars and print
+  vars, v          Show the variables visible from the selected frame
+  print E, p E     Evaluate expression E in the selected frame
+  list, l          Show the source around the current line
+  quit, q          Stop the program and exit`
+
+// monk debug file
+// Run the file under a debugger, paused before its first statement, reading commands from stdin
+func runDebug(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return 2
+	}
+
+	name := flags.Arg(0)
+	src, err := os.ReadFile(name)
+	if err != nil {
+		fmt.Fprintf(stderr, "monk debug: %s\n", err)
+		return 1
+	}
+
+	p := parser.New(lexer.NewFile(name, string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(stderr, "Parser Error: %s\n", msg)
+		}
+		return 1
+	}
+
+	env := evaluator.NewEnvironment(evaluator.Config{Seed: time.Now().UnixNano(), Stdout: stdout, Stderr: stderr})
+	cli := &debugCLI{
+		session:     debugger.New(program, env),
+		lines:       strings.Split(string(src), "\n"),
+		in:          bufio.NewScanner(stdin),
+		out:         stdout,
+		breakpoints: map[int]bool{},
+	}
+
+	return cli.run()
+}
+
+// Terminal frontend of a debugger Session
+type debugCLI struct {
+	session     *debugger.Session
+	lines       []string // Source, for listing
+	in          *bufio.Scanner
+	out         io.Writer
+	breakpoints map[int]bool
+
+	event *debugger.Event // Current pause
+	frame int             // Index of the selected frame
+}
+
+func (c *debugCLI) run() int {
+	c.session.Start(true)
+
+	for {
+		event := c.session.Wait()
+		if event.Done {
+			return c.finish(event.Result)
+		}
+
+		c.event, c.frame = &event, 0
+		pos := event.Stmt.Pos()
+		fmt.Fprintf(c.out, "Paused at %s (%s)\n", pos, event.Reason)
+		c.printLine(pos.Line, "")
+
+		if !c.prompt() {
+			c.session.Terminate()
+			c.session.Wait()
+			return 0
+		}
+	}
+}
+
+// Report the end of the program, returning the exit status
+func (c *debugCLI) finish(result object.Object) int {
+	if errObj, ok := result.(*object.Error); ok {
+		fmt.Fprintln(c.out, errObj.Trace())
+		return 1
+	}
+
+	if result == nil {
+		fmt.Fprintln(c.out, "Program finished")
+	} else {
+		fmt.Fprintf(c.out, "Program finished: %s\n", result.Inspect())
+	}
+
+	return 0
+}
+
+// Read commands until one resumes the program, false when the input ends or asks to quit
+func (c *debugCLI) prompt() bool {
+	for {
+		fmt.Fprint(c.out, "(debug) ")
+		if !c.in.Scan() {
+			fmt.Fprintln(c.out)
+			return false
+		}
+
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(c.in.Text()), " ")
+		arg = strings.TrimSpace(arg)
+
+		var err error
+		switch cmd {
+		case "":
+			continue
+
+		case "continue", "c":
+			err = c.session.Continue()
+		case "step", "s":
+			err = c.session.StepIn()
+		case "next", "n":
+			err = c.session.StepOver()
+		case "out", "o":
+			err = c.session.StepOut()
+
+		case "quit", "q":
+			return false
+
+		default:
+			c.command(cmd, arg)
+			continue
+		}
+
+		if err != nil {
+			fmt.Fprintf(c.out, "%s\n", err)
+			continue
+		}
+
+		return true
+	}
+}
+
+// Run a command which inspects the paused program or changes the breakpoints
+func (c *debugCLI) command(cmd, arg string) {
+	switch cmd {
+	case "break", "b", "delete", "d":
+		line, err := strconv.Atoi(arg)
+		if err != nil || line < 1 {
+			fmt.Fprintf(c.out, "Invalid line %q\n", arg)
+			return
+		}
+
+		if cmd == "delete" || cmd == "d" {
+			delete(c.breakpoints, line)
+			c.setBreakpoints()
+			fmt.Fprintf(c.out, "Breakpoint removed at line %d\n", line)
+			return
+		}
+
+		c.breakpoints[line] = true
+		if verified := c.setBreakpoints(); !verified[line] {
+			delete(c.breakpoints, line)
+			c.setBreakpoints()
+			fmt.Fprintf(c.out, "No statement at line %d\n", line)
+			return
+		}
+		fmt.Fprintf(c.out, "Breakpoint set at line %d\n", line)
+
+	case "breakpoints":
+		if len(c.breakpoints) == 0 {
+			fmt.Fprintln(c.out, "No breakpoints")
+		}
+		for _, line := range c.sortedBreakpoints() {
+			c.printLine(line, "")
+		}
+
+	case "stack", "bt":
+		for idx, frame := range c.event.Frames {
+			marker := " "
+			if idx == c.frame {
+				marker = "*"
+			}
+			fmt.Fprintf(c.out, "%s #%d %s at %s\n", marker, idx, frame.Name(), frame.Position)
+		}
+
+	case "frame", "f":
+		idx, err := strconv.Atoi(arg)
+		if err != nil || idx < 0 || idx >= len(c.event.Frames) {
+			fmt.Fprintf(c.out, "Invalid frame %q, Got %d frames\n", arg, len(c.event.Frames))
+			return
+		}
+
+		c.frame = idx
+		frame := c.event.Frames[idx]
+		fmt.Fprintf(c.out, "#%d %s at %s\n", idx, frame.Name(), frame.Position)
+
+	case "vars", "v":
+		for _, scope := range debugger.Scopes(c.event.Frames[c.frame]) {
+			fmt.Fprintf(c.out, "%s:\n", scope.Name)
+			for _, v := range scope.Vars {
+				fmt.Fprintf(c.out, "  %s = %s\n", v.Name, v.Value.Inspect())
+			}
+		}
+
+	case "print", "p":
+		val, err := c.session.Evaluate(c.frame, arg)
+		switch {
+		case err != nil:
+			fmt.Fprintf(c.out, "Parser Error: %s\n", err)
+		case val == nil:
+			fmt.Fprintln(c.out, "null")
+		default:
+			fmt.Fprintln(c.out, val.Inspect())
+		}
+
+	case "list", "l":
+		line := c.event.Frames[c.frame].Position.Line
+		for idx := max(line-2, 1); idx <= min(line+2, len(c.lines)); idx++ {
+			marker := ""
+			if idx == line {
+				marker = "=>"
+			}
+			c.printLine(idx, marker)
+		}
+
+	case "help", "h":
+		fmt.Fprintln(c.out, debugHelp)
+
+	default:
+		fmt.Fprintf(c.out, "Unknown command %q, try help\n", cmd)
+	}
+}
+
+// Hand the breakpoints to the session, returning those at lines with a statement
+func (c *debugCLI) setBreakpoints() map[int]bool {
+	verified := map[int]bool{}
+	for _, line := range c.session.SetBreakpoints(c.sortedBreakpoints()) {
+		verified[line] = true
+	}
+
+	return verified
+}
+
+func (c *debugCLI) sortedBreakpoints() []int {
+	lines := make([]int, 0, len(c.breakpoints))
+	for line := range c.breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+
+	return lines
+}
+
+// Print a line of the source with its number, after a marker such as => for the current line
+func (c *debugCLI) printLine(line int, marker string) {
+	if line < 1 || line > len(c.lines) {
+		return
+	}
+
+	fmt.Fprintf(c.out, "%2s %3d | %s\n", marker, line, c.lines[line-1])
+}
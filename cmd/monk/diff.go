@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+// Line of a diff: ' ' when in both texts, '-' only in the old and '+' only in the new
+type edit struct {
+	kind byte
+	text string
+}
+
+// Unified diff between the text of the named file before and after a change, empty when they are equal
+func unifiedDiff(name, before, after string) string {
+	edits := diffLines(splitLines(before), splitLines(after))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", name, name)
+
+	changed := false
+	for start := 0; start < len(edits); {
+		// Find the next change, and extend the hunk until a stretch of unchanged lines is long enough to split it
+		first := start
+		for first < len(edits) && edits[first].kind == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		changed = true
+
+		last := first
+		for idx := first; idx < len(edits) && idx <= last+2*diffContext; idx++ {
+			if edits[idx].kind != ' ' {
+				last = idx
+			}
+		}
+
+		from := max(start, first-diffContext)
+		to := min(len(edits), last+diffContext+1)
+		writeHunk(&out, edits, from, to)
+
+		start = to
+	}
+
+	if !changed {
+		return ""
+	}
+
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, edits []edit, from, to int) {
+	// Lines of each text before the hunk
+	oldLine, newLine := 0, 0
+	for _, e := range edits[:from] {
+		if e.kind != '+' {
+			oldLine++
+		}
+		if e.kind != '-' {
+			newLine++
+		}
+	}
+
+	oldLen, newLen := 0, 0
+	for _, e := range edits[from:to] {
+		if e.kind != '+' {
+			oldLen++
+		}
+		if e.kind != '-' {
+			newLen++
+		}
+	}
+
+	// Ranges start at the first line of the hunk, or the line before an empty one
+	if oldLen > 0 {
+		oldLine++
+	}
+	if newLen > 0 {
+		newLine++
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", oldLine, oldLen, newLine, newLen)
+	for _, e := range edits[from:to] {
+		fmt.Fprintf(out, "%c%s\n", e.kind, e.text)
+	}
+}
+
+// Edits turning before into after, from the longest common subsequence of their lines
+func diffLines(before, after []string) []edit {
+	// common[i][j] is the length of the longest common subsequence of before[i:] and after[j:]
+	common := make([][]int, len(before)+1)
+	for i := range common {
+		common[i] = make([]int, len(after)+1)
+	}
+
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	edits := []edit{}
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			edits = append(edits, edit{' ', before[i]})
+			i++
+			j++
+		case j == len(after) || (i < len(before) && common[i+1][j] >= common[i][j+1]):
+			edits = append(edits, edit{'-', before[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', after[j]})
+			j++
+		}
+	}
+
+	return edits
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
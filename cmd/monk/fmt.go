@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"monkey/printer"
+	"os"
+)
+
+// monk fmt [-w] [-d] [files...]
+// Format the files, or stdin when none are given, printing the result to stdout
+func runFmt(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	write := flags.Bool("w", false, "write the result to the file instead of stdout")
+	diff := flags.Bool("d", false, "print a diff of the changes instead of the result")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(stderr, "monk fmt: cannot use -w with stdin")
+			return 2
+		}
+
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "monk fmt: %s\n", err)
+			return 1
+		}
+
+		return formatSource("<stdin>", src, false, *diff, stdout, stderr)
+	}
+
+	status := 0
+	for _, name := range flags.Args() {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "monk fmt: %s\n", err)
+			status = 1
+			continue
+		}
+
+		if code := formatSource(name, src, *write, *diff, stdout, stderr); code != 0 {
+			status = code
+		}
+	}
+
+	return status
+}
+
+func formatSource(name string, src []byte, write, diff bool, stdout, stderr io.Writer) int {
+	formatted, err := printer.Format(src)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", name, err)
+		return 1
+	}
+
+	if diff && !bytes.Equal(src, formatted) {
+		fmt.Fprint(stdout, unifiedDiff(name, string(src), string(formatted)))
+	}
+
+	if write && !bytes.Equal(src, formatted) {
+		info, err := os.Stat(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "monk fmt: %s\n", err)
+			return 1
+		}
+
+		if err := os.WriteFile(name, formatted, info.Mode().Perm()); err != nil {
+			fmt.Fprintf(stderr, "monk fmt: %s\n", err)
+			return 1
+		}
+	}
+
+	if !write && !diff {
+		stdout.Write(formatted)
+	}
+
+	return 0
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/lint"
+	"monkey/parser"
+)
+
+// monk lint [files...]
+// Report likely mistakes in the files, or stdin when none are given. Exits with 1 when anything is reported
+func runLint(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	names := flags.Args()
+	if len(names) == 0 {
+		names = []string{""}
+	}
+
+	builtins := evaluator.DefaultRegistry(evaluator.Config{})
+
+	status := 0
+	for _, name := range names {
+		name, src, err := readSource(name, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "monk lint: %s\n", err)
+			status = 1
+			continue
+		}
+
+		p := parser.New(lexer.NewFile(name, string(src)))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			reportParseErrors(stdout, name, p.Errors())
+			status = 1
+			continue
+		}
+
+		for _, diag := range lint.Lint(program, builtins) {
+			fmt.Fprintln(stdout, diag)
+			status = 1
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey/evaluator"
+	"monkey/lsp"
+)
+
+// monk lsp
+// Serve the Language Server Protocol over stdin and stdout, for editors to check, navigate and format .monk files
+func runLsp(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	server := lsp.NewServer(evaluator.DefaultRegistry(evaluator.Config{}))
+	if err := server.Serve(stdin, stdout); err != nil {
+		fmt.Fprintf(stderr, "monk lsp: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
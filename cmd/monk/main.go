@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"monkey/repl"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Subcommands of monk, each given its arguments and returning the exit status
+var commands = map[string]func(args []string, stdin io.Reader, stdout, stderr io.Writer) int{
+	"ast":   runAst,
+	"check": runCheck,
+	"dap":   runDap,
+	"debug": runDebug,
+	"fmt":   runFmt,
+	"lint":  runLint,
+	"lsp":   runLsp,
+	"run":   runRun,
+}
+
+// monk [command] [args...]
+// Start the REPL without a command, otherwise run the command
+func main() {
+	if len(os.Args) == 1 {
+		fmt.Printf("Starting REPL...\n----------------\n\n")
+		repl.Start(os.Stdin, os.Stdout)
+		return
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintf(os.Stderr, "monk: unknown command %s\nUsage: monk [%s] [args...]\n", os.Args[1], strings.Join(names, "|"))
+		os.Exit(2)
+	}
+
+	os.Exit(cmd(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+}
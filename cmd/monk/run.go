@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"monkey"
+	"time"
+)
+
+// monk run file
+// Evaluate the file, exiting with 1 when it does not parse or raises an Error
+func runRun(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return 2
+	}
+
+	interp := monkey.New(monkey.WithStdout(stdout), monkey.WithStderr(stderr), monkey.WithSeed(time.Now().UnixNano()))
+	if _, err := interp.EvalFile(flags.Arg(0)); err != nil {
+		var parseErr *monkey.ParseError
+		var runtimeErr *monkey.RuntimeError
+		switch {
+		case errors.As(err, &parseErr):
+			reportParseErrors(stderr, flags.Arg(0), parseErr.Errors)
+		case errors.As(err, &runtimeErr):
+			fmt.Fprintln(stderr, runtimeErr.Err.Trace())
+		default:
+			fmt.Fprintf(stderr, "monk run: %s\n", err)
+		}
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,346 @@
+// Package dap implements a Debug Adapter Protocol adapter which runs a Monkey program under the debugger package,
+// speaking to an editor over a pair of streams such as stdin and stdout
+package dap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"monkey/debugger"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Programs run on a single thread
+const threadID = 1
+
+type Adapter struct {
+	config evaluator.Config
+	conn   *conn
+
+	initialized bool
+	lineBase    int // Number of the first line for the client, 0 or 1
+	columnBase  int
+
+	path        string // Of the launched program
+	session     *debugger.Session
+	stopOnEntry bool
+	noDebug     bool
+	done        chan struct{} // Closed once the program ended and its events are written, nil until it starts
+
+	mu          sync.Mutex
+	event       *debugger.Event // Current pause, nil while running
+	refs        []any           // Targets of the variablesReferences of the pause, reference n being refs[n-1]
+	terminating bool            // The program was stopped by the client
+	quiet       bool            // The client disconnected, nothing more is written
+}
+
+// Create an Adapter running programs with the builtins configured by cfg, whose Stdout and Stderr are
+// replaced by output events
+func NewAdapter(cfg evaluator.Config) *Adapter {
+	return &Adapter{config: cfg, lineBase: 1, columnBase: 1}
+}
+
+// Handle the requests read from in, writing responses and events to out, until the client disconnects or in ends.
+// A program still running then is terminated. An error is returned when a stream breaks
+func (a *Adapter) Serve(in io.Reader, out io.Writer) error {
+	a.conn = newConn(in, out)
+	defer a.stop(true)
+
+	for {
+		body, err := a.conn.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req message
+		if err := json.Unmarshal(body, &req); err != nil || req.Type != requestType {
+			// Without a valid request there is nothing to respond to
+			continue
+		}
+
+		if req.Command == "disconnect" {
+			a.stop(true)
+			return a.conn.respond(&req, nil)
+		}
+
+		if err := a.request(&req); err != nil {
+			return err
+		}
+	}
+}
+
+// Handle a request and write its response
+func (a *Adapter) request(req *message) error {
+	if !a.initialized && req.Command != "initialize" {
+		return a.conn.respondError(req, "Adapter is not initialized")
+	}
+
+	var body any
+	var err error
+
+	switch req.Command {
+	case "initialize":
+		body, err = handle(a, req.Arguments, (*Adapter).initialize)
+
+	case "launch":
+		if body, err = handle(a, req.Arguments, (*Adapter).launch); err == nil {
+			// Breakpoints can be checked against the program from now on, so the client is asked for them
+			if err := a.conn.respond(req, body); err != nil {
+				return err
+			}
+			return a.conn.event("initialized", nil)
+		}
+
+	case "setBreakpoints":
+		body, err = handle(a, req.Arguments, (*Adapter).setBreakpoints)
+
+	case "configurationDone":
+		if a.session == nil {
+			return a.conn.respondError(req, "No program launched")
+		}
+		if err := a.conn.respond(req, nil); err != nil {
+			return err
+		}
+		a.start()
+		return nil
+
+	case "threads":
+		body = ThreadsResponseBody{Threads: []Thread{{ID: threadID, Name: "main"}}}
+
+	case "continue":
+		return a.resume(req, ContinueResponseBody{AllThreadsContinued: true}, (*debugger.Session).Continue)
+	case "next":
+		return a.resume(req, nil, (*debugger.Session).StepOver)
+	case "stepIn":
+		return a.resume(req, nil, (*debugger.Session).StepIn)
+	case "stepOut":
+		return a.resume(req, nil, (*debugger.Session).StepOut)
+
+	case "stackTrace":
+		body, err = handle(a, req.Arguments, (*Adapter).stackTrace)
+	case "scopes":
+		body, err = handle(a, req.Arguments, (*Adapter).scopes)
+	case "variables":
+		body, err = handle(a, req.Arguments, (*Adapter).variables)
+	case "evaluate":
+		body, err = handle(a, req.Arguments, (*Adapter).evaluate)
+
+	case "terminate":
+		a.stop(false)
+
+	default:
+		return a.conn.respondError(req, "Unknown command %s", req.Command)
+	}
+
+	if err != nil {
+		return a.conn.respondError(req, "%s", err)
+	}
+
+	return a.conn.respond(req, body)
+}
+
+// Decode the arguments of a request and pass them to the method handling it
+func handle[A, B any](a *Adapter, raw json.RawMessage, method func(a *Adapter, args A) (B, error)) (any, error) {
+	var args A
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, fmt.Errorf("Invalid arguments: %w", err)
+		}
+	}
+
+	return method(a, args)
+}
+
+/*** Lifecycle ***/
+
+func (a *Adapter) initialize(args InitializeRequestArguments) (Capabilities, error) {
+	a.initialized = true
+	if args.LinesStartAt1 != nil && !*args.LinesStartAt1 {
+		a.lineBase = 0
+	}
+	if args.ColumnsStartAt1 != nil && !*args.ColumnsStartAt1 {
+		a.columnBase = 0
+	}
+
+	return Capabilities{
+		SupportsConfigurationDoneRequest: true,
+		SupportsEvaluateForHovers:        true,
+		SupportsTerminateRequest:         true,
+	}, nil
+}
+
+// Parse the program, which starts once the client is done configuring it
+func (a *Adapter) launch(args LaunchRequestArguments) (any, error) {
+	if a.session != nil {
+		return nil, errors.New("Program already launched")
+	}
+
+	src, err := os.ReadFile(args.Program)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.NewFile(args.Program, string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, fmt.Errorf("Parser Error: %s", strings.Join(p.Errors(), "\n"))
+	}
+
+	cfg := a.config
+	cfg.Stdout, cfg.Stderr = &output{a, "stdout"}, &output{a, "stderr"}
+
+	a.path, a.stopOnEntry, a.noDebug = args.Program, args.StopOnEntry, args.NoDebug
+	a.session = debugger.New(program, evaluator.NewEnvironment(cfg))
+
+	return nil, nil
+}
+
+// Run the program, reporting its pauses and its end as events
+func (a *Adapter) start() {
+	if a.done != nil {
+		return
+	}
+
+	a.done = make(chan struct{})
+	a.session.Start(a.stopOnEntry && !a.noDebug)
+
+	go func() {
+		defer close(a.done)
+
+		for {
+			event := a.session.Wait()
+			if event.Done {
+				a.finish(event.Result)
+				return
+			}
+
+			a.mu.Lock()
+			a.event, a.refs = &event, nil
+			a.mu.Unlock()
+
+			a.emit("stopped", StoppedEventBody{Reason: event.Reason, ThreadID: threadID, AllThreadsStopped: true})
+		}
+	}()
+}
+
+// Report the end of the program, with its error unless the client stopped it
+func (a *Adapter) finish(result object.Object) {
+	a.mu.Lock()
+	a.event, a.refs = nil, nil
+	terminating := a.terminating
+	a.mu.Unlock()
+
+	exitCode := 0
+	if errObj, ok := result.(*object.Error); ok {
+		exitCode = 1
+		if !terminating {
+			a.emit("output", OutputEventBody{Category: "stderr", Output: errObj.Trace() + "\n"})
+		}
+	}
+
+	a.emit("exited", ExitedEventBody{ExitCode: exitCode})
+	a.emit("terminated", nil)
+}
+
+// Terminate the program if it is running and wait for its end, quietly once the client disconnects
+func (a *Adapter) stop(quiet bool) {
+	a.mu.Lock()
+	a.terminating = true
+	a.quiet = a.quiet || quiet
+	a.mu.Unlock()
+
+	if a.done == nil {
+		return
+	}
+
+	a.session.Terminate()
+	<-a.done
+}
+
+// Write an event about the program, unless the client disconnected. Errors of the stream
+// surface on the next read or response
+func (a *Adapter) emit(name string, body any) {
+	a.mu.Lock()
+	quiet := a.quiet
+	a.mu.Unlock()
+
+	if !quiet {
+		a.conn.event(name, body)
+	}
+}
+
+// Writer of the program sending what it writes as output events
+type output struct {
+	adapter  *Adapter
+	category string
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.adapter.emit("output", OutputEventBody{Category: o.category, Output: string(p)})
+	return len(p), nil
+}
+
+/*** Execution ***/
+
+// Replace the breakpoints of the program. Lines without a statement and other files cannot be stopped at
+func (a *Adapter) setBreakpoints(args SetBreakpointsArguments) (SetBreakpointsResponseBody, error) {
+	body := SetBreakpointsResponseBody{Breakpoints: []Breakpoint{}}
+	if a.session == nil {
+		return body, errors.New("No program launched")
+	}
+
+	var lines []int
+	for _, bp := range args.Breakpoints {
+		lines = append(lines, bp.Line+1-a.lineBase)
+	}
+
+	sameFile := filepath.Clean(args.Source.Path) == filepath.Clean(a.path)
+	verified := map[int]bool{}
+	if sameFile && !a.noDebug {
+		for _, line := range a.session.SetBreakpoints(lines) {
+			verified[line] = true
+		}
+	}
+
+	for idx, bp := range args.Breakpoints {
+		breakpoint := Breakpoint{Verified: verified[lines[idx]], Line: bp.Line}
+		switch {
+		case !sameFile:
+			breakpoint.Message = "Not in the launched program"
+		case !breakpoint.Verified:
+			breakpoint.Message = "No statement at this line"
+		}
+		body.Breakpoints = append(body.Breakpoints, breakpoint)
+	}
+
+	return body, nil
+}
+
+// Respond to a request resuming the paused program, then resume it, so the response comes before its next events
+func (a *Adapter) resume(req *message, body any, resume func(s *debugger.Session) error) error {
+	a.mu.Lock()
+	paused := a.event != nil
+	a.event, a.refs = nil, nil
+	a.mu.Unlock()
+
+	if !paused {
+		return a.conn.respondError(req, "Program is not paused")
+	}
+
+	if err := a.conn.respond(req, body); err != nil {
+		return err
+	}
+
+	return resume(a.session)
+}
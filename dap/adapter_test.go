@@ -0,0 +1,132 @@
+package dap
+
+import (
+	"flag"
+	"io"
+	"monkey/evaluator"
+	"monkey/internal/frame"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Record the messages received into the transcripts")
+
+// Programs debugged by the transcripts of the same name in testdata. A transcript holds the messages of a
+// session in order, one per line: those after -> are sent to the adapter and those after <- are expected
+// from it. ${program} stands for the path of the program
+var transcripts = []struct {
+	name    string
+	program string
+}{
+	{"run", `let greet = fn(name) { puts("Hello, " + name) };
+greet("Monkey");
+greet("DAP");`},
+	{"breakpoints", `let total = fn(values, factor) {
+  let sum = values[0] + values[1];
+  sum * factor
+};
+let config = {"factor": 3};
+let result = total([1, 2], config["factor"]);
+puts(result);`},
+	{"stepping", `let add = fn(a, b) {
+  let s = a + b;
+  s
+};
+let twice = fn(x) {
+  let y = add(x, x);
+  y * 2
+};
+twice(3)`},
+	{"terminate", `let loop = fn(n) { loop(n + 1) };
+loop(0)`},
+	{"errors", `let f = fn(x) { x + true };
+f(1)`},
+}
+
+func TestTranscripts(t *testing.T) {
+	for _, tt := range transcripts {
+		t.Run(tt.name, func(t *testing.T) {
+			program := filepath.Join(t.TempDir(), tt.name+".monk")
+			if err := os.WriteFile(program, []byte(tt.program), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join("testdata", tt.name+".txt")
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorded := replay(t, strings.Split(strings.TrimRight(string(src), "\n"), "\n"), program)
+			if *update {
+				if err := os.WriteFile(path, []byte(strings.Join(recorded, "\n")+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Play a transcript against an Adapter serving over pipes, returning it with the messages actually received
+func replay(t *testing.T, lines []string, program string) []string {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		err := NewAdapter(evaluator.Config{}).Serve(inReader, outWriter)
+		outWriter.Close()
+		done <- err
+	}()
+
+	adapter := newConn(outReader, nil)
+	receive := func() (string, error) {
+		body, err := adapter.Read()
+		return strings.ReplaceAll(string(body), program, "${program}"), err
+	}
+
+	var recorded []string
+	for idx, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "->"):
+			body := strings.ReplaceAll(strings.TrimSpace(line[2:]), "${program}", program)
+			if err := frame.Write(inWriter, []byte(body)); err != nil {
+				t.Fatalf("Line %d: failed to send: %s", idx+1, err)
+			}
+
+		case strings.HasPrefix(line, "<-"):
+			got, err := receive()
+			if err != nil {
+				t.Fatalf("Line %d: failed to receive: %s", idx+1, err)
+			}
+
+			if expected := strings.TrimSpace(line[2:]); got != expected && !*update {
+				t.Errorf("Line %d: wrong message.\nGot=     %s\nexpected=%s", idx+1, got, expected)
+			}
+			line = "<- " + got
+		}
+
+		recorded = append(recorded, line)
+	}
+
+	// The adapter stops once its input ends, with nothing more to say
+	inWriter.Close()
+	for {
+		got, err := receive()
+		if err != nil {
+			break
+		}
+		if !*update {
+			t.Errorf("Unexpected message after the transcript: %s", got)
+		}
+		recorded = append(recorded, "<- "+got)
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("Serve failed: %s", err)
+	}
+
+	return recorded
+}
@@ -0,0 +1,70 @@
+package dap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"monkey/internal/frame"
+	"sync"
+)
+
+// Stream of DAP messages, each preceded by a Content-Length header. Writes may come from several goroutines
+type conn struct {
+	*frame.Reader
+	out io.Writer
+
+	mu  sync.Mutex
+	seq int // Of the last message written
+}
+
+func newConn(in io.Reader, out io.Writer) *conn {
+	return &conn{Reader: frame.NewReader(in), out: out}
+}
+
+// Write msg with the next sequence number
+func (c *conn) write(msg *message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+	msg.Seq = c.seq
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return frame.Write(c.out, body)
+}
+
+func (c *conn) respond(req *message, body any) error {
+	raw, err := marshalBody(body)
+	if err != nil {
+		return err
+	}
+
+	success := true
+	return c.write(&message{Type: responseType, RequestSeq: req.Seq, Command: req.Command, Success: &success, Body: raw})
+}
+
+func (c *conn) respondError(req *message, format string, args ...any) error {
+	success := false
+	return c.write(&message{Type: responseType, RequestSeq: req.Seq, Command: req.Command, Success: &success, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *conn) event(name string, body any) error {
+	raw, err := marshalBody(body)
+	if err != nil {
+		return err
+	}
+
+	return c.write(&message{Type: eventType, Event: name, Body: raw})
+}
+
+// Bodies are optional, nil leaves them out
+func marshalBody(body any) (json.RawMessage, error) {
+	if body == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(body)
+}
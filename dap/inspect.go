@@ -0,0 +1,182 @@
+package dap
+
+import (
+	"errors"
+	"fmt"
+	"monkey/debugger"
+	"monkey/evaluator"
+	"monkey/object"
+	"path/filepath"
+	"sort"
+)
+
+// Current pause, frame ids and variablesReferences are only valid during it
+func (a *Adapter) paused() (*debugger.Event, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.event == nil {
+		return nil, errors.New("Program is not paused")
+	}
+
+	return a.event, nil
+}
+
+// Frame of the pause by id, which is its index in the stack plus one
+func (a *Adapter) frame(id int) (*evaluator.StackFrame, error) {
+	event, err := a.paused()
+	if err != nil {
+		return nil, err
+	}
+
+	if id < 1 || id > len(event.Frames) {
+		return nil, fmt.Errorf("Invalid frame id %d", id)
+	}
+
+	return event.Frames[id-1], nil
+}
+
+func (a *Adapter) stackTrace(args StackTraceArguments) (StackTraceResponseBody, error) {
+	body := StackTraceResponseBody{StackFrames: []StackFrame{}}
+	event, err := a.paused()
+	if err != nil {
+		return body, err
+	}
+
+	frames := event.Frames
+	end := len(frames)
+	if args.Levels > 0 {
+		end = min(args.StartFrame+args.Levels, end)
+	}
+
+	for idx := args.StartFrame; idx < end; idx++ {
+		pos := frames[idx].Position
+		body.StackFrames = append(body.StackFrames, StackFrame{
+			ID:     idx + 1,
+			Name:   frames[idx].Name(),
+			Source: Source{Name: filepath.Base(a.path), Path: a.path},
+			Line:   pos.Line - 1 + a.lineBase,
+			Column: pos.Column - 1 + a.columnBase,
+		})
+	}
+	body.TotalFrames = len(frames)
+
+	return body, nil
+}
+
+func (a *Adapter) scopes(args ScopesArguments) (ScopesResponseBody, error) {
+	body := ScopesResponseBody{Scopes: []Scope{}}
+	frame, err := a.frame(args.FrameID)
+	if err != nil {
+		return body, err
+	}
+
+	for _, scope := range debugger.Scopes(frame) {
+		body.Scopes = append(body.Scopes, Scope{Name: scope.Name, VariablesReference: a.reference(scope)})
+	}
+
+	return body, nil
+}
+
+// Variables of a scope, or the elements of an array or hash
+func (a *Adapter) variables(args VariablesArguments) (VariablesResponseBody, error) {
+	body := VariablesResponseBody{Variables: []Variable{}}
+	if _, err := a.paused(); err != nil {
+		return body, err
+	}
+
+	a.mu.Lock()
+	var target any
+	if args.VariablesReference >= 1 && args.VariablesReference <= len(a.refs) {
+		target = a.refs[args.VariablesReference-1]
+	}
+	a.mu.Unlock()
+
+	switch target := target.(type) {
+	case debugger.Scope:
+		for _, v := range target.Vars {
+			body.Variables = append(body.Variables, a.variable(v.Name, v.Value))
+		}
+
+	case *object.Array:
+		for idx, val := range target.Value {
+			body.Variables = append(body.Variables, a.variable(fmt.Sprintf("[%d]", idx), val))
+		}
+
+	case *object.Hash:
+		pairs := make([]object.HashPair, 0, len(target.Pairs))
+		for _, pair := range target.Pairs {
+			pairs = append(pairs, pair)
+		}
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key.Inspect() < pairs[j].Key.Inspect() })
+
+		for _, pair := range pairs {
+			body.Variables = append(body.Variables, a.variable(pair.Key.Inspect(), pair.Val))
+		}
+
+	default:
+		return body, fmt.Errorf("Invalid variablesReference %d", args.VariablesReference)
+	}
+
+	return body, nil
+}
+
+func (a *Adapter) variable(name string, val object.Object) Variable {
+	return Variable{Name: name, Value: val.Inspect(), Type: string(val.Type()), VariablesReference: a.expandable(val)}
+}
+
+// Reference to the elements of val, 0 when it has none
+func (a *Adapter) expandable(val object.Object) int {
+	switch val := val.(type) {
+	case *object.Array:
+		if len(val.Value) > 0 {
+			return a.reference(val)
+		}
+	case *object.Hash:
+		if len(val.Pairs) > 0 {
+			return a.reference(val)
+		}
+	}
+
+	return 0
+}
+
+func (a *Adapter) reference(target any) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.refs = append(a.refs, target)
+	return len(a.refs)
+}
+
+// Evaluate an expression in a frame of the pause, the innermost one when no frame is given
+func (a *Adapter) evaluate(args EvaluateArguments) (EvaluateResponseBody, error) {
+	var body EvaluateResponseBody
+	if _, err := a.paused(); err != nil {
+		return body, err
+	}
+
+	idx := 0
+	if args.FrameID != 0 {
+		if _, err := a.frame(args.FrameID); err != nil {
+			return body, err
+		}
+		idx = args.FrameID - 1
+	}
+
+	val, err := a.session.Evaluate(idx, args.Expression)
+	if err != nil {
+		return body, err
+	}
+
+	switch val := val.(type) {
+	case nil:
+		body.Result, body.Type = "null", string(object.NULL_OBJ)
+	case *object.Error:
+		return body, errors.New(val.Message)
+	default:
+		body.Result, body.Type, body.VariablesReference = val.Inspect(), string(val.Type()), a.expandable(val)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,270 @@
+// Package debugger runs a program under the control of a frontend, such as a terminal or an editor,
+// pausing it at breakpoints and after steps so its frames and variables can be inspected
+package debugger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Reasons the program paused
+const (
+	ENTRY      = "entry"      // Before the first statement, when started with stopOnEntry
+	BREAKPOINT = "breakpoint" // At a statement on a line with a breakpoint
+	STEP       = "step"       // At the statement a step ended on
+)
+
+// Pause of the program, or its end once Done
+type Event struct {
+	Reason string
+	Stmt   ast.Statement
+	Frames []*evaluator.StackFrame // Calls in progress, the innermost first
+
+	Done   bool
+	Result object.Object // Value of the program once Done, an *object.Error when it failed
+}
+
+// Ways to resume a paused program
+type mode int
+
+const (
+	run      mode = iota // Until a breakpoint
+	stepIn               // Until the next line, inside a call or out of the current one
+	stepOver             // Until the next line of the current call or the one it returns to
+	stepOut              // Until the call returns
+)
+
+type Session struct {
+	program *ast.Program
+	env     *object.Environment
+	cancel  context.CancelFunc
+
+	mu          sync.Mutex
+	breakpoints map[int]bool // Lines
+	mode        mode
+	entry       bool
+	depth       int                   // Number of frames when the program was resumed
+	last        *evaluator.StackFrame // Frame of the last statement evaluated
+	lastLine    int                   // Line of the last statement evaluated
+	paused      *Event                // Current pause, nil while running
+	terminated  bool
+
+	events chan Event
+	resume chan mode
+}
+
+// Create a Session evaluating program in env
+func New(program *ast.Program, env *object.Environment) *Session {
+	return &Session{
+		program:     program,
+		env:         env,
+		breakpoints: map[int]bool{},
+		events:      make(chan Event, 1),
+		resume:      make(chan mode),
+	}
+}
+
+// Replace the breakpoints, returning the lines which hold the start of a statement and so can be stopped at
+func (s *Session) SetBreakpoints(lines []int) []int {
+	statements := map[int]bool{}
+	ast.Inspect(s.program, func(node ast.Node) bool {
+		if stmt, ok := node.(ast.Statement); ok {
+			if _, isBlock := stmt.(*ast.BlockStatement); !isBlock {
+				statements[stmt.Pos().Line] = true
+			}
+		}
+		return true
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.breakpoints = map[int]bool{}
+	var verified []int
+	for _, line := range lines {
+		s.breakpoints[line] = true
+		if statements[line] {
+			verified = append(verified, line)
+		}
+	}
+
+	return verified
+}
+
+// Start evaluating the program, pausing before the first statement when stopOnEntry is set
+func (s *Session) Start(stopOnEntry bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.entry = stopOnEntry
+
+	evaluator.SetDebugger(s.env, s)
+	go func() {
+		result := evaluator.EvalContext(ctx, s.program, s.env)
+		evaluator.SetDebugger(s.env, nil)
+		cancel()
+
+		s.events <- Event{Done: true, Result: result}
+	}()
+}
+
+// Wait for the program to pause or end
+func (s *Session) Wait() Event {
+	return <-s.events
+}
+
+func (s *Session) Continue() error { return s.resumeWith(run) }
+func (s *Session) StepIn() error   { return s.resumeWith(stepIn) }
+func (s *Session) StepOver() error { return s.resumeWith(stepOver) }
+func (s *Session) StepOut() error  { return s.resumeWith(stepOut) }
+
+func (s *Session) resumeWith(m mode) error {
+	s.mu.Lock()
+	if s.paused == nil {
+		s.mu.Unlock()
+		return errors.New("Program is not paused")
+	}
+	s.paused = nil
+	s.mu.Unlock()
+
+	s.resume <- m
+	return nil
+}
+
+// Stop the program, which ends with an Error once it notices. Breakpoints and steps no longer pause it
+func (s *Session) Terminate() {
+	s.mu.Lock()
+	s.terminated = true
+	paused := s.paused != nil
+	s.paused = nil
+	s.mu.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+	if paused {
+		s.resume <- run
+	}
+}
+
+// Frames of the paused program, the innermost first
+func (s *Session) Frames() ([]*evaluator.StackFrame, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused == nil {
+		return nil, errors.New("Program is not paused")
+	}
+
+	return s.paused.Frames, nil
+}
+
+// Parse and evaluate src in the frame of the paused program at index, 0 being the innermost
+func (s *Session) Evaluate(frame int, src string) (object.Object, error) {
+	frames, err := s.Frames()
+	if err != nil {
+		return nil, err
+	}
+
+	if frame < 0 || frame >= len(frames) {
+		return nil, fmt.Errorf("Invalid frame %d, Got %d frames", frame, len(frames))
+	}
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+
+	return evaluator.EvalInFrame(program, frames[frame]), nil
+}
+
+// Statement pauses the program when it reaches a breakpoint or the end of a step, implementing evaluator.Debugger
+func (s *Session) Statement(stmt ast.Statement, frames []*evaluator.StackFrame) {
+	s.mu.Lock()
+
+	// Only the first statement of a line is stopped at
+	top, line := frames[len(frames)-1], stmt.Pos().Line
+	sameLine := top == s.last && line == s.lastLine
+	s.last, s.lastLine = top, line
+
+	reason := ""
+	switch {
+	case s.terminated || sameLine:
+	case s.entry:
+		reason, s.entry = ENTRY, false
+	case s.breakpoints[line]:
+		reason = BREAKPOINT
+	case s.mode == stepIn, s.mode == stepOver && len(frames) <= s.depth, s.mode == stepOut && len(frames) < s.depth:
+		reason = STEP
+	}
+
+	if reason == "" {
+		s.mu.Unlock()
+		return
+	}
+
+	event := Event{Reason: reason, Stmt: stmt, Frames: make([]*evaluator.StackFrame, len(frames))}
+	for idx, frame := range frames {
+		// Frames are updated as the program runs, the event keeps them as they are now
+		copied := *frame
+		event.Frames[len(frames)-1-idx] = &copied
+	}
+	s.paused = &event
+	s.mu.Unlock()
+
+	s.events <- event
+	m := <-s.resume
+
+	s.mu.Lock()
+	s.mode, s.depth = m, len(frames)
+	s.mu.Unlock()
+}
+
+/*** Variables ***/
+
+// Variables of an Environment in the chain of a frame
+type Scope struct {
+	Name string // Locals, Closure or Globals
+	Env  *object.Environment
+	Vars []Var // Sorted by name
+}
+
+type Var struct {
+	Name  string
+	Value object.Object
+}
+
+// Scopes visible from the frame, the innermost first. The builtins in the outermost Environment are left out
+func Scopes(frame *evaluator.StackFrame) []Scope {
+	var scopes []Scope
+
+	for env := frame.Env; env != nil && env.Outer() != nil; env = env.Outer() {
+		vars := env.Locals()
+		name := "Closure"
+		switch {
+		case vars == nil:
+			vars, name = env.Bindings(), "Globals"
+		case len(scopes) == 0:
+			name = "Locals"
+		}
+
+		scope := Scope{Name: name, Env: env}
+		for varName, val := range vars {
+			scope.Vars = append(scope.Vars, Var{Name: varName, Value: val})
+		}
+		sort.Slice(scope.Vars, func(i, j int) bool { return scope.Vars[i].Name < scope.Vars[j].Name })
+
+		scopes = append(scopes, scope)
+	}
+
+	return scopes
+}
@@ -0,0 +1,203 @@
+package debugger
+
+import (
+	"fmt"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"strings"
+	"testing"
+)
+
+const program = `let add = fn(a, b) {
+  let s = a + b;
+  s
+};
+let twice = fn(x) {
+  let y = add(x, x);
+  y * 2
+};
+let r = twice(3);
+r + 1`
+
+func newSession(t *testing.T, input string) *Session {
+	p := parser.New(lexer.New(input))
+	prog := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	return New(prog, evaluator.NewEnvironment(evaluator.Config{}))
+}
+
+// Describe a pause as "reason line frame>frame" with the innermost frame first, or the result once done
+func describe(event Event) string {
+	if event.Done {
+		return "done " + event.Result.Inspect()
+	}
+
+	names := make([]string, len(event.Frames))
+	for idx, frame := range event.Frames {
+		names[idx] = frame.Name()
+	}
+
+	return fmt.Sprintf("%s %d %s", event.Reason, event.Stmt.Pos().Line, strings.Join(names, ">"))
+}
+
+func TestStepping(t *testing.T) {
+	tests := []struct {
+		breakpoints []int
+		commands    string // First letters of Continue, StepIn, StepOver and StepOut, one per pause
+		expected    []string
+	}{
+		{nil, "c", []string{"entry 1 <program>", "done 13"}},
+		{[]int{2}, "cc", []string{"entry 1 <program>", "breakpoint 2 add>twice><program>", "done 13"}},
+		{nil, "nnnnn", []string{"entry 1 <program>", "step 5 <program>", "step 9 <program>", "step 10 <program>", "done 13"}},
+		{nil, "nniiiic", []string{
+			"entry 1 <program>", "step 5 <program>", "step 9 <program>",
+			"step 6 twice><program>", "step 2 add>twice><program>", "step 3 add>twice><program>",
+			"step 7 twice><program>", "done 13",
+		}},
+		{[]int{2}, "cnnc", []string{"entry 1 <program>", "breakpoint 2 add>twice><program>", "step 3 add>twice><program>", "step 7 twice><program>", "done 13"}},
+		{[]int{2}, "cooc", []string{"entry 1 <program>", "breakpoint 2 add>twice><program>", "step 7 twice><program>", "step 10 <program>", "done 13"}},
+		// Breakpoints stop steps over calls
+		{[]int{3}, "nnnc", []string{"entry 1 <program>", "step 5 <program>", "step 9 <program>", "breakpoint 3 add>twice><program>", "done 13"}},
+	}
+
+	for _, tt := range tests {
+		s := newSession(t, program)
+		s.SetBreakpoints(tt.breakpoints)
+		s.Start(true)
+
+		var got []string
+		for idx := 0; ; idx++ {
+			event := s.Wait()
+			got = append(got, describe(event))
+			if event.Done || idx >= len(tt.commands) {
+				break
+			}
+
+			resume := map[byte]func() error{'c': s.Continue, 'i': s.StepIn, 'n': s.StepOver, 'o': s.StepOut}
+			if err := resume[tt.commands[idx]](); err != nil {
+				t.Fatalf("Error resuming: %s", err)
+			}
+		}
+
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("Wrong pauses for %v %q.\nGot=%q\nexpected=%q", tt.breakpoints, tt.commands, got, tt.expected)
+		}
+	}
+}
+
+func TestSetBreakpoints(t *testing.T) {
+	s := newSession(t, program)
+
+	verified := s.SetBreakpoints([]int{2, 4, 6, 11})
+	if fmt.Sprint(verified) != "[2 6]" {
+		t.Errorf("Wrong verified breakpoints. Got=%v, expected=[2 6]", verified)
+	}
+}
+
+func TestInspection(t *testing.T) {
+	s := newSession(t, program)
+	s.SetBreakpoints([]int{3})
+	s.Start(false)
+	defer s.Terminate()
+
+	event := s.Wait()
+	if describe(event) != "breakpoint 3 add>twice><program>" {
+		t.Fatalf("Wrong pause. Got=%q", describe(event))
+	}
+
+	scopes := []struct {
+		frame    int
+		expected string
+	}{
+		{0, "Locals: a=3 b=3 s=6; Globals: add twice"},
+		{1, "Locals: x=3; Globals: add twice"},
+		{2, "Globals: add twice"},
+	}
+
+	for _, tt := range scopes {
+		var parts []string
+		for _, scope := range Scopes(event.Frames[tt.frame]) {
+			var vars []string
+			for _, v := range scope.Vars {
+				if _, isFn := v.Value.(*object.Function); isFn {
+					vars = append(vars, v.Name)
+				} else {
+					vars = append(vars, v.Name+"="+v.Value.Inspect())
+				}
+			}
+			parts = append(parts, scope.Name+": "+strings.Join(vars, " "))
+		}
+
+		if strings.Join(parts, "; ") != tt.expected {
+			t.Errorf("Wrong scopes of frame %d. Got=%q, expected=%q", tt.frame, strings.Join(parts, "; "), tt.expected)
+		}
+	}
+
+	evaluations := []struct {
+		frame    int
+		input    string
+		expected string
+	}{
+		{0, "s * 10", "60"},
+		{1, "x + 1", "4"},
+		{1, "s", "ERROR: Unknown Identifier s"},
+		{2, "twice(5)", "20"},
+		{3, "1", "Invalid frame 3, Got 3 frames"},
+		{0, "let", "Got=EOF Expected=Identifier"},
+	}
+
+	for _, tt := range evaluations {
+		val, err := s.Evaluate(tt.frame, tt.input)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = val.Inspect()
+		}
+
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("Wrong evaluation of %q in frame %d. Got=%q, expected=%q", tt.input, tt.frame, got, tt.expected)
+		}
+	}
+
+	// Calls made by evaluations do not stop at breakpoints, nor disturb the program
+	if err := s.Continue(); err != nil {
+		t.Fatalf("Error continuing: %s", err)
+	}
+	if event := s.Wait(); describe(event) != "done 13" {
+		t.Errorf("Wrong pause after evaluations. Got=%q", describe(event))
+	}
+}
+
+func TestNotPaused(t *testing.T) {
+	s := newSession(t, "let x = 1;")
+
+	if err := s.Continue(); err == nil || err.Error() != "Program is not paused" {
+		t.Errorf("Resumed a program which is not paused. Got=%v", err)
+	}
+	if _, err := s.Evaluate(0, "x"); err == nil {
+		t.Errorf("Evaluated in a program which is not paused")
+	}
+}
+
+func TestTerminate(t *testing.T) {
+	s := newSession(t, "let loop = fn(n) { loop(n + 1) };\nloop(0)")
+	s.Start(true)
+	s.Wait()
+
+	s.Terminate()
+	event := s.Wait()
+
+	errObj, ok := event.Result.(*object.Error)
+	if !event.Done || !ok {
+		t.Fatalf("Program did not end with an Error. Got=%q", describe(event))
+	}
+	if !strings.Contains(errObj.Message, "cancel") {
+		t.Errorf("Wrong error. Got=%q", errObj.Message)
+	}
+}
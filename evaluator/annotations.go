@@ -0,0 +1,68 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+	"monkey/token"
+)
+
+// Check the args of calls against the annotations of the function's parameters, for evaluations in env
+// and the Environments it encloses. Checks are on unless turned off
+func SetTypeChecks(env *object.Environment, enabled bool) {
+	stateOf(env).skipTypeChecks = !enabled
+}
+
+// Environments created outside of the evaluator have no state and are checked
+func (s *evalState) typeChecks() bool {
+	return s == nil || !s.skipTypeChecks
+}
+
+// Check each arg whose parameter is annotated has the ObjectType of the annotation.
+// Only the outermost type is checked, the elements of an Array are not
+func checkParams(fn *object.Function, args []object.Object) *object.Error {
+	for idx, param := range fn.Parameters {
+		if param.Type == nil {
+			continue
+		}
+
+		expected := annotationType(param.Type)
+		actual := args[idx].Type()
+		if expected == "" || actual == expected || expected == object.FUNCTION_OBJ && actual == object.BUILTIN_OBJ {
+			continue
+		}
+
+		name := fn.Name
+		if name == "" {
+			name = "anonymous function"
+		}
+
+		return newError("Type mismatch for parameter %s of %s, Got=%s, expected=%s", param.Value, name, actual, expected)
+	}
+
+	return nil
+}
+
+// ObjectType of the values an annotation describes, empty for any
+func annotationType(ta *ast.TypeAnnotation) object.ObjectType {
+	switch ta.Token.Type {
+	case token.LBRACKET:
+		return object.ARRAY_OBJ
+	case token.LBRACE:
+		return object.HASH_OBJ
+	case token.FUNCTION:
+		return object.FUNCTION_OBJ
+	}
+
+	switch ta.Name {
+	case "int":
+		return object.INTEGER_OBJ
+	case "bool":
+		return object.BOOLEAN_OBJ
+	case "string":
+		return object.STRING_OBJ
+	case "null":
+		return object.NULL_OBJ
+	}
+
+	return ""
+}
@@ -2,11 +2,15 @@ package evaluator
 
 import (
 	"fmt"
+	"io"
 	"monkey/object"
 )
 
-var builtins = map[string]*object.Builtin{
-	"len": {
+// Builtins for inspecting Strings and Arrays
+func registerCore(r *Registry) {
+	r.Register("len", &object.Builtin{
+		Params: []object.Param{{Name: "value"}},
+		Doc:    "Length of a String or Array",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("Invalid number of args, Got=%d, expected=1", len(args))
@@ -22,8 +26,11 @@ var builtins = map[string]*object.Builtin{
 
 			return newError("Unsupported arg type to len(): Got=%s", args[0].Type())
 		},
-	},
-	"first": {
+	})
+
+	r.Register("first", &object.Builtin{
+		Params: []object.Param{{Name: "arr", Type: object.ARRAY_OBJ}},
+		Doc:    "First element of an Array, null if empty",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("Invalid number of args, Got%d, want=1", len(args))
@@ -40,8 +47,11 @@ var builtins = map[string]*object.Builtin{
 
 			return arr.Value[0]
 		},
-	},
-	"last": {
+	})
+
+	r.Register("last", &object.Builtin{
+		Params: []object.Param{{Name: "arr", Type: object.ARRAY_OBJ}},
+		Doc:    "Last element of an Array, null if empty",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("Invalid number of args, Got%d, want=1", len(args))
@@ -58,8 +68,11 @@ var builtins = map[string]*object.Builtin{
 
 			return arr.Value[len(arr.Value)-1]
 		},
-	},
-	"rest": {
+	})
+
+	r.Register("rest", &object.Builtin{
+		Params: []object.Param{{Name: "arr", Type: object.ARRAY_OBJ}},
+		Doc:    "New Array without the first element, null if empty",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("Invalid number of args, Got%d, want=1", len(args))
@@ -78,14 +91,49 @@ var builtins = map[string]*object.Builtin{
 				Value: arr.Value[1:len(arr.Value)],
 			}
 		},
-	},
-	"puts": {
+	})
+}
+
+// Builtins writing to the host's output and error streams
+func registerOutput(r *Registry, stdout, stderr io.Writer) {
+	values := []object.Param{{Name: "values"}}
+
+	r.Register("puts", &object.Builtin{
+		Params:   values,
+		Variadic: true,
+		Doc:      "Print each value on its own line",
+		Fn: func(args ...object.Object) object.Object {
+			for _, val := range args {
+				fmt.Fprintln(stdout, val.Inspect())
+			}
+
+			return NULL
+		},
+	})
+
+	r.Register("print", &object.Builtin{
+		Params:   values,
+		Variadic: true,
+		Doc:      "Print the values without a trailing newline",
+		Fn: func(args ...object.Object) object.Object {
+			for _, val := range args {
+				fmt.Fprint(stdout, val.Inspect())
+			}
+
+			return NULL
+		},
+	})
+
+	r.Register("eprint", &object.Builtin{
+		Params:   values,
+		Variadic: true,
+		Doc:      "Print each value on its own line to the error output",
 		Fn: func(args ...object.Object) object.Object {
 			for _, val := range args {
-				fmt.Println(val.Inspect())
+				fmt.Fprintln(stderr, val.Inspect())
 			}
 
 			return NULL
 		},
-	},
+	})
 }
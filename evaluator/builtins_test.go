@@ -0,0 +1,551 @@
+package evaluator
+
+import (
+	"bytes"
+	"context"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testBuiltin(t *testing.T, name string) *object.Builtin {
+	builtin, ok := DefaultRegistry(Config{}).Lookup(name)
+	if !ok {
+		t.Fatalf("Builtin %s is not registered", name)
+	}
+
+	return builtin
+}
+
+func TestJsonParse(t *testing.T) {
+	input := `{"name": "monkey", "tags": ["a", "b"], "version": 2, "stable": true, "parent": null}`
+
+	evaluated := testBuiltin(t, "json_parse").Fn(&object.String{Value: input})
+	hash, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("json_parse did not return Hash. Got=%T (%+v)", evaluated, evaluated)
+	}
+
+	get := func(key string) object.Object {
+		pair, ok := hash.Pairs[(&object.String{Value: key}).HashKey()]
+		if !ok {
+			t.Fatalf("Hash missing key %q", key)
+		}
+		return pair.Val
+	}
+
+	if str, ok := get("name").(*object.String); !ok || str.Value != "monkey" {
+		t.Errorf("name is not \"monkey\". Got=%+v", get("name"))
+	}
+
+	tags, ok := get("tags").(*object.Array)
+	if !ok || len(tags.Value) != 2 {
+		t.Errorf("tags is not an Array of 2 elements. Got=%+v", get("tags"))
+	}
+
+	testIntObject(t, get("version"), 2)
+	testBoolObject(t, get("stable"), true)
+	testNullObject(t, get("parent"))
+}
+
+func TestJsonParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": 1.5}`, "json_parse() only supports integer numbers, Got=1.5"},
+		{`[1, 2`, "json_parse() invalid JSON: unexpected EOF"},
+		{`1 2`, "json_parse() invalid JSON: unexpected data after top-level value"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testBuiltin(t, "json_parse").Fn(&object.String{Value: tt.input})
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("No Error returned for %q. Got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expected {
+			t.Errorf("Wrong error message. Got=%q, expected=%q", errObj.Message, tt.expected)
+		}
+	}
+}
+
+func TestJsonStringify(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`json_stringify(5)`, `5`},
+		{`json_stringify("hi")`, `"hi"`},
+		{`json_stringify([1, true, "x", [], {}])`, `[1,true,"x",[],{}]`},
+		{`json_stringify({"b": 2, "a": 1, 3: false})`, `{"3":false,"a":1,"b":2}`},
+		{`json_stringify(if (false) { 1 })`, `null`},
+		{`json_stringify({"a": [1]}, 2)`, "{\n  \"a\": [\n    1\n  ]\n}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("json_stringify did not return String for %q. Got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if str.Value != tt.expected {
+			t.Errorf("Wrong output for %q. Got=%q, expected=%q", tt.input, str.Value, tt.expected)
+		}
+	}
+}
+
+func TestJsonStringifyErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`json_stringify(fn(x) { x })`, "json_stringify() unable to serialize FUNCTION"},
+		{`json_stringify([len])`, "json_stringify() unable to serialize BUILTIN"},
+		{`json_stringify({"a": 1}, true)`, "json_stringify() indent must be an Integer or String, Got=BOOLEAN"},
+		{`json_stringify({1: "int", "1": "string"})`, `json_stringify() duplicate key "1" in Hash`},
+		{`json_stringify({true: 1, "true": 2})`, `json_stringify() duplicate key "true" in Hash`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("No Error returned for %q. Got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expected {
+			t.Errorf("Wrong error message. Got=%q, expected=%q", errObj.Message, tt.expected)
+		}
+	}
+}
+
+func TestRegex(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`regex.match("[0-9]+", "abc123")`, `true`},
+		{`regex.match("^[0-9]+$", "abc123")`, `false`},
+		{`regex.find("([a-z]+)([0-9]+)", "xx abc123 def45")`, `["abc123","abc","123",]`},
+		{`regex.find("[0-9]+", "abc")`, `null`},
+		{`regex.find("(?P<key>[a-z]+)=(?P<val>[0-9]+)", "a=1")["val"]`, `"1"`},
+		{`regex.find("(?P<key>[a-z]+)=(?P<val>[0-9]+)", "a=1")[0]`, `"a=1"`},
+		{`len(regex.find_all("[0-9]", "a1b2c3"))`, `3`},
+		{`regex.find_all("(a)(b)?", "a ab")`, `[["a","a",null,],["ab","a","b",],]`},
+		{`regex.replace("([a-z]+)=([0-9]+)", "a=1 b=2", "$2=$1")`, `"1=a 2=b"`},
+		{`regex.replace("[0-9]+", "a1 b22", fn(m) { "<" + m[0] + ">" })`, `"a<1> b<22>"`},
+		{`regex.split(", *", "a,b,  c")`, `["a","b","c",]`},
+		{`let re = regex.compile("o+"); regex.replace(re, "foo boo", "0")`, `"f0 b0"`},
+		{`regex.compile("a|b")`, `regex("a|b")`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("Wrong result for %q. Got=%+v, expected=%s", tt.input, evaluated, tt.expected)
+		}
+	}
+}
+
+func TestRegexErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`regex.match("(", "a")`, "regex.match() invalid pattern: error parsing regexp: missing closing ): `(`"},
+		{`regex.match(1, "a")`, "regex.match() pattern must be a String or Regex, Got=INTEGER"},
+		{`regex.replace("a", "a", fn(m) { 1 })`, "regex.replace() callback must return a String, Got=INTEGER"},
+		{`regex.nope`, "Module regex has no member nope"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("No Error returned for %q. Got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expected {
+			t.Errorf("Wrong error message. Got=%q, expected=%q", errObj.Message, tt.expected)
+		}
+	}
+}
+
+// Callbacks of regex.replace run as part of the evaluation, under its context and in its stack traces
+func TestRegexReplaceCallback(t *testing.T) {
+	inputs := []string{
+		`regex.replace("a", "aaa", fn(m) { time.sleep(60000); "b" })`,
+		`let loop = fn(n) { loop(n + 1) }; regex.replace("a", "aaa", fn(m) { loop(0) })`,
+	}
+
+	for _, input := range inputs {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+
+		start := time.Now()
+		evaluated := EvalContext(ctx, parser.New(lexer.New(input)).ParseProgram(), NewEnvironment(Config{}))
+		cancel()
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok || errObj.Message != "Evaluation timed out" {
+			t.Errorf("Wrong result for %q. Got=%+v, expected=Evaluation timed out", input, evaluated)
+		}
+		if elapsed := time.Since(start); elapsed > 10*time.Second {
+			t.Errorf("Callback of %q outlasted the deadline, took %s", input, elapsed)
+		}
+	}
+
+	evaluated := testEval("let upper = fn(m) { m[0] + 1 };\nregex.replace(\"a\", \"a\", upper)")
+	errObj, ok := evaluated.(*object.Error)
+	expected := "ERROR: Infix expression type mismatch: STRING + INTEGER\n    at 1:26\n    in upper, called by a builtin\n    in regex.replace, called at 2:14"
+	if !ok || errObj.Trace() != expected {
+		t.Errorf("Wrong trace of a failed callback.\nGot=%+v\nexpected=%q", evaluated, expected)
+	}
+}
+
+func TestMath(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`math.abs(-5)`, `5`},
+		{`math.abs(5)`, `5`},
+		{`math.min(3, 1, 2)`, `1`},
+		{`math.max([3, 1, 2])`, `3`},
+		{`math.pow(2, 10)`, `1024`},
+		{`math.pow(-3, 3)`, `-27`},
+		{`math.pow(5, 0)`, `1`},
+		{`math.pow(-2, 63)`, `-9223372036854775808`},
+		{`math.pow(1, 9223372036854775807)`, `1`},
+		{`math.abs(-9223372036854775807 - 1)`, `ERROR: math.abs() result overflows an Integer, Got=-9223372036854775808`},
+		{`math.abs(-9223372036854775807)`, `9223372036854775807`},
+		{`math.pow(2, 63)`, `ERROR: math.pow() result overflows an Integer, Got=2 ** 63`},
+		{`math.pow(10, 19)`, `ERROR: math.pow() result overflows an Integer, Got=10 ** 19`},
+		{`math.pow(-3, 41)`, `ERROR: math.pow() result overflows an Integer, Got=-3 ** 41`},
+		{`math.sqrt(16)`, `4`},
+		{`math.sqrt(17)`, `4`},
+		{`math.sqrt(0)`, `0`},
+		{`math.floor(7)`, `7`},
+		{`math.clamp(15, 0, 10)`, `10`},
+		{`math.clamp(-5, 0, 10)`, `0`},
+		{`math.clamp(5, 0, 10)`, `5`},
+		{`math.sqrt(-1)`, `ERROR: math.sqrt() of negative number -1`},
+		{`math.pow(2, -1)`, `ERROR: math.pow() exponent must not be negative, Got=-1`},
+		{`math.min()`, `ERROR: math.min() requires at least one Integer`},
+		{`math.abs("a")`, `ERROR: math.abs() only supports Integer's, Got=STRING`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("Wrong result for %q. Got=%+v, expected=%s", tt.input, evaluated, tt.expected)
+		}
+	}
+}
+
+func TestSeededRandom(t *testing.T) {
+	input := `[rand_int(1, 1000), rand_int(1, 1000), shuffle([1, 2, 3, 4, 5]), choice(["a", "b", "c"])]`
+
+	run := func(seed int64) string {
+		l := lexer.New(input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+
+		return Eval(program, NewEnvironment(Config{Seed: seed})).Inspect()
+	}
+
+	first := run(42)
+	if second := run(42); first != second {
+		t.Errorf("Same seed produced different values. Got=%s and %s", first, second)
+	}
+
+	if other := run(7); first == other {
+		t.Errorf("Different seeds produced the same values. Got=%s", first)
+	}
+
+	for i := 0; i < 100; i++ {
+		evaluated := testEval(`rand_int(-2, 2)`)
+		num, ok := evaluated.(*object.Integer)
+		if !ok || num.Value < -2 || num.Value > 2 {
+			t.Fatalf("rand_int out of range. Got=%+v", evaluated)
+		}
+	}
+
+	testNullObject(t, testEval(`choice([])`))
+}
+
+func TestSystemBuiltins(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(root, "in.txt"), []byte("hello"), 0o644)
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644)
+	os.Symlink(outside, filepath.Join(root, "escape"))
+	os.Symlink(filepath.Join(outside, "planted.txt"), filepath.Join(root, "dangling"))
+	t.Setenv("MONKEY_ALLOWED", "yes")
+	t.Setenv("MONKEY_DENIED", "no")
+
+	caps := Capabilities{
+		Roots: []string{root},
+		Env:   []string{"MONKEY_ALLOWED", "MONKEY_UNSET"},
+		Args:  []string{"-v", "input.json"},
+	}
+
+	tests := []struct {
+		input    string
+		caps     Capabilities
+		expected string
+	}{
+		{`read_file(path + "/in.txt")`, caps, `"hello"`},
+		{`write_file(path + "/out.txt", "data"); read_file(path + "/out.txt")`, caps, `"data"`},
+		{`list_dir(path)`, caps, `["dangling","escape","in.txt","out.txt",]`},
+		{`getenv("MONKEY_ALLOWED")`, caps, `"yes"`},
+		{`getenv("MONKEY_UNSET")`, caps, `null`},
+		{`args()`, caps, `["-v","input.json",]`},
+		{`read_file(outside + "/secret.txt")`, caps, "ERROR: read_file() access denied, " + outside + "/secret.txt is outside the allowed directories"},
+		{`read_file(path + "/escape/secret.txt")`, caps, "ERROR: read_file() access denied, " + root + "/escape/secret.txt is outside the allowed directories"},
+		{`read_file(path + "/../secret.txt")`, caps, "ERROR: read_file() access denied, " + root + "/../secret.txt is outside the allowed directories"},
+		{`write_file(path + "/dangling", "data")`, caps, "ERROR: write_file() access denied, " + root + "/dangling is a symlink to a missing file"},
+		{`read_file(path + "/dangling")`, caps, "ERROR: read_file() access denied, " + root + "/dangling is a symlink to a missing file"},
+		{`getenv("MONKEY_DENIED")`, caps, "ERROR: getenv() access denied, MONKEY_DENIED is not an allowed environment variable"},
+		{`write_file(path + "/ro.txt", "data")`, Capabilities{Roots: []string{root}, ReadOnly: true}, "ERROR: write_file() access denied, filesystem is read-only"},
+		{`read_file(path + "/in.txt")`, Capabilities{}, "ERROR: read_file() access denied, " + root + "/in.txt is outside the allowed directories"},
+		{`args()`, Capabilities{}, `[]`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+
+		env := NewEnvironment(Config{Caps: tt.caps})
+		env.Set("path", &object.String{Value: root})
+		env.Set("outside", &object.String{Value: outside})
+
+		evaluated := Eval(program, env)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("Wrong result for %q. Got=%+v, expected=%s", tt.input, evaluated, tt.expected)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(outside, "planted.txt")); err == nil {
+		t.Errorf("File written outside the allowed directories through a dangling symlink")
+	}
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func TestTime(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`time.now()`, `time("2024-03-01T12:30:00Z")`},
+		{`time.unix(time.now())`, `1709296200000`},
+		{`time.format(time.now(), "2006-01-02")`, `"2024-03-01"`},
+		{`time.format(time.from_unix(0))`, `"1970-01-01T00:00:00Z"`},
+		{`time.parse("2024-03-02", "2006-01-02")`, `time("2024-03-02T00:00:00Z")`},
+		{`time.add(time.now(), 1500)`, `time("2024-03-01T12:30:01.5Z")`},
+		{`time.diff(time.parse("2024-03-02T00:00:00Z"), time.now())`, `41400000`},
+		{`let start = time.now(); time.sleep(250); time.diff(time.now(), start)`, `250`},
+		{`time.parse("yesterday")`, `ERROR: time.parse() parsing time "yesterday" as "2006-01-02T15:04:05Z07:00": cannot parse "yesterday" as "2006"`},
+		{`time.sleep(-1)`, `ERROR: time.sleep() duration must not be negative, Got=-1`},
+		{`time.add(1, 1)`, `ERROR: time.add() only supports Time's, Got=INTEGER`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+
+		clock := &fakeClock{now: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)}
+		evaluated := Eval(program, NewEnvironment(Config{Clock: clock}))
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("Wrong result for %q. Got=%+v, expected=%s", tt.input, evaluated, tt.expected)
+		}
+	}
+}
+
+func TestSleepInterrupted(t *testing.T) {
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelTimeout()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	tests := []struct {
+		ctx      context.Context
+		expected string
+	}{
+		{timeout, "Evaluation timed out"},
+		{cancelled, "Evaluation cancelled: context canceled"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(`let wait = fn() { time.sleep(60000) }; wait()`))
+		program := p.ParseProgram()
+
+		start := time.Now()
+		evaluated := EvalContext(tt.ctx, program, NewEnvironment(Config{}))
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok || errObj.Message != tt.expected {
+			t.Errorf("Wrong result. Got=%+v, expected=%q", evaluated, tt.expected)
+		}
+		if elapsed := time.Since(start); elapsed > 10*time.Second {
+			t.Errorf("Sleep was not interrupted, took %s", elapsed)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	var out bytes.Buffer
+	r := DefaultRegistry(Config{Stdout: &out})
+
+	r.Register("http.get", &object.Builtin{
+		Params: []object.Param{{Name: "url", Type: object.STRING_OBJ}},
+		Fn: func(args ...object.Object) object.Object {
+			return &object.String{Value: "GET " + args[0].(*object.String).Value}
+		},
+	})
+	r.Register("db.query.one", &object.Builtin{
+		Fn: func(args ...object.Object) object.Object { return &object.Integer{Value: 1} },
+	})
+	r.Register("puts", &object.Builtin{
+		Fn: func(args ...object.Object) object.Object { return &object.String{Value: "overridden"} },
+	})
+	r.Remove("math")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`http.get("/users")`, `"GET /users"`},
+		{`db.query.one()`, `1`},
+		{`puts(1)`, `"overridden"`},
+		{`math.abs(1)`, `ERROR: Unknown Identifier math`},
+		{`let len = fn(x) { 0 }; len("abc")`, `0`},
+		{`http`, `<module http>`},
+		{`http.get`, `<builtin http.get(url: STRING)>`},
+		{`json_stringify`, `<builtin json_stringify(value, ?indent)>`},
+		{`math`, `ERROR: Unknown Identifier math`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+
+		evaluated := Eval(program, r.Environment())
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("Wrong result for %q. Got=%+v, expected=%s", tt.input, evaluated, tt.expected)
+		}
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("Overridden puts wrote output. Got=%q", out.String())
+	}
+
+	if _, ok := r.Lookup("math.abs"); ok {
+		t.Errorf("Removed module member is still registered")
+	}
+
+	if builtin, ok := r.Lookup("puts"); !ok || builtin.Name != "puts" {
+		t.Errorf("Lookup did not return the named builtin. Got=%+v", builtin)
+	}
+}
+
+func TestRegisterCopiesAndClashes(t *testing.T) {
+	r := NewRegistry()
+	builtin := &object.Builtin{Fn: func(args ...object.Object) object.Object { return NULL }}
+	r.Register("one", builtin)
+	r.Register("two", builtin)
+
+	one, _ := r.Lookup("one")
+	two, _ := r.Lookup("two")
+	if builtin.Name != "" || one.Name != "one" || two.Name != "two" {
+		t.Errorf("Register changed the builtin given. Got=%q, one=%q, two=%q", builtin.Name, one.Name, two.Name)
+	}
+
+	for _, name := range []string{"one.x", "one.x.y"} {
+		if err := r.RegisterFunc(name, func() int { return 1 }); err == nil || err.Error() != "cannot register "+name+", one is a builtin rather than a module" {
+			t.Errorf("Wrong error registering %s. Got=%v", name, err)
+		}
+	}
+
+	r.Register("mod.f", builtin)
+	if err := r.RegisterFunc("mod", func() int { return 1 }); err == nil || err.Error() != "cannot register mod, it is the module of mod.f" {
+		t.Errorf("Wrong error registering a module name. Got=%v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register did not panic on a clash")
+		}
+		if _, ok := r.Lookup("one"); !ok {
+			t.Errorf("Clash replaced the builtin")
+		}
+	}()
+	r.Register("one.x", builtin)
+}
+
+func TestRegistryNamesAndClone(t *testing.T) {
+	r := NewRegistry()
+	r.Register("b", &object.Builtin{})
+	r.Register("a.y", &object.Builtin{})
+	r.Register("a.x", &object.Builtin{})
+
+	names := r.Names()
+	expected := []string{"a.x", "a.y", "b"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Wrong names. Got=%v, expected=%v", names, expected)
+	}
+
+	r.Register("c.d.e", &object.Builtin{})
+	defined := r.Defined()
+	for _, name := range []string{"a", "a.x", "b", "c", "c.d", "c.d.e"} {
+		if !defined[name] {
+			t.Errorf("%s is not defined. Got=%v", name, defined)
+		}
+	}
+	if len(defined) != 7 {
+		t.Errorf("Wrong defined names. Got=%v", defined)
+	}
+	if len((*Registry)(nil).Defined()) != 0 {
+		t.Errorf("Names defined by a nil Registry")
+	}
+	r.Remove("c")
+
+	clone := r.Clone()
+	clone.Remove("a")
+	if len(clone.Names()) != 1 || len(r.Names()) != 3 {
+		t.Errorf("Clone is not independent. Got clone=%v, original=%v", clone.Names(), r.Names())
+	}
+
+	if err := r.RegisterFunc("host.add", func(a, b int) int { return a + b }); err != nil {
+		t.Fatalf("RegisterFunc returned error: %s", err)
+	}
+
+	builtin, _ := r.Lookup("host.add")
+	if builtin.Signature() != "host.add(arg0: INTEGER, arg1: INTEGER)" {
+		t.Errorf("Wrong signature. Got=%s", builtin.Signature())
+	}
+}
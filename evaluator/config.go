@@ -0,0 +1,29 @@
+package evaluator
+
+import (
+	"io"
+	"monkey/object"
+)
+
+// Interpreter configuration for builtins which depend on the host and the resources scripts may use
+type Config struct {
+	// Seed for the random builtins, the same seed reproduces the same values.
+	// Zero is a seed like any other, a Config without one always draws the same values
+	Seed   int64
+	Caps   Capabilities // Host access granted to the file and environment builtins
+	Clock  Clock        // Source of the current time for the time module, the system clock if nil
+	Stdout io.Writer    // Destination of puts and print, os.Stdout if nil
+	Stderr io.Writer    // Destination of eprint, os.Stderr if nil
+	Limits Limits       // Budget of each evaluation
+
+	SkipTypeChecks bool // Call functions without checking args against the annotations of their parameters
+}
+
+// Create the root Environment for a program with the standard builtins configured by cfg
+func NewEnvironment(cfg Config) *object.Environment {
+	env := DefaultRegistry(cfg).Environment()
+	SetLimits(env, cfg.Limits)
+	SetTypeChecks(env, !cfg.SkipTypeChecks)
+
+	return env
+}
@@ -0,0 +1,108 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+	"monkey/token"
+)
+
+// Call in progress as reported to a Debugger, or the program itself
+type StackFrame struct {
+	Function *object.Function    // Nil for the program
+	Env      *object.Environment // Variables of the call or of the catch block being evaluated, enclosing those it can see
+	Position token.Position      // Position of the statement being evaluated
+}
+
+// Name of the function of the frame, as in stack traces
+func (f *StackFrame) Name() string {
+	switch {
+	case f.Function == nil:
+		return "<program>"
+	case f.Function.Name == "":
+		return "<anonymous>"
+	}
+
+	return f.Function.Name
+}
+
+// Hook of a debugger, attached to an Environment by SetDebugger
+type Debugger interface {
+	// Called before each statement is evaluated with the calls in progress, the outermost first.
+	// The evaluation waits for it to return, so a debugger pauses the program by blocking.
+	// Evaluations made while it runs, such as of watched expressions, are not reported to it
+	Statement(stmt ast.Statement, frames []*StackFrame)
+}
+
+// Report the statements evaluated in env, and the Environments it encloses, to debugger. Nil detaches it
+func SetDebugger(env *object.Environment, debugger Debugger) {
+	stateOf(env).debugger = debugger
+}
+
+func (s *evalState) debugging() bool {
+	return s != nil && s.debugger != nil
+}
+
+// Report node to the debugger if it is a statement, blocks are not statements of their own
+func (s *evalState) statement(node ast.Node, env *object.Environment) {
+	stmt, ok := node.(ast.Statement)
+	if _, isBlock := node.(*ast.BlockStatement); !ok || isBlock || s.paused {
+		return
+	}
+
+	if len(s.frames) == 0 {
+		s.frames = append(s.frames, &StackFrame{Env: env})
+	}
+	// The Environment of a catch block encloses the one of its call
+	s.frames[len(s.frames)-1].Env = env
+	s.frames[len(s.frames)-1].Position = stmt.Pos()
+
+	s.paused = true
+	defer func() { s.paused = false }()
+
+	s.debugger.Statement(stmt, s.frames)
+}
+
+// Record the call of fn evaluated in env, replacing the innermost frame for a tail call
+func (s *evalState) pushFrame(fn *object.Function, env *object.Environment, tail bool) {
+	frame := &StackFrame{Function: fn, Env: env}
+	if tail && len(s.frames) > 0 {
+		s.frames[len(s.frames)-1] = frame
+		return
+	}
+
+	s.frames = append(s.frames, frame)
+}
+
+func (s *evalState) popFrame() {
+	if len(s.frames) > 0 {
+		s.frames = s.frames[:len(s.frames)-1]
+	}
+}
+
+// Evaluate node as if in the paused frame, seeing its locals, those of the functions it is nested in and
+// the globals. Lets bind variables of a scratch scope which is discarded afterwards.
+// Its steps and objects are not counted against the limits of the paused program
+func EvalInFrame(node ast.Node, frame *StackFrame) object.Object {
+	st := stateOf(frame.Env)
+	steps, objects := st.steps, st.objects
+	defer func() { st.steps, st.objects = steps, objects }()
+
+	locals := map[string]object.Object{}
+
+	env := frame.Env
+	for ; env.Locals() != nil; env = env.Outer() {
+		// Inner frames shadow the outer ones
+		for name, val := range env.Locals() {
+			if _, ok := locals[name]; !ok {
+				locals[name] = val
+			}
+		}
+	}
+
+	scratch := object.NewEnclosingEnvironment(env)
+	for name, val := range locals {
+		scratch.Set(name, val)
+	}
+
+	return Eval(node, scratch)
+}
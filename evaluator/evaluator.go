@@ -1,19 +1,83 @@
 package evaluator
 
 import (
+	"context"
+	"fmt"
 	"monkey/ast"
 	"monkey/object"
+	"monkey/resolver"
+	"monkey/token"
 )
 
 // Single instance values
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL  = object.NULL
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
 )
 
 // Evaluate given ast.Node based on its type
 func Eval(astNode ast.Node, env *object.Environment) object.Object {
+	return EvalContext(context.Background(), astNode, env)
+}
+
+// Evaluate given ast.Node, stopping with an Error once ctx is done or a limit set by SetLimits is exceeded.
+// Each evaluation gets a fresh budget, unless it is started from inside another evaluation on the same
+// Environment (a builtin calling back into Monkey) which it then shares the context and budget of
+func EvalContext(ctx context.Context, astNode ast.Node, env *object.Environment) (obj object.Object) {
+	defer recoverFault(&obj)
+	defer stateOf(env).begin(ctx)()
+
+	// Undefined names are reported before anything is evaluated
+	if errObj := Resolve(astNode, env); errObj != nil {
+		return errObj
+	}
+
+	return eval(astNode, env)
+}
+
+// Check the names used by astNode against its lets and the bindings of env, returning the Error of the
+// first undefined one or nil. EvalContext does this itself, callers rewriting the tree first do it beforehand
+func Resolve(astNode ast.Node, env *object.Environment) *object.Error {
+	errs := resolver.Resolve(astNode, func(name string) bool { return env.Get(name) != nil })
+	if len(errs) > 0 {
+		return &object.Error{Message: "Unknown Identifier " + errs[0].Name, Position: errs[0].Position}
+	}
+
+	return nil
+}
+
+// Turn a Go panic inside the evaluator into an internal Error, a fault of the interpreter
+// which try cannot catch as the panic has already unwound past it
+func recoverFault(obj *object.Object) {
+	if r := recover(); r != nil {
+		*obj = &object.Error{Message: fmt.Sprint(r), Internal: true}
+	}
+}
+
+func eval(astNode ast.Node, env *object.Environment) object.Object {
+	st, _ := env.State().(*evalState)
+	if err := st.step(); err != nil {
+		return withPosition(err, astNode)
+	}
+
+	if st.debugging() {
+		st.statement(astNode, env)
+	}
+
+	return withPosition(evalNode(astNode, env, st), astNode)
+}
+
+// Record the position of the innermost node an Error was produced by
+func withPosition(obj object.Object, node ast.Node) object.Object {
+	if errObj, ok := obj.(*object.Error); ok && !errObj.Position.IsValid() {
+		errObj.Position = node.Pos()
+	}
+
+	return obj
+}
+
+func evalNode(astNode ast.Node, env *object.Environment, st *evalState) object.Object {
 	switch node := astNode.(type) {
 
 	case *ast.Program:
@@ -28,23 +92,26 @@ func Eval(astNode ast.Node, env *object.Environment) object.Object {
 	case *ast.LetStatement:
 		return evalLetStatement(node, env)
 
+	case *ast.ThrowStatement:
+		return evalThrowStatement(node, env)
+
 	case *ast.ExpressionStatement:
-		return Eval(node.Expr, env)
+		return eval(node.Expr, env)
 
 	case *ast.IntLiteral:
-		return &object.Integer{Value: node.Value}
+		return st.alloc(&object.Integer{Value: node.Value})
 
 	case *ast.StringLiteral:
-		return &object.String{Value: node.Value}
+		return st.alloc(&object.String{Value: node.Value})
 
 	case *ast.BoolLiteral:
 		return getBoolObj(node.Value)
 
 	case *ast.ArrayLiteral:
-		return evalArrayLiteral(node, env)
+		return st.alloc(evalArrayLiteral(node, env))
 
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		return st.alloc(evalHashLiteral(node, env))
 
 	case *ast.IndexExpression:
 		return evalIndexExpression(node, env)
@@ -53,7 +120,7 @@ func Eval(astNode ast.Node, env *object.Environment) object.Object {
 		return evalIdentifier(node, env)
 
 	case *ast.FnLiteral:
-		return evalFnLiteral(node, env)
+		return st.alloc(evalFnLiteral(node, env))
 
 	case *ast.CallExpression:
 		return evalCallExpression(node, env)
@@ -61,11 +128,14 @@ func Eval(astNode ast.Node, env *object.Environment) object.Object {
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+
 	case *ast.PrefixExpression:
-		return evalPrefixExpression(node, env)
+		return st.alloc(evalPrefixExpression(node, env))
 
 	case *ast.InfixExpression:
-		return evalInfixExpression(node, env)
+		return st.alloc(evalInfixExpression(node, env))
 
 	default:
 		return nil
@@ -77,7 +147,7 @@ func evalProgram(statements []ast.Statement, env *object.Environment) object.Obj
 	var obj object.Object
 
 	for _, stmt := range statements {
-		obj = Eval(stmt, env)
+		obj = eval(stmt, env)
 
 		// Do not evaluate remaining statements after Return/Error objects
 		switch result := obj.(type) {
@@ -96,7 +166,7 @@ func evalBlockStatment(statements []ast.Statement, env *object.Environment) obje
 	var obj object.Object
 
 	for _, stmt := range statements {
-		obj = Eval(stmt, env)
+		obj = eval(stmt, env)
 
 		// Check for Return object in BlockStatement
 		// This should return from not just block scope but entire program
@@ -105,6 +175,11 @@ func evalBlockStatment(statements []ast.Statement, env *object.Environment) obje
 			// Any nested BlockStatements will now return this obj and return from the top level Program
 			return obj
 		}
+
+		// Errors abort the remaining statements until caught by a try
+		if isError(obj) {
+			return obj
+		}
 	}
 
 	return obj
@@ -115,7 +190,7 @@ func evalExpressions(expressions []ast.Expression, env *object.Environment) []ob
 	var results []object.Object
 
 	for _, exp := range expressions {
-		expRes := Eval(exp, env)
+		expRes := eval(exp, env)
 		if isError(expRes) {
 			return []object.Object{expRes}
 		}
@@ -128,17 +203,32 @@ func evalExpressions(expressions []ast.Expression, env *object.Environment) []ob
 
 // Bind the identifier of LetStatement with the value produces by its expression in the given Environment
 func evalLetStatement(stmt *ast.LetStatement, env *object.Environment) object.Object {
-	expVal := Eval(stmt.Value, env)
+	expVal := eval(stmt.Value, env)
 	if isError(expVal) {
 		return expVal
 	}
 
-	env.Set(stmt.Name.Value, expVal)
+	// Name anonymous functions after the first identifier they are bound to, for stack traces
+	if fn, ok := expVal.(*object.Function); ok && fn.Name == "" {
+		fn.Name = stmt.Name.Value
+	}
+
+	bind(env, stmt.Name, expVal)
 	return nil
 }
 
+// Bind the variable named by ident, a slot of the current frame for locals
+func bind(env *object.Environment, ident *ast.Identifier, val object.Object) {
+	if ident.Addr != nil {
+		env.SetLocal(ident.Addr.Depth, ident.Addr.Slot, val)
+		return
+	}
+
+	env.Set(ident.Value, val)
+}
+
 func evalReturnStatement(ret *ast.ReturnStatement, env *object.Environment) object.Object {
-	val := Eval(ret.Value, env)
+	val := eval(ret.Value, env)
 	if isError(val) {
 		return val
 	}
@@ -146,6 +236,86 @@ func evalReturnStatement(ret *ast.ReturnStatement, env *object.Environment) obje
 	return &object.Return{Value: val}
 }
 
+// Raise an Error carrying the thrown value, String values become its message
+func evalThrowStatement(throw *ast.ThrowStatement, env *object.Environment) object.Object {
+	val := eval(throw.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	msg := val.Inspect()
+	if str, ok := val.(*object.String); ok {
+		msg = str.Value
+	}
+
+	return &object.Error{Message: msg, Value: val}
+}
+
+// Evaluate the try block, handing an Error it produces to the catch block.
+// The finally block always runs afterwards, replacing the result only if it returns or raises an Error itself
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := eval(te.Block, env)
+
+	if errObj, ok := result.(*object.Error); ok && !errObj.Internal && te.Catch != nil {
+		catchEnv := object.NewFrame(env, te.CatchLocals)
+		bind(catchEnv, te.Param, caughtError(errObj))
+
+		result = eval(te.Catch, catchEnv)
+	}
+
+	if te.Finally != nil {
+		finally := eval(te.Finally, env)
+		if isError(finally) || (finally != nil && finally.Type() == object.RETURN_OBJ) {
+			return finally
+		}
+	}
+
+	return result
+}
+
+// Value bound by catch, a Hash exposing the message, value, position and stack of the Error
+func caughtError(errObj *object.Error) *object.Hash {
+	hash := &object.Hash{Pairs: map[object.HashKey]object.HashPair{}}
+	set := func(h *object.Hash, key string, val object.Object) {
+		keyObj := &object.String{Value: key}
+		h.Pairs[keyObj.HashKey()] = object.HashPair{Key: keyObj, Val: val}
+	}
+
+	value := errObj.Value
+	if value == nil {
+		value = NULL
+	}
+
+	stack := &object.Array{Value: make([]object.Object, len(errObj.Stack))}
+	for idx, frame := range errObj.Stack {
+		frameHash := &object.Hash{Pairs: map[object.HashKey]object.HashPair{}}
+		set(frameHash, "function", optionalString(frame.Function))
+		set(frameHash, "position", &object.String{Value: frame.Position.String()})
+		stack.Value[idx] = frameHash
+	}
+
+	position := object.Object(NULL)
+	if errObj.Position.IsValid() {
+		position = &object.String{Value: errObj.Position.String()}
+	}
+
+	set(hash, "message", &object.String{Value: errObj.Message})
+	set(hash, "value", value)
+	set(hash, "position", position)
+	set(hash, "stack", stack)
+
+	return hash
+}
+
+// String object for str, NULL when it is empty
+func optionalString(str string) object.Object {
+	if str == "" {
+		return NULL
+	}
+
+	return &object.String{Value: str}
+}
+
 // Evaluate the expressions in an ast.ArrayLiteral
 func evalArrayLiteral(arrAst *ast.ArrayLiteral, env *object.Environment) object.Object {
 	evaledExpr := evalExpressions(arrAst.Elements, env)
@@ -162,7 +332,7 @@ func evalHashLiteral(hashAst *ast.HashLiteral, env *object.Environment) object.O
 	}
 
 	for key, val := range hashAst.Pairs {
-		evalKey := Eval(key, env)
+		evalKey := eval(key, env)
 		if isError(evalKey) {
 			return evalKey
 		}
@@ -172,7 +342,7 @@ func evalHashLiteral(hashAst *ast.HashLiteral, env *object.Environment) object.O
 			return newError("Key is not HashAble. Got=%s", evalKey.Type())
 		}
 
-		evalVal := Eval(val, env)
+		evalVal := eval(val, env)
 		if isError(evalVal) {
 			return evalVal
 		}
@@ -189,12 +359,12 @@ func evalHashLiteral(hashAst *ast.HashLiteral, env *object.Environment) object.O
 
 // Get the element of the Array or Hash specified by the index
 func evalIndexExpression(idxExp *ast.IndexExpression, env *object.Environment) object.Object {
-	arrObj := Eval(idxExp.Left, env)
+	arrObj := eval(idxExp.Left, env)
 	if isError(arrObj) {
 		return arrObj
 	}
 
-	idxObj := Eval(idxExp.Index, env)
+	idxObj := eval(idxExp.Index, env)
 	if isError(idxObj) {
 		return idxObj
 	}
@@ -206,8 +376,11 @@ func evalIndexExpression(idxExp *ast.IndexExpression, env *object.Environment) o
 	case *object.Hash:
 		return evalHashIndex(obj, idxObj, env)
 
+	case *object.Module:
+		return evalModuleIndex(obj, idxObj)
+
 	default:
-		return newError("Only Array/Hash is index-able, Got=%s. Line %d Column %d", arrObj.Type(), idxExp.Token.Position.Line, idxExp.Token.Position.Column)
+		return newError("Only Array/Hash/Module is index-able, Got=%s. Line %d Column %d", arrObj.Type(), idxExp.Token.Position.Line, idxExp.Token.Position.Column)
 	}
 }
 
@@ -226,6 +399,20 @@ func evalHashIndex(hash *object.Hash, idxObj object.Object, env *object.Environm
 
 }
 
+func evalModuleIndex(module *object.Module, idxObj object.Object) object.Object {
+	name, ok := idxObj.(*object.String)
+	if !ok {
+		return newError("Module member is not a String, Got=%s", idxObj.Type())
+	}
+
+	member, ok := module.Members[name.Value]
+	if !ok {
+		return newError("Module %s has no member %s", module.Name, name.Value)
+	}
+
+	return member
+}
+
 func evalArrayIndex(arr *object.Array, idxObj object.Object, env *object.Environment) object.Object {
 	idx, ok := idxObj.(*object.Integer)
 	if !ok {
@@ -241,14 +428,17 @@ func evalArrayIndex(arr *object.Array, idxObj object.Object, env *object.Environ
 
 // Get the Object bound to the given Identifier
 func evalIdentifier(ident *ast.Identifier, env *object.Environment) object.Object {
-	val := env.Get(ident.Value)
-	if val != nil {
-		return val
+	var val object.Object
+	if ident.Addr != nil {
+		// Locals are nil until their let has been evaluated
+		val = env.Local(ident.Addr.Depth, ident.Addr.Slot)
+	} else {
+		// Builtins are bound in the outermost scope of the Environment by its Registry
+		val = env.Get(ident.Value)
 	}
 
-	//Check for identifier as a builtin function
-	if builtin, ok := builtins[ident.Value]; ok {
-		return builtin
+	if val != nil {
+		return val
 	}
 
 	return newError("Unknown Identifier %s", ident.Value)
@@ -257,7 +447,7 @@ func evalIdentifier(ident *ast.Identifier, env *object.Environment) object.Objec
 func evalCallExpression(callExp *ast.CallExpression, env *object.Environment) object.Object {
 	// Identifier or FnLiteral should produce a Function object
 	// Builtin function also possible
-	fnObj := Eval(callExp.Fn, env)
+	fnObj := eval(callExp.Fn, env)
 	if isError(fnObj) {
 		return fnObj
 	}
@@ -267,36 +457,120 @@ func evalCallExpression(callExp *ast.CallExpression, env *object.Environment) ob
 		return evalFnArgs[0]
 	}
 
-	return applyFunction(fnObj, evalFnArgs)
+	return callFunction(fnObj, evalFnArgs, callExp, env)
+}
+
+// Apply the function called in env, adding the frame of the call to an Error propagating out of it
+func callFunction(fnObj object.Object, args []object.Object, callExp *ast.CallExpression, env *object.Environment) object.Object {
+	st, _ := env.State().(*evalState)
+	val := applyFunction(fnObj, args, st.context())
+	if errObj, ok := val.(*object.Error); ok {
+		switch fn := fnObj.(type) {
+		case *object.Function:
+			errObj.Stack = append(errObj.Stack, object.Frame{Function: fn.Name, Position: callExp.Pos()})
+		case *object.Builtin:
+			errObj.Stack = append(errObj.Stack, object.Frame{Function: fn.Name, Position: callExp.Pos()})
+		}
+	}
+
+	return val
+}
+
+// Apply a function given to a builtin, adding a frame without a call position to an Error propagating out of it
+func applyCallback(fnObj object.Object, args []object.Object, ctx context.Context) object.Object {
+	val := applyFunction(fnObj, args, ctx)
+	if errObj, ok := val.(*object.Error); ok {
+		switch fn := fnObj.(type) {
+		case *object.Function:
+			errObj.Stack = append(errObj.Stack, object.Frame{Function: fn.Name})
+		case *object.Builtin:
+			errObj.Stack = append(errObj.Stack, object.Frame{Function: fn.Name})
+		}
+	}
+
+	return val
+}
+
+// Call a Function or Builtin object with the given args from outside of an evaluation
+func ApplyFunction(fn object.Object, args []object.Object) (obj object.Object) {
+	defer recoverFault(&obj)
+
+	if fnObj, ok := fn.(*object.Function); ok {
+		defer stateOf(fnObj.Env).begin(context.Background())()
+	}
+
+	return applyFunction(fn, args, context.Background())
 }
 
 // Create the new Environment to be use inside function execution.
-// Bind args of function call to function parameters in new env
-func applyFunction(obj object.Object, args []object.Object) object.Object {
+// Bind args of function call to function parameters in new env.
+// Builtins which block are given ctx, the context of the evaluation making the call
+func applyFunction(obj object.Object, args []object.Object, ctx context.Context) object.Object {
 
 	switch fn := obj.(type) {
 	case *object.Function:
-		// Create new Environment to be used when evaluating function
-		// Environment which the function was declared in (closure) used as enclosing env
-		fnEnv := object.NewEnclosingEnvironment(fn.Env)
-
-		if len(fn.Parameters) != len(args) {
-			return newError("Call expression does not match number of Function paramters: args=%d, params=%d", len(args), len(fn.Parameters))
+		st, _ := fn.Env.State().(*evalState)
+		if err := st.enter(); err != nil {
+			return err
 		}
-
-		for idx, param := range fn.Parameters {
-			fnEnv.Set(param.Value, args[idx])
+		defer st.leave()
+
+		// Frames of a debugged evaluation follow the calls, a tail call replaces the frame of the call it ends
+		framed := false
+		if st.debugging() {
+			defer func() {
+				if framed {
+					st.popFrame()
+				}
+			}()
 		}
 
-		evalFn := Eval(fn.Body, fnEnv)
-		returnVal, ok := evalFn.(*object.Return)
-		if ok {
-			return returnVal.Value
+		// Calls in tail position come back as a tailCall which replaces the current call,
+		// looping here rather than recursing so tail recursion runs in constant Go stack
+		var tailPos token.Position
+		for {
+			if len(fn.Parameters) != len(args) {
+				return tailFrame(newError("Call expression does not match number of Function paramters: args=%d, params=%d", len(args), len(fn.Parameters)), fn, tailPos)
+			}
+
+			if st.typeChecks() {
+				if err := checkParams(fn, args); err != nil {
+					return tailFrame(err, fn, tailPos)
+				}
+			}
+
+			// Create new frame to be used when evaluating function
+			// Environment which the function was declared in (closure) used as enclosing env
+			// Parameters occupy the first slots
+			fnEnv := object.NewFrame(fn.Env, fn.Locals)
+			for idx, param := range fn.Parameters {
+				bind(fnEnv, param, args[idx])
+			}
+
+			if st.debugging() {
+				st.pushFrame(fn, fnEnv, framed)
+				framed = true
+			}
+
+			evalFn := evalTail(fn.Body, fnEnv, true)
+			if call, ok := evalFn.(*tailCall); ok {
+				fn, args, tailPos = call.fn, call.args, call.pos
+				continue
+			}
+
+			returnVal, ok := evalFn.(*object.Return)
+			if ok {
+				return returnVal.Value
+			}
+
+			return tailFrame(evalFn, fn, tailPos)
 		}
 
-		return evalFn
-
 	case *object.Builtin:
+		if fn.ContextFn != nil {
+			return fn.ContextFn(ctx, args...)
+		}
+
 		val := fn.Fn(args...)
 		return val
 
@@ -309,6 +583,7 @@ func evalFnLiteral(fn *ast.FnLiteral, env *object.Environment) object.Object {
 	fnObj := &object.Function{
 		Parameters: fn.Parameters,
 		Body:       fn.Body,
+		Locals:     fn.Locals,
 		// Lexical env the function was declared in, creating a closure
 		// Includes the identifier this fnObj is bound allowing recursive calls
 		Env: env,
@@ -319,17 +594,17 @@ func evalFnLiteral(fn *ast.FnLiteral, env *object.Environment) object.Object {
 
 // Evaluate ast.IfExpression
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	cond := Eval(ie.Condition, env)
+	cond := eval(ie.Condition, env)
 	if isError(cond) {
 		return cond
 	}
 
 	if isTruthy(cond) {
-		return Eval(ie.Consequence, env)
+		return eval(ie.Consequence, env)
 	}
 
 	if !isTruthy(cond) && ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return eval(ie.Alternative, env)
 	}
 
 	// Condition is false and no else clause provided
@@ -339,7 +614,7 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 // Evaluate the prefix expression (! -)
 // If operator is not valid an Error is returned
 func evalPrefixExpression(prefix *ast.PrefixExpression, env *object.Environment) object.Object {
-	operand := Eval(prefix.Operand, env)
+	operand := eval(prefix.Operand, env)
 	if isError(operand) {
 		return operand
 	}
@@ -357,12 +632,12 @@ func evalPrefixExpression(prefix *ast.PrefixExpression, env *object.Environment)
 
 // Evaluate the given infix expression
 func evalInfixExpression(infix *ast.InfixExpression, env *object.Environment) object.Object {
-	right := Eval(infix.Right, env)
+	right := eval(infix.Right, env)
 	if isError(right) {
 		return right
 	}
 
-	left := Eval(infix.Left, env)
+	left := eval(infix.Left, env)
 	if isError(left) {
 		return left
 	}
@@ -1,11 +1,17 @@
 package evaluator
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"monkey/ast"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"monkey/token"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Test for expressions which evaluate to Booleans
@@ -115,11 +121,15 @@ func TestFunctionObject(t *testing.T) {
 		t.Fatalf("parameter is not 'x'. got=%q", fn.Parameters[0])
 	}
 
-	expectedBody := "(x + 2)"
+	expectedBody := "{ (x + 2) }"
 
 	if fn.Body.String() != expectedBody {
 		t.Fatalf("body is not %q. got=%q", expectedBody, fn.Body.String())
 	}
+
+	if fn.Inspect() != "fn(x) { (x + 2) }" {
+		t.Errorf("Inspect is not valid source. got=%q", fn.Inspect())
+	}
 }
 
 func TestFunctionApplication(t *testing.T) {
@@ -370,7 +380,7 @@ func testEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
-	env := object.NewEnvironment()
+	env := NewEnvironment(Config{})
 
 	return Eval(program, env)
 }
@@ -414,3 +424,557 @@ func testIntObject(t *testing.T, obj object.Object, val int64) bool {
 
 	return true
 }
+
+func TestOutputBuiltins(t *testing.T) {
+	tests := []struct {
+		input  string
+		stdout string
+		stderr string
+	}{
+		{`puts(1, "a")`, "1\n\"a\"\n", ""},
+		{`print(1); print(2, 3)`, "123", ""},
+		{`eprint("oops")`, "", "\"oops\"\n"},
+		{`let f = fn(x) { puts(x); x * 2 }; print(f(4))`, "4\n8", ""},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		Eval(program, NewEnvironment(Config{Stdout: &stdout, Stderr: &stderr}))
+
+		if stdout.String() != tt.stdout {
+			t.Errorf("Wrong stdout for %q. Got=%q, expected=%q", tt.input, stdout.String(), tt.stdout)
+		}
+
+		if stderr.String() != tt.stderr {
+			t.Errorf("Wrong stderr for %q. Got=%q, expected=%q", tt.input, stderr.String(), tt.stderr)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	expired, cancelExpired := context.WithTimeout(context.Background(), 0)
+	defer cancelExpired()
+
+	runaway, cancelRunaway := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelRunaway()
+
+	tests := []struct {
+		input    string
+		ctx      context.Context
+		limits   Limits
+		expected string
+	}{
+		// Tail recursion loops in constant stack, under the default limits only the context stops it
+		{"let f = fn(x) { f(x) }; f(1)", runaway, Limits{}, "Evaluation timed out"},
+		// Calls which are not tail calls nest
+		{"let f = fn(x) { 1 + f(x) }; f(1)", context.Background(), Limits{}, "Call depth limit exceeded, more than 10000 nested function calls"},
+		{"let f = fn(x) { 1 + f(x) }; f(1)", context.Background(), Limits{MaxDepth: 10}, "Call depth limit exceeded, more than 10 nested function calls"},
+		{"let f = fn(x) { f(x + 1) }; f(1)", context.Background(), Limits{MaxSteps: 100}, "Step limit exceeded, evaluated more than 100 steps"},
+		{"let f = fn(x) { f(x) }; f(1)", context.Background(), Limits{MaxSteps: 100000}, "Step limit exceeded, evaluated more than 100000 steps"},
+		{"[1, 2, 3, 4, 5]", context.Background(), Limits{MaxObjects: 5}, "Object limit exceeded, allocated more than 5 objects"},
+		{"1 + 2", cancelled, Limits{}, "Evaluation cancelled: context canceled"},
+		{"1 + 2", expired, Limits{}, "Evaluation timed out"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		evaluated := EvalContext(tt.ctx, program, NewEnvironment(Config{Limits: tt.limits}))
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("No error for %q. Got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expected {
+			t.Errorf("Wrong error for %q. Got=%q, expected=%q", tt.input, errObj.Message, tt.expected)
+		}
+	}
+}
+
+// Errors of the context fail every step after the first, so a try catching one cannot keep the program going
+func TestContextErrorsNotCaught(t *testing.T) {
+	inputs := []string{
+		"let loop = fn(n) { try { 1 + 2 * 3 } catch (e) { 0 }; loop(n + 1) }; loop(0)",
+		"let loop = fn(n) { try { time.sleep(10) } catch (e) { 0 }; loop(n + 1) }; loop(0)",
+	}
+
+	for _, input := range inputs {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+
+		start := time.Now()
+		evaluated := EvalContext(ctx, parser.New(lexer.New(input)).ParseProgram(), NewEnvironment(Config{}))
+		cancel()
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok || errObj.Message != "Evaluation timed out" {
+			t.Errorf("Wrong result for %q. Got=%+v, expected=Evaluation timed out", input, evaluated)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("Evaluation of %q outlasted its deadline, took %s", input, elapsed)
+		}
+	}
+}
+
+func TestLimitsResetEachEvaluation(t *testing.T) {
+	env := NewEnvironment(Config{Limits: Limits{MaxSteps: 50, MaxDepth: 5}})
+
+	for _, input := range []string{"let f = fn(x) { x * 2 };", "f(1)", "f(2)", "f(3)"} {
+		l := lexer.New(input)
+		p := parser.New(l)
+		evaluated := Eval(p.ParseProgram(), env)
+
+		if isError(evaluated) {
+			t.Fatalf("Evaluation of %q exceeded a limit: %s", input, evaluated.Inspect())
+		}
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	// Calls are not in tail position, which would replace the frames of their callers
+	input := `let inner = fn(x) { x + true };
+let outer = fn(x) { [inner(x)] };
+let run = fn(f) { [f(1)] };
+run(fn(x) { [outer(x)] })`
+
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("Evaluation did not produce an Error. Got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if errObj.Message != "Infix expression type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("Wrong error message. Got=%q", errObj.Message)
+	}
+
+	if errObj.Position.Line != 1 || errObj.Position.Column != 23 {
+		t.Errorf("Wrong error position. Got=%s, expected=1:23", errObj.Position)
+	}
+
+	expected := []object.Frame{
+		{Function: "inner", Position: token.Position{Line: 2, Column: 27}},
+		{Function: "outer", Position: token.Position{Line: 4, Column: 19}},
+		{Function: "", Position: token.Position{Line: 3, Column: 21}},
+		{Function: "run", Position: token.Position{Line: 4, Column: 4}},
+	}
+
+	if len(errObj.Stack) != len(expected) {
+		t.Fatalf("Wrong number of frames. Got=%+v, expected=%+v", errObj.Stack, expected)
+	}
+
+	for idx, frame := range expected {
+		got := errObj.Stack[idx]
+		if got.Function != frame.Function || got.Position.Line != frame.Position.Line || got.Position.Column != frame.Position.Column {
+			t.Errorf("Wrong frame %d. Got=%+v, expected=%+v", idx, got, frame)
+		}
+	}
+
+	builtinErr, ok := testEval(`let f = fn() { len(1) }; f()`).(*object.Error)
+	if !ok || len(builtinErr.Stack) != 2 || builtinErr.Stack[0].Function != "len" || builtinErr.Stack[1].Function != "f" {
+		t.Errorf("Builtin missing from stack. Got=%+v", builtinErr)
+	}
+}
+
+func TestErrorTrace(t *testing.T) {
+	evaluated := testEval("let f = fn(x) { 1 + f(x) }; f(1)")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("Evaluation did not produce an Error. Got=%T (%+v)", evaluated, evaluated)
+	}
+
+	lines := strings.Split(errObj.Trace(), "\n")
+	expected := []string{
+		"ERROR: Call depth limit exceeded, more than 10000 nested function calls",
+		"    at 1:22",
+		"    in f, called at 1:22",
+	}
+
+	for idx, line := range expected {
+		if lines[idx] != line {
+			t.Errorf("Wrong trace line %d. Got=%q, expected=%q", idx, lines[idx], line)
+		}
+	}
+
+	// The message, its position, 10 innermost frames, the elision, then 10 outermost frames
+	if len(lines) != 2+21 || lines[12] != "    ... 9981 more frames" || lines[22] != "    in f, called at 1:30" {
+		t.Errorf("Deep stack not elided. Got=%q", lines[10:])
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { 1 + true } catch (e) { 2 }`, 2},
+		{`try { throw "bad"; 1 } catch (e) { 2 }`, 2},
+		{`try { if (true) { throw "bad"; 1 }; 3 } catch (e) { 2 }`, 2},
+		{`try { throw "bad" } catch (e) { e.message }`, "bad"},
+		{`try { throw 42 } catch (e) { e.value }`, 42},
+		{`try { throw 42 } catch (e) { e.message }`, "42"},
+		{`try { 1 + true } catch (e) { e.message }`, "Infix expression type mismatch: INTEGER + BOOLEAN"},
+		{`try { len(1) } catch (e) { e.message }`, "Unsupported arg type to len(): Got=INTEGER"},
+		{`try { 1 / 0 } catch (e) { e.message }`, "Division by zero: 1 / 0"},
+		{`try { 1 + true } catch (e) { e.position }`, "1:9"},
+		{`let f = fn() { throw "deep" }; try { f() } catch (e) { e.stack[0].function }`, "f"},
+		{`let f = fn() { throw "deep" }; try { f() } catch (e) { len(e.stack) }`, 1},
+		{`let x = 1; try { 1 } finally { let x = 2 }; x`, 2},
+		{`try { 1 } catch (e) { 2 } finally { 3 }`, 1},
+		{`let f = fn() { try { return 1 } finally { 2 } }; f()`, 1},
+		{`let f = fn() { try { return 1 } finally { return 2 } }; f()`, 2},
+		{`try { try { throw "inner" } finally { 1 } } catch (e) { e.message }`, "inner"},
+		{`try { try { throw "inner" } catch (e) { throw e.message + "!" } } catch (e) { e.message }`, "inner!"},
+		{`let f = fn(x) { 1 + f(x) }; try { f(1) } catch (e) { "recovered" }`, "recovered"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("Wrong result for %q. Got=%+v, expected=%q", tt.input, evaluated, expected)
+			}
+		}
+	}
+}
+
+func TestParameterAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any // Integer result or error message
+	}{
+		{`let f = fn(a: int, b: string, c: bool) { a }; f(1, "b", true)`, 1},
+		{`let f = fn(a: [int], h: {string: int}) { len(a) }; f([1, 2], {})`, 2},
+		{`let f = fn(g: fn(int) -> int) { g(3) }; f(fn(x) { x * 2 })`, 6},
+		{`let f = fn(g: fn(string) -> int) { g("abc") }; f(len)`, 3},
+		{`let f = fn(a: any, b) { 1 }; f([1], "b")`, 1},
+		{`let f = fn(a: null) { 1 }; f(if (false) { 1 })`, 1},
+		{`let f = fn(a: int) { a }; f("one")`, "Type mismatch for parameter a of f, Got=STRING, expected=INTEGER"},
+		{`let f = fn(a, b: [int]) { a }; f(1, {})`, "Type mismatch for parameter b of f, Got=HASH, expected=ARRAY"},
+		{`fn(g: fn()) { 1 }(2)`, "Type mismatch for parameter g of anonymous function, Got=INTEGER, expected=FUNCTION"},
+		{`let f = fn(n: int) { if (n == 0) { "done" } else { f(n - 1) } }; f(3)`, nil},
+		{`let f = fn(n: int) { if (n == 0) { f("done") } else { f(n - 1) } }; f(3)`, "Type mismatch for parameter n of f, Got=STRING, expected=INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("Wrong result for %q. Got=%+v, expected error %q", tt.input, evaluated, expected)
+			}
+		}
+	}
+}
+
+func TestTypeChecksDisabled(t *testing.T) {
+	env := NewEnvironment(Config{SkipTypeChecks: true})
+	program := parser.New(lexer.New(`let f = fn(a: int) { a }; f("one")`)).ParseProgram()
+
+	if str, ok := Eval(program, env).(*object.String); !ok || str.Value != "one" {
+		t.Errorf("Annotation checked while disabled")
+	}
+}
+
+func TestUncaughtErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`throw "bad"`, "bad"},
+		{`try { throw "bad" } finally { 1 }`, "bad"},
+		{`try { 1 } finally { throw "finally" }`, "finally"},
+		{`try { throw "a" } catch (e) { throw "b" }`, "b"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("No error for %q", tt.input)
+			continue
+		}
+
+		if errObj.Message != tt.expected || errObj.Internal {
+			t.Errorf("Wrong error for %q. Got=%+v, expected=%q", tt.input, errObj, tt.expected)
+		}
+	}
+}
+
+func TestInternalFaults(t *testing.T) {
+	r := DefaultRegistry(Config{})
+	r.Register("explode", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		panic("unexpected state")
+	}})
+
+	l := lexer.New(`try { explode() } catch (e) { "caught" }`)
+	p := parser.New(l)
+	evaluated := Eval(p.ParseProgram(), r.Environment())
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("Internal fault was caught. Got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if !errObj.Internal || errObj.Inspect() != "INTERNAL ERROR: unexpected state" {
+		t.Errorf("Fault not reported as internal. Got=%q", errObj.Inspect())
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let count = fn(n, acc) { if (n == 0) { acc } else { count(n - 1, acc + 1) } }; count(1000000, 0)", 1000000},
+		{"let count = fn(n, acc) { if (n == 0) { return acc; } return count(n - 1, acc + 1); }; count(1000000, 0)", 1000000},
+		{"let count = fn(n) { if (n > 0) { return count(n - 1) }; 7 }; count(1000000)", 7},
+		{`let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+if (even(1000001)) { 1 } else { 0 }`, 0},
+		{"let sum = fn(arr, acc) { if (len(arr) == 0) { acc } else { sum(rest(arr), acc + first(arr)) } }; sum([1, 2, 3, 4], 0)", 10},
+		{"let f = fn(x) { len([x]) }; f(5)", 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if isError(evaluated) {
+			t.Errorf("Error evaluating %q: %s", tt.input, evaluated.Inspect())
+			continue
+		}
+
+		testIntObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestTailCallErrorStack(t *testing.T) {
+	input := `let fail = fn(x) { x + true };
+let loop = fn(n) { if (n == 0) { fail(n) } else { loop(n - 1) } };
+[loop(3)]`
+
+	errObj, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("Evaluation did not produce an Error")
+	}
+
+	// Frames of the calls replaced by tail calls are gone
+	if len(errObj.Stack) != 2 || errObj.Stack[0].Function != "fail" || errObj.Stack[1].Function != "loop" {
+		t.Errorf("Wrong stack. Got=%+v", errObj.Stack)
+	}
+}
+
+func TestLexicalScoping(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 1; let f = fn() { let x = 2; x }; f() + x", 3},
+		{"let adder = fn(a) { fn(b) { fn(c) { a + b + c } } }; adder(1)(2)(3)", 6},
+		{"let f = fn() { let fact = fn(n) { if (n == 0) { 1 } else { n * fact(n - 1) } }; fact(5) }; f()", 120},
+		{"let f = fn() { let a = fn() { b() }; let b = fn() { 4 }; a() }; f()", 4},
+		{"let f = fn(x) { if (x > 0) { let y = x * 2 }; y }; f(3)", 6},
+		{"let counter = fn() { let n = 10; fn() { n } }; let c = counter(); c() + c()", 20},
+		{"let x = 5; try { throw x } catch (x) { let y = x.value; y + 1 }", 6},
+		{"let f = fn(e) { try { throw 1 } catch (e) { 0 }; e }; f(9)", 9},
+		{"let f = fn(a, a) { a }; f(1, 2)", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if isError(evaluated) {
+			t.Errorf("Error evaluating %q: %s", tt.input, evaluated.Inspect())
+			continue
+		}
+
+		testIntObject(t, evaluated, tt.expected)
+	}
+
+	unbound := []struct {
+		input    string
+		expected string
+	}{
+		// Reported before evaluation, nothing is printed
+		{`puts("never"); let f = fn() { missing }`, "Unknown Identifier missing"},
+		// Declared but evaluated before its let
+		{"let f = fn() { let a = b; let b = 1; a }; f()", "Unknown Identifier b"},
+	}
+
+	for _, tt := range unbound {
+		var out bytes.Buffer
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		evaluated := Eval(p.ParseProgram(), NewEnvironment(Config{Stdout: &out}))
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok || errObj.Message != tt.expected {
+			t.Errorf("Wrong result for %q. Got=%+v, expected=%q", tt.input, evaluated, tt.expected)
+		}
+
+		if out.Len() != 0 {
+			t.Errorf("Program with undefined names was evaluated. Output=%q", out.String())
+		}
+	}
+}
+
+// Records the stack at each statement, as "line:frame>frame" with the outermost frame first
+type recordingDebugger struct {
+	stops []string
+}
+
+func (d *recordingDebugger) Statement(stmt ast.Statement, frames []*StackFrame) {
+	names := make([]string, len(frames))
+	for idx, frame := range frames {
+		names[idx] = frame.Name()
+	}
+	d.stops = append(d.stops, fmt.Sprintf("%d:%s", stmt.Pos().Line, strings.Join(names, ">")))
+}
+
+func TestDebuggerHook(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = 1;\na + 1", []string{"1:<program>", "2:<program>"}},
+		{`let add = fn(a, b) {
+  a + b
+};
+add(1, fn() { 2 }())`, []string{"1:<program>", "4:<program>", "4:<program>><anonymous>", "2:<program>>add"}},
+		// Tail calls replace the frame of the caller
+		{`let loop = fn(n) {
+  if (n == 0) { n } else { loop(n - 1) }
+};
+loop(2)`, []string{"1:<program>", "4:<program>", "2:<program>>loop", "2:<program>>loop", "2:<program>>loop", "2:<program>>loop", "2:<program>>loop", "2:<program>>loop"}},
+		// Frames are popped by failed calls
+		{"let f = fn(x: int) { x };\nf(true);", []string{"1:<program>", "2:<program>"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		env := NewEnvironment(Config{})
+
+		debugger := &recordingDebugger{}
+		SetDebugger(env, debugger)
+		Eval(p.ParseProgram(), env)
+
+		if strings.Join(debugger.stops, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("Wrong statements for %q. Got=%q, expected=%q", tt.input, debugger.stops, tt.expected)
+		}
+	}
+}
+
+func TestEvalInFrame(t *testing.T) {
+	input := `let g = 10;
+let outer = fn(a) {
+  let inner = fn(b) {
+    let c = a + b;
+    c
+  };
+  let r = inner(2);
+  r
+};
+outer(1)`
+
+	var frames []*StackFrame
+	hook := debuggerFunc(func(stmt ast.Statement, stack []*StackFrame) {
+		if stmt.Pos().Line == 5 {
+			frames = append([]*StackFrame{}, stack...)
+		}
+	})
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	env := NewEnvironment(Config{})
+	SetDebugger(env, hook)
+	Eval(p.ParseProgram(), env)
+
+	if len(frames) != 3 {
+		t.Fatalf("Wrong number of frames. Got=%d, expected=3", len(frames))
+	}
+
+	tests := []struct {
+		frame    int
+		input    string
+		expected int64
+	}{
+		{2, "a + b + c + g", 16},
+		{1, "a + g", 11},
+		{0, "g", 10},
+		{2, "let g = 1; g + c", 4},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		testIntObject(t, EvalInFrame(p.ParseProgram(), frames[tt.frame]), tt.expected)
+	}
+
+	// Lets made in a frame do not leak into the program
+	if val := env.Get("g"); val.(*object.Integer).Value != 10 {
+		t.Errorf("Let in frame changed the global. Got=%s", val.Inspect())
+	}
+}
+
+func TestEvalInFrameCatch(t *testing.T) {
+	input := `let f = fn(n) {
+  try { throw "oops" } catch (e) {
+    let m = e.message;
+    m
+  }
+};
+f(1)`
+
+	var got []string
+	hook := debuggerFunc(func(stmt ast.Statement, stack []*StackFrame) {
+		if stmt.Pos().Line == 4 {
+			p := parser.New(lexer.New(`[m, e.message, n]`))
+			got = append(got, EvalInFrame(p.ParseProgram(), stack[len(stack)-1]).Inspect())
+		}
+	})
+
+	env := NewEnvironment(Config{})
+	SetDebugger(env, hook)
+	Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+
+	if len(got) != 1 || got[0] != `["oops","oops",1,]` {
+		t.Errorf("Catch block not seen by its frame. Got=%q", got)
+	}
+}
+
+// Evaluating in a frame leaves the budget of the paused program as it was
+func TestEvalInFrameBudget(t *testing.T) {
+	input := "let a = 1;\nlet b = a + 1;\nb * 2"
+	expensive := parser.New(lexer.New("1 + 1 + 1 + 1 + 1 + 1 + 1 + 1")).ParseProgram()
+
+	hook := debuggerFunc(func(stmt ast.Statement, stack []*StackFrame) {
+		if val := EvalInFrame(expensive, stack[len(stack)-1]); val.Inspect() != "8" {
+			t.Errorf("Wrong result in frame. Got=%s", val.Inspect())
+		}
+	})
+
+	env := NewEnvironment(Config{})
+	SetLimits(env, Limits{MaxSteps: 40, MaxObjects: 30})
+	SetDebugger(env, hook)
+
+	testIntObject(t, Eval(parser.New(lexer.New(input)).ParseProgram(), env), 4)
+}
+
+type debuggerFunc func(stmt ast.Statement, frames []*StackFrame)
+
+func (f debuggerFunc) Statement(stmt ast.Statement, frames []*StackFrame) { f(stmt, frames) }
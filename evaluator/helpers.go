@@ -108,6 +108,9 @@ func evalIntegerInfix(operator string, left, right object.Object) object.Object
 	case "*":
 		return &object.Integer{Value: leftInt.Value * rightInt.Value}
 	case "/":
+		if rightInt.Value == 0 {
+			return newError("Division by zero: %d / 0", leftInt.Value)
+		}
 		return &object.Integer{Value: leftInt.Value / rightInt.Value}
 
 		/* Boolean Producing Infix Expressions */
@@ -0,0 +1,234 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"monkey/object"
+	"sort"
+	"strings"
+)
+
+func registerJson(r *Registry) {
+	r.Register("json_parse", &object.Builtin{
+		Params: []object.Param{{Name: "str", Type: object.STRING_OBJ}},
+		Doc:    "Parse a JSON document into Hash, Array, String, Integer, Boolean and null values",
+		Fn:     jsonParse,
+	})
+
+	r.Register("json_stringify", &object.Builtin{
+		Params:   []object.Param{{Name: "value"}, {Name: "indent"}},
+		Optional: 1,
+		Doc:      "Serialize a value as JSON, indented by a number of spaces or an indent String",
+		Fn:       jsonStringify,
+	})
+}
+
+// Parse a JSON document into the equivalent Monkey object.
+// Objects become Hash, arrays become Array, numbers must be integral as there are no floats
+func jsonParse(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("Invalid number of args, Got=%d, expected=1", len(args))
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newError("json_parse() only supports String's, Got=%s", args[0].Type())
+	}
+
+	dec := json.NewDecoder(strings.NewReader(str.Value))
+	dec.UseNumber()
+
+	var val any
+	if err := dec.Decode(&val); err != nil {
+		return newError("json_parse() invalid JSON: %s", err)
+	}
+
+	// Only a single JSON value may be present in the document
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return newError("json_parse() invalid JSON: unexpected data after top-level value")
+	}
+
+	return fromJson(val)
+}
+
+// Convert a value produced by encoding/json into a Monkey object
+func fromJson(val any) object.Object {
+	switch v := val.(type) {
+	case nil:
+		return NULL
+
+	case bool:
+		return getBoolObj(v)
+
+	case string:
+		return &object.String{Value: v}
+
+	case json.Number:
+		num, err := v.Int64()
+		if err != nil {
+			return newError("json_parse() only supports integer numbers, Got=%s", v.String())
+		}
+
+		return &object.Integer{Value: num}
+
+	case []any:
+		arr := &object.Array{Value: make([]object.Object, 0, len(v))}
+		for _, elem := range v {
+			obj := fromJson(elem)
+			if isError(obj) {
+				return obj
+			}
+
+			arr.Value = append(arr.Value, obj)
+		}
+
+		return arr
+
+	case map[string]any:
+		hash := &object.Hash{Pairs: map[object.HashKey]object.HashPair{}}
+		for key, elem := range v {
+			obj := fromJson(elem)
+			if isError(obj) {
+				return obj
+			}
+
+			keyObj := &object.String{Value: key}
+			hash.Pairs[keyObj.HashKey()] = object.HashPair{Key: keyObj, Val: obj}
+		}
+
+		return hash
+
+	default:
+		return newError("json_parse() unsupported JSON value %T", val)
+	}
+}
+
+// Serialize a Monkey object as JSON, optionally indented by the given number of spaces (or indent string)
+func jsonStringify(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("Invalid number of args, Got=%d, expected=1 or 2", len(args))
+	}
+
+	indent := ""
+	if len(args) == 2 {
+		switch arg := args[1].(type) {
+		case *object.Integer:
+			if arg.Value < 0 {
+				return newError("json_stringify() indent must not be negative, Got=%d", arg.Value)
+			}
+			indent = strings.Repeat(" ", int(arg.Value))
+
+		case *object.String:
+			indent = arg.Value
+
+		default:
+			return newError("json_stringify() indent must be an Integer or String, Got=%s", args[1].Type())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := writeJson(&out, args[0]); err != nil {
+		return err
+	}
+
+	if indent == "" {
+		return &object.String{Value: out.String()}
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, out.Bytes(), "", indent); err != nil {
+		return newError("json_stringify() failed to indent output: %s", err)
+	}
+
+	return &object.String{Value: pretty.String()}
+}
+
+// Write the compact JSON encoding of obj to out.
+// Hash keys are unordered, so they are sorted to keep the output deterministic
+func writeJson(out *bytes.Buffer, obj object.Object) *object.Error {
+	switch o := obj.(type) {
+	case *object.Null:
+		out.WriteString("null")
+
+	case *object.Boolean, *object.Integer:
+		out.WriteString(o.Inspect())
+
+	case *object.String:
+		writeJsonString(out, o.Value)
+
+	case *object.Array:
+		out.WriteString("[")
+		for idx, elem := range o.Value {
+			if idx > 0 {
+				out.WriteString(",")
+			}
+
+			if err := writeJson(out, elem); err != nil {
+				return err
+			}
+		}
+		out.WriteString("]")
+
+	case *object.Hash:
+		keys := make([]string, 0, len(o.Pairs))
+		vals := make(map[string]object.Object, len(o.Pairs))
+		for _, pair := range o.Pairs {
+			key, err := jsonKey(pair.Key)
+			if err != nil {
+				return err
+			}
+
+			// Keys such as 1 and "1" would be written twice, losing one of the values once parsed
+			if _, ok := vals[key]; ok {
+				return newError("json_stringify() duplicate key %q in Hash", key)
+			}
+
+			keys = append(keys, key)
+			vals[key] = pair.Val
+		}
+		sort.Strings(keys)
+
+		out.WriteString("{")
+		for idx, key := range keys {
+			if idx > 0 {
+				out.WriteString(",")
+			}
+
+			writeJsonString(out, key)
+			out.WriteString(":")
+			if err := writeJson(out, vals[key]); err != nil {
+				return err
+			}
+		}
+		out.WriteString("}")
+
+	default:
+		return newError("json_stringify() unable to serialize %s", obj.Type())
+	}
+
+	return nil
+}
+
+// JSON object keys must be strings, Integer and Boolean keys use their literal form,
+// so they collide with the String keys of the same text
+func jsonKey(key object.Object) (string, *object.Error) {
+	switch k := key.(type) {
+	case *object.String:
+		return k.Value, nil
+	case *object.Integer, *object.Boolean:
+		return k.Inspect(), nil
+	default:
+		return "", newError("json_stringify() unable to serialize Hash key %s", key.Type())
+	}
+}
+
+func writeJsonString(out *bytes.Buffer, str string) {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(str)
+
+	// Encode terminates each value with a newline
+	out.Truncate(out.Len() - 1)
+}
@@ -0,0 +1,156 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"monkey/object"
+)
+
+// Limits on the resources a single evaluation may use.
+// Zero values mean unlimited, except MaxDepth which falls back to DefaultMaxDepth.
+// MaxObjects does not count what builtins allocate, a single regex.split or json_parse may create any number of
+// objects. Their calls are steps, so MaxSteps and the context of the evaluation still bound such scripts.
+// Tail calls do not nest, so a loop written as tail recursion, such as let f = fn(x) { f(x) }; f(1), never
+// reaches MaxDepth. Like any loop it runs until MaxSteps or the context stops it, which the defaults do not
+type Limits struct {
+	MaxSteps   int64 // Number of AST nodes evaluated
+	MaxDepth   int   // Number of nested function calls
+	MaxObjects int64 // Number of objects allocated by literals and operators
+}
+
+// Call depth allowed when Limits.MaxDepth is zero, well before deep recursion could overflow the Go stack
+const DefaultMaxDepth = 10000
+
+// Number of steps between checks of the context for cancellation
+const cancelCheckInterval = 256
+
+// State of the evaluations in an Environment, shared with every Environment it encloses
+type evalState struct {
+	ctx     context.Context
+	limits  Limits
+	steps   int64
+	objects int64
+	depth   int
+	active  int   // Number of evaluations in progress, only the outermost resets the budget
+	done    error // Error of the context once it is seen done, failing every later step so try cannot outlast it
+
+	skipTypeChecks bool
+
+	debugger Debugger
+	frames   []*StackFrame // Calls in progress while debugging, the program first
+	paused   bool          // Whether the debugger is handling a statement
+}
+
+// Apply limits to evaluations in env and the Environments it encloses
+func SetLimits(env *object.Environment, limits Limits) {
+	stateOf(env).limits = limits
+}
+
+// Get the evaluation state of env, attaching a new one if there is none
+func stateOf(env *object.Environment) *evalState {
+	st, ok := env.State().(*evalState)
+	if !ok {
+		st = &evalState{ctx: context.Background()}
+		env.SetState(st)
+	}
+
+	return st
+}
+
+// Start an evaluation, returning the func which ends it
+func (s *evalState) begin(ctx context.Context) func() {
+	if s.active == 0 {
+		s.ctx = ctx
+		s.steps, s.objects, s.depth = 0, 0, 0
+		s.done = nil
+		s.frames = nil
+	}
+
+	s.active++
+	return func() { s.active-- }
+}
+
+// Count a step of the evaluation, periodically checking for cancellation.
+// Environments created outside of the evaluator have no state and are not limited
+func (s *evalState) step() *object.Error {
+	if s == nil {
+		return nil
+	}
+
+	if s.done != nil {
+		return contextError(s.done)
+	}
+
+	s.steps++
+	if s.limits.MaxSteps > 0 && s.steps > s.limits.MaxSteps {
+		return newError("Step limit exceeded, evaluated more than %d steps", s.limits.MaxSteps)
+	}
+
+	if s.steps%cancelCheckInterval == 1 {
+		if err := s.ctx.Err(); err != nil {
+			s.done = err
+			return contextError(err)
+		}
+	}
+
+	return nil
+}
+
+// Context of the evaluation, for builtins which block
+func (s *evalState) context() context.Context {
+	if s == nil {
+		return context.Background()
+	}
+
+	return s.ctx
+}
+
+// Error ending an evaluation whose context is done with err
+func contextError(err error) *object.Error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return newError("Evaluation timed out")
+	}
+
+	return newError("Evaluation cancelled: %s", err)
+}
+
+// Enter a function call
+func (s *evalState) enter() *object.Error {
+	if s == nil {
+		return nil
+	}
+
+	maxDepth := s.limits.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxDepth
+	}
+
+	if s.depth >= maxDepth {
+		return newError("Call depth limit exceeded, more than %d nested function calls", maxDepth)
+	}
+
+	s.depth++
+	return nil
+}
+
+// Leave a function call entered successfully
+func (s *evalState) leave() {
+	if s != nil {
+		s.depth--
+	}
+}
+
+// Count an object produced by an expression, passing it through.
+// Errors and the single instance values are not allocations
+func (s *evalState) alloc(obj object.Object) object.Object {
+	if s == nil || obj == nil || isError(obj) || obj == NULL || obj == TRUE || obj == FALSE {
+		return obj
+	}
+
+	s.objects++
+	if s.limits.MaxObjects > 0 && s.objects > s.limits.MaxObjects {
+		return newError("Object limit exceeded, allocated more than %d objects", s.limits.MaxObjects)
+	}
+
+	return obj
+}
@@ -0,0 +1,313 @@
+package evaluator
+
+import (
+	"math"
+	"math/rand"
+	"monkey/object"
+)
+
+func registerMath(r *Registry) {
+	x := object.Param{Name: "x", Type: object.INTEGER_OBJ}
+	values := object.Param{Name: "values"}
+
+	r.Register("math.abs", &object.Builtin{
+		Params: []object.Param{x},
+		Doc:    "Absolute value",
+		Fn:     mathAbs,
+	})
+
+	r.Register("math.min", &object.Builtin{
+		Params:   []object.Param{values},
+		Variadic: true,
+		Doc:      "Smallest of the Integers or of an Array of Integers",
+		Fn:       mathMin,
+	})
+
+	r.Register("math.max", &object.Builtin{
+		Params:   []object.Param{values},
+		Variadic: true,
+		Doc:      "Largest of the Integers or of an Array of Integers",
+		Fn:       mathMax,
+	})
+
+	r.Register("math.pow", &object.Builtin{
+		Params: []object.Param{{Name: "base", Type: object.INTEGER_OBJ}, {Name: "exp", Type: object.INTEGER_OBJ}},
+		Doc:    "Raise base to a non-negative exponent",
+		Fn:     mathPow,
+	})
+
+	r.Register("math.sqrt", &object.Builtin{
+		Params: []object.Param{x},
+		Doc:    "Integer square root, rounded down",
+		Fn:     mathSqrt,
+	})
+
+	r.Register("math.floor", &object.Builtin{
+		Params: []object.Param{x},
+		Doc:    "Largest whole number not greater than x",
+		Fn:     mathFloor,
+	})
+
+	r.Register("math.clamp", &object.Builtin{
+		Params: []object.Param{x, {Name: "low", Type: object.INTEGER_OBJ}, {Name: "high", Type: object.INTEGER_OBJ}},
+		Doc:    "Restrict x to the range [low, high]",
+		Fn:     mathClamp,
+	})
+}
+
+func mathAbs(args ...object.Object) object.Object {
+	nums, err := integerArgs("math.abs", 1, args)
+	if err != nil {
+		return err
+	}
+
+	if nums[0] == math.MinInt64 {
+		return newError("math.abs() result overflows an Integer, Got=%d", nums[0])
+	}
+
+	if nums[0] < 0 {
+		return &object.Integer{Value: -nums[0]}
+	}
+
+	return args[0]
+}
+
+// Smallest of the given Integers, or of the elements of a single Array argument
+func mathMin(args ...object.Object) object.Object {
+	return integerFold("min", args, func(a, b int64) bool { return a < b })
+}
+
+// Largest of the given Integers, or of the elements of a single Array argument
+func mathMax(args ...object.Object) object.Object {
+	return integerFold("max", args, func(a, b int64) bool { return a > b })
+}
+
+// Raise base to a non-negative exponent
+func mathPow(args ...object.Object) object.Object {
+	nums, err := integerArgs("math.pow", 2, args)
+	if err != nil {
+		return err
+	}
+
+	base, exp := nums[0], nums[1]
+	if exp < 0 {
+		return newError("math.pow() exponent must not be negative, Got=%d", exp)
+	}
+
+	result, ok := int64(1), true
+	for exp > 0 && ok {
+		if exp&1 == 1 {
+			result, ok = mulInt64(result, base)
+		}
+
+		// Squaring past the last bit could overflow needlessly
+		if exp >>= 1; exp > 0 && ok {
+			base, ok = mulInt64(base, base)
+		}
+	}
+
+	if !ok {
+		return newError("math.pow() result overflows an Integer, Got=%d ** %d", nums[0], nums[1])
+	}
+
+	return &object.Integer{Value: result}
+}
+
+// Product of a and b, false when it does not fit in an Integer
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+
+	product := a * b
+	if product/b != a || a == -1 && b == math.MinInt64 || b == -1 && a == math.MinInt64 {
+		return 0, false
+	}
+
+	return product, true
+}
+
+// Integer square root, rounded down
+func mathSqrt(args ...object.Object) object.Object {
+	nums, err := integerArgs("math.sqrt", 1, args)
+	if err != nil {
+		return err
+	}
+
+	n := nums[0]
+	if n < 0 {
+		return newError("math.sqrt() of negative number %d", n)
+	}
+
+	// Binary search for the largest root where root*root <= n
+	lo, hi := int64(0), int64(3037000499) // floor(sqrt(MaxInt64))
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		if mid*mid <= n {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return &object.Integer{Value: lo}
+}
+
+// Integers are already whole numbers so floor is the identity
+func mathFloor(args ...object.Object) object.Object {
+	if _, err := integerArgs("math.floor", 1, args); err != nil {
+		return err
+	}
+
+	return args[0]
+}
+
+// Restrict a value to the range [low, high]
+func mathClamp(args ...object.Object) object.Object {
+	nums, err := integerArgs("math.clamp", 3, args)
+	if err != nil {
+		return err
+	}
+
+	val, low, high := nums[0], nums[1], nums[2]
+	if low > high {
+		return newError("math.clamp() low is greater than high: %d > %d", low, high)
+	}
+
+	switch {
+	case val < low:
+		return &object.Integer{Value: low}
+	case val > high:
+		return &object.Integer{Value: high}
+	default:
+		return args[0]
+	}
+}
+
+// Validate that exactly count Integer args were given, returning their values
+func integerArgs(name string, count int, args []object.Object) ([]int64, *object.Error) {
+	if len(args) != count {
+		return nil, newError("Invalid number of args, Got=%d, expected=%d", len(args), count)
+	}
+
+	nums := make([]int64, len(args))
+	for idx, arg := range args {
+		num, ok := arg.(*object.Integer)
+		if !ok {
+			return nil, newError("%s() only supports Integer's, Got=%s", name, arg.Type())
+		}
+
+		nums[idx] = num.Value
+	}
+
+	return nums, nil
+}
+
+// Select the Integer which is preferred over all others
+func integerFold(name string, args []object.Object, prefer func(a, b int64) bool) object.Object {
+	if len(args) == 1 {
+		if arr, ok := args[0].(*object.Array); ok {
+			args = arr.Value
+		}
+	}
+
+	if len(args) == 0 {
+		return newError("math.%s() requires at least one Integer", name)
+	}
+
+	var best *object.Integer
+	for _, arg := range args {
+		num, ok := arg.(*object.Integer)
+		if !ok {
+			return newError("math.%s() only supports Integer's, Got=%s", name, arg.Type())
+		}
+
+		if best == nil || prefer(num.Value, best.Value) {
+			best = num
+		}
+	}
+
+	return best
+}
+
+/*** Random ***/
+
+// Random builtins share a single source so that a seeded program is reproducible
+func registerRandom(r *Registry, rng *rand.Rand) {
+	r.Register("rand_int", &object.Builtin{
+		Params: []object.Param{{Name: "low", Type: object.INTEGER_OBJ}, {Name: "high", Type: object.INTEGER_OBJ}},
+		Doc:    "Random Integer in the inclusive range [low, high]",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("Invalid number of args, Got=%d, expected=2", len(args))
+			}
+
+			low, lowOk := args[0].(*object.Integer)
+			high, highOk := args[1].(*object.Integer)
+			if !lowOk || !highOk {
+				return newError("rand_int() only supports Integer's, Got=%s, %s", args[0].Type(), args[1].Type())
+			}
+
+			if low.Value > high.Value {
+				return newError("rand_int() low is greater than high: %d > %d", low.Value, high.Value)
+			}
+
+			span := high.Value - low.Value + 1
+			if span <= 0 {
+				// Range is wider than int64 can represent, sample until a value lands inside it
+				for {
+					val := int64(rng.Uint64())
+					if val >= low.Value && val <= high.Value {
+						return &object.Integer{Value: val}
+					}
+				}
+			}
+
+			return &object.Integer{Value: low.Value + rng.Int63n(span)}
+		},
+	})
+
+	r.Register("shuffle", &object.Builtin{
+		Params: []object.Param{{Name: "arr", Type: object.ARRAY_OBJ}},
+		Doc:    "New Array with the elements in a random order",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("Invalid number of args, Got=%d, expected=1", len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("shuffle() only supports Array's, Got=%s", args[0].Type())
+			}
+
+			shuffled := make([]object.Object, len(arr.Value))
+			copy(shuffled, arr.Value)
+			rng.Shuffle(len(shuffled), func(i, j int) {
+				shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+			})
+
+			return &object.Array{Value: shuffled}
+		},
+	})
+
+	r.Register("choice", &object.Builtin{
+		Params: []object.Param{{Name: "arr", Type: object.ARRAY_OBJ}},
+		Doc:    "Random element of the Array, null if empty",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("Invalid number of args, Got=%d, expected=1", len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("choice() only supports Array's, Got=%s", args[0].Type())
+			}
+
+			if len(arr.Value) == 0 {
+				return NULL
+			}
+
+			return arr.Value[rng.Intn(len(arr.Value))]
+		},
+	})
+}
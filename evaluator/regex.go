@@ -0,0 +1,244 @@
+package evaluator
+
+import (
+	"context"
+	"monkey/object"
+	"regexp"
+	"strings"
+)
+
+func registerRegex(r *Registry) {
+	pattern := object.Param{Name: "pattern"}
+	str := object.Param{Name: "str", Type: object.STRING_OBJ}
+
+	r.Register("regex.compile", &object.Builtin{
+		Params: []object.Param{{Name: "pattern", Type: object.STRING_OBJ}},
+		Doc:    "Compile a pattern into a reusable Regex",
+		Fn:     regexCompile,
+	})
+
+	r.Register("regex.match", &object.Builtin{
+		Params: []object.Param{pattern, str},
+		Doc:    "Report whether the String contains a match of the pattern",
+		Fn:     regexMatch,
+	})
+
+	r.Register("regex.find", &object.Builtin{
+		Params: []object.Param{pattern, str},
+		Doc:    "Capture groups of the first match, null if there is none",
+		Fn:     regexFind,
+	})
+
+	r.Register("regex.find_all", &object.Builtin{
+		Params: []object.Param{pattern, str},
+		Doc:    "Capture groups of every match",
+		Fn:     regexFindAll,
+	})
+
+	r.Register("regex.replace", &object.Builtin{
+		Params:    []object.Param{pattern, str, {Name: "replacement"}},
+		Doc:       "Replace every match with a template String or the result of a function given the capture groups",
+		Fn:        func(args ...object.Object) object.Object { return regexReplace(context.Background(), args...) },
+		ContextFn: regexReplace,
+	})
+
+	r.Register("regex.split", &object.Builtin{
+		Params: []object.Param{pattern, str},
+		Doc:    "Split the String around each match",
+		Fn:     regexSplit,
+	})
+}
+
+// Compile a pattern into a Regex object which can be reused by the other regex functions
+func regexCompile(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("Invalid number of args, Got=%d, expected=1", len(args))
+	}
+
+	re, err := toRegexp("compile", args[0])
+	if err != nil {
+		return err
+	}
+
+	return &object.Regex{Value: re}
+}
+
+// Report whether the string contains any match of the pattern
+func regexMatch(args ...object.Object) object.Object {
+	re, str, err := regexArgs("match", args)
+	if err != nil {
+		return err
+	}
+
+	return getBoolObj(re.MatchString(str))
+}
+
+// Get the capture groups of the first match, NULL if there is no match
+func regexFind(args ...object.Object) object.Object {
+	re, str, err := regexArgs("find", args)
+	if err != nil {
+		return err
+	}
+
+	loc := re.FindStringSubmatchIndex(str)
+	if loc == nil {
+		return NULL
+	}
+
+	return regexGroups(re, str, loc)
+}
+
+// Get the capture groups of every match
+func regexFindAll(args ...object.Object) object.Object {
+	re, str, err := regexArgs("find_all", args)
+	if err != nil {
+		return err
+	}
+
+	matches := &object.Array{Value: []object.Object{}}
+	for _, loc := range re.FindAllStringSubmatchIndex(str, -1) {
+		matches.Value = append(matches.Value, regexGroups(re, str, loc))
+	}
+
+	return matches
+}
+
+// Replace every match of the pattern.
+// The replacement is either a template string ($1, ${name}) or a function called with the capture groups,
+// as part of the evaluation whose context is ctx
+func regexReplace(ctx context.Context, args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return newError("Invalid number of args, Got=%d, expected=3", len(args))
+	}
+
+	re, str, err := regexArgs("replace", args[:2])
+	if err != nil {
+		return err
+	}
+
+	switch repl := args[2].(type) {
+	case *object.String:
+		return &object.String{Value: re.ReplaceAllString(str, repl.Value)}
+
+	case *object.Function, *object.Builtin:
+		var out strings.Builder
+		last := 0
+
+		for _, loc := range re.FindAllStringSubmatchIndex(str, -1) {
+			val := applyCallback(repl, []object.Object{regexGroups(re, str, loc)}, ctx)
+			if isError(val) {
+				return val
+			}
+
+			replStr, ok := val.(*object.String)
+			if !ok {
+				return newError("regex.replace() callback must return a String, Got=%s", val.Type())
+			}
+
+			out.WriteString(str[last:loc[0]])
+			out.WriteString(replStr.Value)
+			last = loc[1]
+		}
+		out.WriteString(str[last:])
+
+		return &object.String{Value: out.String()}
+
+	default:
+		return newError("regex.replace() replacement must be a String or Function, Got=%s", args[2].Type())
+	}
+}
+
+// Split the string around each match of the pattern
+func regexSplit(args ...object.Object) object.Object {
+	re, str, err := regexArgs("split", args)
+	if err != nil {
+		return err
+	}
+
+	parts := &object.Array{Value: []object.Object{}}
+	for _, part := range re.Split(str, -1) {
+		parts.Value = append(parts.Value, &object.String{Value: part})
+	}
+
+	return parts
+}
+
+// Validate the (pattern, string) arguments shared by most regex functions
+func regexArgs(name string, args []object.Object) (*regexp.Regexp, string, *object.Error) {
+	if len(args) != 2 {
+		return nil, "", newError("Invalid number of args, Got=%d, expected=2", len(args))
+	}
+
+	re, err := toRegexp(name, args[0])
+	if err != nil {
+		return nil, "", err
+	}
+
+	str, ok := args[1].(*object.String)
+	if !ok {
+		return nil, "", newError("regex.%s() only supports matching String's, Got=%s", name, args[1].Type())
+	}
+
+	return re, str.Value, nil
+}
+
+// Patterns may be given as a precompiled Regex or a String to compile
+func toRegexp(name string, obj object.Object) (*regexp.Regexp, *object.Error) {
+	switch pattern := obj.(type) {
+	case *object.Regex:
+		return pattern.Value, nil
+
+	case *object.String:
+		re, err := regexp.Compile(pattern.Value)
+		if err != nil {
+			return nil, newError("regex.%s() invalid pattern: %s", name, err)
+		}
+
+		return re, nil
+
+	default:
+		return nil, newError("regex.%s() pattern must be a String or Regex, Got=%s", name, obj.Type())
+	}
+}
+
+// Build the capture groups of a single match from its submatch index pairs.
+// Patterns without named groups produce an Array [match, group1, ...].
+// Patterns with named groups produce a Hash keyed by group index as well as group name.
+// Groups which did not participate in the match are NULL
+func regexGroups(re *regexp.Regexp, str string, loc []int) object.Object {
+	groups := make([]object.Object, len(loc)/2)
+	for idx := range groups {
+		start, end := loc[2*idx], loc[2*idx+1]
+		if start < 0 {
+			groups[idx] = NULL
+			continue
+		}
+
+		groups[idx] = &object.String{Value: str[start:end]}
+	}
+
+	named := false
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			named = true
+			break
+		}
+	}
+
+	if !named {
+		return &object.Array{Value: groups}
+	}
+
+	hash := &object.Hash{Pairs: map[object.HashKey]object.HashPair{}}
+	for idx, name := range re.SubexpNames() {
+		idxKey := &object.Integer{Value: int64(idx)}
+		hash.Pairs[idxKey.HashKey()] = object.HashPair{Key: idxKey, Val: groups[idx]}
+
+		if name != "" {
+			nameKey := &object.String{Value: name}
+			hash.Pairs[nameKey.HashKey()] = object.HashPair{Key: nameKey, Val: groups[idx]}
+		}
+	}
+
+	return hash
+}
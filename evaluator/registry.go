@@ -0,0 +1,192 @@
+package evaluator
+
+import (
+	"fmt"
+	"math/rand"
+	"monkey/object"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Registry of the builtins available to a program.
+// A dotted name registers a member of a module, "http.get" is called from Monkey as http.get(url)
+type Registry struct {
+	builtins map[string]*object.Builtin // Keyed by qualified name
+}
+
+// Create a Registry without any builtins
+func NewRegistry() *Registry {
+	return &Registry{
+		builtins: map[string]*object.Builtin{},
+	}
+}
+
+// Create a Registry holding the standard builtins, configured for the host by cfg
+func DefaultRegistry(cfg Config) *Registry {
+	r := NewRegistry()
+
+	registerCore(r)
+	registerJson(r)
+	registerRegex(r)
+	registerMath(r)
+	registerRandom(r, rand.New(rand.NewSource(cfg.Seed)))
+	registerSystem(r, cfg.Caps)
+
+	clock := cfg.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+	registerTime(r, clock)
+
+	stdout := cfg.Stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+
+	stderr := cfg.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	registerOutput(r, stdout, stderr)
+
+	return r
+}
+
+// Add a copy of builtin under its qualified name, replacing any builtin already registered with that name.
+// Panics when the name clashes with a module, a builtin "x" cannot be registered along with "x.y"
+func (r *Registry) Register(name string, builtin *object.Builtin) {
+	if err := r.clash(name); err != nil {
+		panic(err)
+	}
+
+	copied := *builtin
+	copied.Name = name
+	r.builtins[name] = &copied
+}
+
+// Wrap a Go function with object.WrapFunc and register it, failing when the name clashes with a module
+func (r *Registry) RegisterFunc(name string, fn any) error {
+	if err := r.clash(name); err != nil {
+		return err
+	}
+
+	builtin, err := object.WrapFunc(fn)
+	if err != nil {
+		return err
+	}
+
+	r.Register(name, builtin)
+	return nil
+}
+
+// Error when name would be both a builtin and a module, the module of a registered builtin or a builtin itself
+func (r *Registry) clash(name string) error {
+	path := strings.Split(name, ".")
+	for idx := 1; idx < len(path); idx++ {
+		if module := strings.Join(path[:idx], "."); r.builtins[module] != nil {
+			return fmt.Errorf("cannot register %s, %s is a builtin rather than a module", name, module)
+		}
+	}
+
+	for qualified := range r.builtins {
+		if strings.HasPrefix(qualified, name+".") {
+			return fmt.Errorf("cannot register %s, it is the module of %s", name, qualified)
+		}
+	}
+
+	return nil
+}
+
+// Remove a builtin, or every member of a module when given the module name
+func (r *Registry) Remove(name string) {
+	delete(r.builtins, name)
+
+	for qualified := range r.builtins {
+		if strings.HasPrefix(qualified, name+".") {
+			delete(r.builtins, qualified)
+		}
+	}
+}
+
+// Get the builtin registered under the qualified name
+func (r *Registry) Lookup(name string) (*object.Builtin, bool) {
+	builtin, ok := r.builtins[name]
+	return builtin, ok
+}
+
+// Sorted qualified names of every registered builtin
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.builtins))
+	for name := range r.builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Qualified names of every registered builtin and of every module containing them, which a program can refer
+// to without binding them. Empty for a nil registry
+func (r *Registry) Defined() map[string]bool {
+	defined := map[string]bool{}
+	if r == nil {
+		return defined
+	}
+
+	for name := range r.builtins {
+		path := strings.Split(name, ".")
+		for idx := range path {
+			defined[strings.Join(path[:idx+1], ".")] = true
+		}
+	}
+
+	return defined
+}
+
+// Copy of the registry which can be modified independently
+func (r *Registry) Clone() *Registry {
+	clone := NewRegistry()
+	for name, builtin := range r.builtins {
+		copied := *builtin
+		clone.builtins[name] = &copied
+	}
+
+	return clone
+}
+
+// Create the root Environment for a program with the registered builtins bound in an outermost scope.
+// Program bindings shadow builtins rather than replace them
+func (r *Registry) Environment() *object.Environment {
+	scope := object.NewEnvironment()
+	modules := map[string]*object.Module{}
+
+	for _, name := range r.Names() {
+		path := strings.Split(name, ".")
+		if len(path) == 1 {
+			scope.Set(name, r.builtins[name])
+			continue
+		}
+
+		module, ok := modules[path[0]]
+		if !ok {
+			module = &object.Module{Name: path[0], Members: map[string]object.Object{}}
+			modules[path[0]] = module
+			scope.Set(path[0], module)
+		}
+
+		// Nested namespaces (a.b.c) become modules within modules
+		for _, part := range path[1 : len(path)-1] {
+			member, ok := module.Members[part].(*object.Module)
+			if !ok {
+				member = &object.Module{Name: module.Name + "." + part, Members: map[string]object.Object{}}
+				module.Members[part] = member
+			}
+			module = member
+		}
+
+		module.Members[path[len(path)-1]] = r.builtins[name]
+	}
+
+	return object.NewEnclosingEnvironment(scope)
+}
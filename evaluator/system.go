@@ -0,0 +1,216 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"monkey/object"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Capabilities granted to a script for accessing the host system.
+// The zero value grants nothing: no filesystem access, no environment variables and no args
+type Capabilities struct {
+	Roots    []string // Directories (and their descendants) which files may be accessed in
+	ReadOnly bool     // Deny write_file even inside Roots
+	Env      []string // Environment variables which getenv may read
+	Args     []string // Values returned by args()
+}
+
+// Resolve path to an absolute path with symlinks evaluated, which must be inside one of the Roots.
+// Symlinks are followed before checking so a link cannot be used to escape the sandbox
+func (c Capabilities) resolve(path string) (string, error) {
+	real, err := realPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	for _, root := range c.Roots {
+		realRoot, err := realPath(root)
+		if err != nil {
+			continue
+		}
+
+		rel, err := filepath.Rel(realRoot, real)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return real, nil
+		}
+	}
+
+	return "", fmt.Errorf("access denied, %s is outside the allowed directories", path)
+}
+
+// Absolute path with symlinks evaluated.
+// A path which does not exist yet (a file to be written) resolves through its parent directory.
+// A dangling symlink is refused, writing through it would create its target wherever it points
+func realPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	real, err := filepath.EvalSymlinks(abs)
+	if errors.Is(err, fs.ErrNotExist) {
+		if info, lstatErr := os.Lstat(abs); lstatErr == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return "", fmt.Errorf("access denied, %s is a symlink to a missing file", path)
+		}
+
+		dir, err := filepath.EvalSymlinks(filepath.Dir(abs))
+		if err != nil {
+			return "", err
+		}
+
+		return filepath.Join(dir, filepath.Base(abs)), nil
+	}
+
+	return real, err
+}
+
+// Builtins for file, environment and argument access, gated by caps
+func registerSystem(r *Registry, caps Capabilities) {
+	pathParam := object.Param{Name: "path", Type: object.STRING_OBJ}
+
+	r.Register("read_file", &object.Builtin{
+		Params: []object.Param{pathParam},
+		Doc:    "Contents of a file as a String",
+		Fn: func(args ...object.Object) object.Object {
+			path, err := stringArgs("read_file", 1, args)
+			if err != nil {
+				return err
+			}
+
+			real, resolveErr := caps.resolve(path[0])
+			if resolveErr != nil {
+				return newError("read_file() %s", resolveErr)
+			}
+
+			content, readErr := os.ReadFile(real)
+			if readErr != nil {
+				return newError("read_file() %s", readErr)
+			}
+
+			return &object.String{Value: string(content)}
+		},
+	})
+
+	r.Register("write_file", &object.Builtin{
+		Params: []object.Param{pathParam, {Name: "content", Type: object.STRING_OBJ}},
+		Doc:    "Create or truncate a file with the given String contents",
+		Fn: func(args ...object.Object) object.Object {
+			strs, err := stringArgs("write_file", 2, args)
+			if err != nil {
+				return err
+			}
+
+			if caps.ReadOnly {
+				return newError("write_file() access denied, filesystem is read-only")
+			}
+
+			real, resolveErr := caps.resolve(strs[0])
+			if resolveErr != nil {
+				return newError("write_file() %s", resolveErr)
+			}
+
+			if writeErr := os.WriteFile(real, []byte(strs[1]), 0o644); writeErr != nil {
+				return newError("write_file() %s", writeErr)
+			}
+
+			return NULL
+		},
+	})
+
+	r.Register("list_dir", &object.Builtin{
+		Params: []object.Param{pathParam},
+		Doc:    "Sorted names of the entries in a directory",
+		Fn: func(args ...object.Object) object.Object {
+			path, err := stringArgs("list_dir", 1, args)
+			if err != nil {
+				return err
+			}
+
+			real, resolveErr := caps.resolve(path[0])
+			if resolveErr != nil {
+				return newError("list_dir() %s", resolveErr)
+			}
+
+			entries, readErr := os.ReadDir(real)
+			if readErr != nil {
+				return newError("list_dir() %s", readErr)
+			}
+
+			names := make([]string, len(entries))
+			for idx, entry := range entries {
+				names[idx] = entry.Name()
+			}
+			sort.Strings(names)
+
+			return stringArray(names)
+		},
+	})
+
+	r.Register("getenv", &object.Builtin{
+		Params: []object.Param{{Name: "name", Type: object.STRING_OBJ}},
+		Doc:    "Value of an allowed environment variable, null if it is unset",
+		Fn: func(args ...object.Object) object.Object {
+			name, err := stringArgs("getenv", 1, args)
+			if err != nil {
+				return err
+			}
+
+			if !slices.Contains(caps.Env, name[0]) {
+				return newError("getenv() access denied, %s is not an allowed environment variable", name[0])
+			}
+
+			val, ok := os.LookupEnv(name[0])
+			if !ok {
+				return NULL
+			}
+
+			return &object.String{Value: val}
+		},
+	})
+
+	r.Register("args", &object.Builtin{
+		Params: []object.Param{},
+		Doc:    "Arguments passed to the script by the host",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newError("Invalid number of args, Got=%d, expected=0", len(args))
+			}
+
+			return stringArray(caps.Args)
+		},
+	})
+}
+
+// Validate that exactly count String args were given, returning their values
+func stringArgs(name string, count int, args []object.Object) ([]string, *object.Error) {
+	if len(args) != count {
+		return nil, newError("Invalid number of args, Got=%d, expected=%d", len(args), count)
+	}
+
+	strs := make([]string, len(args))
+	for idx, arg := range args {
+		str, ok := arg.(*object.String)
+		if !ok {
+			return nil, newError("%s() only supports String's, Got=%s", name, arg.Type())
+		}
+
+		strs[idx] = str.Value
+	}
+
+	return strs, nil
+}
+
+func stringArray(strs []string) *object.Array {
+	arr := &object.Array{Value: make([]object.Object, len(strs))}
+	for idx, str := range strs {
+		arr.Value[idx] = &object.String{Value: str}
+	}
+
+	return arr
+}
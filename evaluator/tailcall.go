@@ -0,0 +1,127 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+	"monkey/token"
+)
+
+// Call in tail position of a function body, handed back to applyFunction to be made in place of the
+// current call instead of nesting inside it. Never escapes applyFunction
+type tailCall struct {
+	fn   *object.Function
+	args []object.Object
+	pos  token.Position // Position of the call expression
+}
+
+func (tc *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+func (tc *tailCall) Inspect() string         { return "tail call" }
+
+// Evaluate a node of a function body, tail is set when the value of the node is the result of the function.
+// Returns are in tail position wherever they appear, except inside expressions and try blocks which are
+// evaluated normally. Counts as a step and records Error positions like eval
+func evalTail(astNode ast.Node, env *object.Environment, tail bool) object.Object {
+	st, _ := env.State().(*evalState)
+	if err := st.step(); err != nil {
+		return withPosition(err, astNode)
+	}
+
+	if st.debugging() {
+		st.statement(astNode, env)
+	}
+
+	switch node := astNode.(type) {
+	case *ast.BlockStatement:
+		return withPosition(evalTailBlock(node.Statements, env, tail), node)
+
+	case *ast.ReturnStatement:
+		val := evalTail(node.Value, env, true)
+		if isError(val) {
+			return withPosition(val, node)
+		}
+
+		if _, ok := val.(*tailCall); ok {
+			return val
+		}
+
+		return &object.Return{Value: val}
+
+	case *ast.ExpressionStatement:
+		return withPosition(evalTail(node.Expr, env, tail), node)
+
+	case *ast.IfExpression:
+		return withPosition(evalTailIf(node, env, tail), node)
+
+	case *ast.CallExpression:
+		if tail {
+			return withPosition(evalTailCall(node, env), node)
+		}
+	}
+
+	return withPosition(evalNode(astNode, env, st), astNode)
+}
+
+// Evaluate statements like evalBlockStatment, the last statement is in tail position if the block is
+func evalTailBlock(statements []ast.Statement, env *object.Environment, tail bool) object.Object {
+	var obj object.Object
+
+	for idx, stmt := range statements {
+		obj = evalTail(stmt, env, tail && idx == len(statements)-1)
+
+		switch obj.(type) {
+		case *object.Return, *object.Error, *tailCall:
+			return obj
+		}
+	}
+
+	return obj
+}
+
+// Evaluate ast.IfExpression, either branch is in tail position if the expression is
+func evalTailIf(ie *ast.IfExpression, env *object.Environment, tail bool) object.Object {
+	cond := eval(ie.Condition, env)
+	if isError(cond) {
+		return cond
+	}
+
+	if isTruthy(cond) {
+		return evalTail(ie.Consequence, env, tail)
+	}
+
+	if ie.Alternative != nil {
+		return evalTail(ie.Alternative, env, tail)
+	}
+
+	return NULL
+}
+
+// Evaluate the function and args of a call in tail position.
+// Calls of Monkey functions are deferred to applyFunction, Builtins are simply called
+func evalTailCall(callExp *ast.CallExpression, env *object.Environment) object.Object {
+	fnObj := eval(callExp.Fn, env)
+	if isError(fnObj) {
+		return fnObj
+	}
+
+	args := evalExpressions(callExp.Args, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	fn, ok := fnObj.(*object.Function)
+	if !ok {
+		return callFunction(fnObj, args, callExp, env)
+	}
+
+	return &tailCall{fn: fn, args: args, pos: callExp.Pos()}
+}
+
+// Add the frame of the tail call made in place of the original call to an Error propagating out of it.
+// Earlier tail calls were replaced and leave no frame
+func tailFrame(obj object.Object, fn *object.Function, pos token.Position) object.Object {
+	if errObj, ok := obj.(*object.Error); ok && pos.IsValid() {
+		errObj.Stack = append(errObj.Stack, object.Frame{Function: fn.Name, Position: pos})
+	}
+
+	return obj
+}
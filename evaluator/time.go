@@ -0,0 +1,226 @@
+package evaluator
+
+import (
+	"context"
+	"monkey/object"
+	"time"
+)
+
+// Source of the current time for the time module, replaceable so tests stay deterministic
+type Clock interface {
+	Now() time.Time
+	Sleep(ctx context.Context, d time.Duration) error // Stops early with ctx.Err() once ctx is done
+}
+
+// Clock backed by the host system
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Time module reading the current time from clock.
+// Durations are Integer milliseconds, layouts use the Go reference time and default to RFC3339
+func registerTime(r *Registry, clock Clock) {
+	t := object.Param{Name: "t", Type: object.TIME_OBJ}
+	layout := object.Param{Name: "layout", Type: object.STRING_OBJ}
+	ms := object.Param{Name: "ms", Type: object.INTEGER_OBJ}
+
+	r.Register("time.now", &object.Builtin{
+		Params: []object.Param{},
+		Doc:    "Current time",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newError("Invalid number of args, Got=%d, expected=0", len(args))
+			}
+
+			return &object.Time{Value: clock.Now()}
+		},
+	})
+
+	// Sleeping is cut short when the evaluation is cancelled or times out
+	sleep := func(ctx context.Context, args ...object.Object) object.Object {
+		ms, err := integerArgs("time.sleep", 1, args)
+		if err != nil {
+			return err
+		}
+
+		if ms[0] < 0 {
+			return newError("time.sleep() duration must not be negative, Got=%d", ms[0])
+		}
+
+		if err := clock.Sleep(ctx, time.Duration(ms[0])*time.Millisecond); err != nil {
+			return contextError(err)
+		}
+		return NULL
+	}
+
+	r.Register("time.sleep", &object.Builtin{
+		Params:    []object.Param{ms},
+		Doc:       "Pause for a number of milliseconds",
+		Fn:        func(args ...object.Object) object.Object { return sleep(context.Background(), args...) },
+		ContextFn: sleep,
+	})
+
+	r.Register("time.format", &object.Builtin{
+		Params:   []object.Param{t, layout},
+		Optional: 1,
+		Doc:      "Format a Time as a String",
+		Fn:       timeFormat,
+	})
+
+	r.Register("time.parse", &object.Builtin{
+		Params:   []object.Param{{Name: "str", Type: object.STRING_OBJ}, layout},
+		Optional: 1,
+		Doc:      "Parse a String into a Time",
+		Fn:       timeParse,
+	})
+
+	r.Register("time.add", &object.Builtin{
+		Params: []object.Param{t, ms},
+		Doc:    "Offset a Time by a number of milliseconds",
+		Fn:     timeAdd,
+	})
+
+	r.Register("time.diff", &object.Builtin{
+		Params: []object.Param{{Name: "a", Type: object.TIME_OBJ}, {Name: "b", Type: object.TIME_OBJ}},
+		Doc:    "Milliseconds elapsed from b to a",
+		Fn:     timeDiff,
+	})
+
+	r.Register("time.unix", &object.Builtin{
+		Params: []object.Param{t},
+		Doc:    "Milliseconds since the unix epoch",
+		Fn:     timeUnix,
+	})
+
+	r.Register("time.from_unix", &object.Builtin{
+		Params: []object.Param{ms},
+		Doc:    "Time from milliseconds since the unix epoch, in UTC",
+		Fn:     timeFromUnix,
+	})
+}
+
+// Format a Time as a String using an optional layout
+func timeFormat(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("Invalid number of args, Got=%d, expected=1 or 2", len(args))
+	}
+
+	t, ok := args[0].(*object.Time)
+	if !ok {
+		return newError("time.format() only supports Time's, Got=%s", args[0].Type())
+	}
+
+	layout, err := timeLayout("format", args[1:])
+	if err != nil {
+		return err
+	}
+
+	return &object.String{Value: t.Value.Format(layout)}
+}
+
+// Parse a String into a Time using an optional layout
+func timeParse(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("Invalid number of args, Got=%d, expected=1 or 2", len(args))
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newError("time.parse() only supports String's, Got=%s", args[0].Type())
+	}
+
+	layout, err := timeLayout("parse", args[1:])
+	if err != nil {
+		return err
+	}
+
+	t, parseErr := time.Parse(layout, str.Value)
+	if parseErr != nil {
+		return newError("time.parse() %s", parseErr)
+	}
+
+	return &object.Time{Value: t}
+}
+
+// Offset a Time by a number of milliseconds
+func timeAdd(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("Invalid number of args, Got=%d, expected=2", len(args))
+	}
+
+	t, ok := args[0].(*object.Time)
+	if !ok {
+		return newError("time.add() only supports Time's, Got=%s", args[0].Type())
+	}
+
+	ms, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("time.add() duration must be an Integer, Got=%s", args[1].Type())
+	}
+
+	return &object.Time{Value: t.Value.Add(time.Duration(ms.Value) * time.Millisecond)}
+}
+
+// Milliseconds elapsed from the second Time to the first
+func timeDiff(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("Invalid number of args, Got=%d, expected=2", len(args))
+	}
+
+	a, aOk := args[0].(*object.Time)
+	b, bOk := args[1].(*object.Time)
+	if !aOk || !bOk {
+		return newError("time.diff() only supports Time's, Got=%s, %s", args[0].Type(), args[1].Type())
+	}
+
+	return &object.Integer{Value: a.Value.Sub(b.Value).Milliseconds()}
+}
+
+// Milliseconds since the unix epoch
+func timeUnix(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("Invalid number of args, Got=%d, expected=1", len(args))
+	}
+
+	t, ok := args[0].(*object.Time)
+	if !ok {
+		return newError("time.unix() only supports Time's, Got=%s", args[0].Type())
+	}
+
+	return &object.Integer{Value: t.Value.UnixMilli()}
+}
+
+// Time from milliseconds since the unix epoch, in UTC
+func timeFromUnix(args ...object.Object) object.Object {
+	ms, err := integerArgs("time.from_unix", 1, args)
+	if err != nil {
+		return err
+	}
+
+	return &object.Time{Value: time.UnixMilli(ms[0]).UTC()}
+}
+
+func timeLayout(name string, args []object.Object) (string, *object.Error) {
+	if len(args) == 0 {
+		return time.RFC3339, nil
+	}
+
+	layout, ok := args[0].(*object.String)
+	if !ok {
+		return "", newError("time.%s() layout must be a String, Got=%s", name, args[0].Type())
+	}
+
+	return layout.Value, nil
+}
@@ -0,0 +1,74 @@
+// Package frame reads and writes messages preceded by a Content-Length header, as the Language Server
+// and Debug Adapter protocols frame them
+package frame
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Largest body read, a longer Content-Length is an error rather than an allocation
+const MaxLength = 64 << 20
+
+type Reader struct {
+	in *bufio.Reader
+}
+
+func NewReader(in io.Reader) *Reader {
+	return &Reader{in: bufio.NewReader(in)}
+}
+
+// Read the body of the next message, io.EOF once the stream ends between messages
+func (r *Reader) Read() ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.in.ReadString('\n')
+		if err == io.EOF && line == "" && length < 0 {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Unexpected end of header: %w", err)
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("Invalid header %q", line)
+		}
+
+		// Other headers, such as Content-Type, are ignored
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || length < 0 {
+				return nil, fmt.Errorf("Invalid Content-Length %q", strings.TrimSpace(value))
+			}
+			if length > MaxLength {
+				return nil, fmt.Errorf("Content-Length %d exceeds the maximum of %d", length, MaxLength)
+			}
+		}
+	}
+
+	if length < 0 {
+		return nil, fmt.Errorf("Missing Content-Length header")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r.in, body); err != nil {
+		return nil, fmt.Errorf("Unexpected end of message: %w", err)
+	}
+
+	return body, nil
+}
+
+// Write body as a message to out
+func Write(out io.Writer, body []byte) error {
+	_, err := fmt.Fprintf(out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
@@ -0,0 +1,56 @@
+package frame
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadWrite(t *testing.T) {
+	var buf bytes.Buffer
+	for _, body := range []string{`{"a":1}`, "", "héllo\r\n"} {
+		if err := Write(&buf, []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Headers other than Content-Length are skipped, whatever their case
+	buf.WriteString("content-type: text\r\ncontent-length: 2\r\n\r\n{}")
+
+	r := NewReader(&buf)
+	for _, expected := range []string{`{"a":1}`, "", "héllo\r\n", "{}"} {
+		body, err := r.Read()
+		if err != nil || string(body) != expected {
+			t.Errorf("Wrong message. Got=%q, err=%v, expected=%q", body, err, expected)
+		}
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last message. Got=%v", err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Content-Type: text\r\n\r\n{}", "Missing Content-Length header"},
+		{"Content-Length: ten\r\n\r\n", `Invalid Content-Length "ten"`},
+		{"Content-Length: -1\r\n\r\n", `Invalid Content-Length "-1"`},
+		{fmt.Sprintf("Content-Length: %d\r\n\r\n", MaxLength+1), fmt.Sprintf("Content-Length %d exceeds the maximum of %d", MaxLength+1, MaxLength)},
+		{"Content-Length: 99999999999999999999\r\n\r\n", `Invalid Content-Length "99999999999999999999"`},
+		{"Content-Length 2\r\n\r\n{}", `Invalid header "Content-Length 2"`},
+		{"Content-Length: 10\r\n\r\n{}", "Unexpected end of message: unexpected EOF"},
+		{"Content-Length: 2\r\n", "Unexpected end of header: EOF"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewReader(strings.NewReader(tt.input)).Read()
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("Wrong error for %q. Got=%v, expected=%s", tt.input, err, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package monkey embeds the Monkey interpreter in Go host applications
+package monkey
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/optimizer"
+	"monkey/parser"
+	"os"
+	"strings"
+	"time"
+)
+
+// Interpreter evaluates Monkey source, keeping its global bindings between calls to Eval
+type Interpreter struct {
+	config   evaluator.Config
+	registry *evaluator.Registry
+	edits    []func(*evaluator.Registry)
+	optimize bool
+	env      *object.Environment
+}
+
+// Option configures an Interpreter created by New
+type Option func(*Interpreter)
+
+// Destination of output written by puts and print
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) { i.config.Stdout = w }
+}
+
+// Destination of output written by eprint
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) { i.config.Stderr = w }
+}
+
+// Seed for the random builtins, making them reproducible
+func WithSeed(seed int64) Option {
+	return func(i *Interpreter) { i.config.Seed = seed }
+}
+
+// Host access granted to the file and environment builtins
+func WithCapabilities(caps evaluator.Capabilities) Option {
+	return func(i *Interpreter) { i.config.Caps = caps }
+}
+
+// Source of the current time for the time module
+func WithClock(clock evaluator.Clock) Option {
+	return func(i *Interpreter) { i.config.Clock = clock }
+}
+
+// Budget of each evaluation, by default only the call depth is limited
+func WithLimits(limits evaluator.Limits) Option {
+	return func(i *Interpreter) { i.config.Limits = limits }
+}
+
+// Call functions without checking args against the annotations of their parameters, which is faster
+func WithoutTypeChecks() Option {
+	return func(i *Interpreter) { i.config.SkipTypeChecks = true }
+}
+
+// Rewrite programs with the optimizer before evaluating them.
+// Results and error messages are unchanged, but calls of inlined functions leave no frame in stack traces,
+// and code inlining a function keeps the old body when a later Eval binds its name again
+func WithOptimizer() Option {
+	return func(i *Interpreter) { i.optimize = true }
+}
+
+// Use the builtins of registry instead of the standard builtins.
+// The registry is used as given, options configuring the standard builtins do not apply to it
+func WithRegistry(registry *evaluator.Registry) Option {
+	return func(i *Interpreter) { i.registry = registry }
+}
+
+// Register a builtin, overriding any builtin with the same name.
+// A dotted name such as "http.get" registers a member of a module, New panics when it clashes with a builtin
+func WithBuiltin(name string, builtin *object.Builtin) Option {
+	return func(i *Interpreter) {
+		i.edits = append(i.edits, func(r *evaluator.Registry) { r.Register(name, builtin) })
+	}
+}
+
+// Remove a builtin, or a whole module, such as puts or time
+func WithoutBuiltin(name string) Option {
+	return func(i *Interpreter) {
+		i.edits = append(i.edits, func(r *evaluator.Registry) { r.Remove(name) })
+	}
+}
+
+// Create an Interpreter, by default random builtins are seeded from the current time
+func New(opts ...Option) *Interpreter {
+	i := &Interpreter{
+		config: evaluator.Config{Seed: time.Now().UnixNano()},
+	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	if i.registry == nil {
+		i.registry = evaluator.DefaultRegistry(i.config)
+	}
+
+	for _, edit := range i.edits {
+		edit(i.registry)
+	}
+
+	i.env = i.registry.Environment()
+	evaluator.SetLimits(i.env, i.config.Limits)
+	evaluator.SetTypeChecks(i.env, !i.config.SkipTypeChecks)
+
+	return i
+}
+
+// Builtins available to the interpreted source, for help and completion
+func (i *Interpreter) Registry() *evaluator.Registry {
+	return i.registry
+}
+
+// Errors reported by the parser, the program is not evaluated
+type ParseError struct {
+	Errors []string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse errors: %s", strings.Join(e.Errors, "; "))
+}
+
+// Error produced while evaluating the program.
+// Err holds the position the error was raised at and the Monkey call stack it propagated through
+type RuntimeError struct {
+	Err *object.Error
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Err.Message
+}
+
+// Evaluate the source, returning the value of the last statement
+func (i *Interpreter) Eval(src string) (object.Object, error) {
+	return i.eval(context.Background(), lexer.New(src))
+}
+
+// Evaluate the source, stopping with a *RuntimeError once ctx is cancelled or its deadline passes
+func (i *Interpreter) EvalContext(ctx context.Context, src string) (object.Object, error) {
+	return i.eval(ctx, lexer.New(src))
+}
+
+// Evaluate the source of the file at path, returning the value of the last statement
+func (i *Interpreter) EvalFile(path string) (object.Object, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.eval(context.Background(), lexer.NewFile(path, string(content)))
+}
+
+func (i *Interpreter) eval(ctx context.Context, l *lexer.Lexer) (object.Object, error) {
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, &ParseError{Errors: p.Errors()}
+	}
+
+	if i.optimize {
+		// Names in branches the optimizer drops are still reported
+		if errObj := evaluator.Resolve(program, i.env); errObj != nil {
+			return result(errObj)
+		}
+		program = optimizer.Optimize(program)
+	}
+
+	return result(evaluator.EvalContext(ctx, program, i.env))
+}
+
+// Bind a global, visible to subsequently evaluated source
+func (i *Interpreter) Set(name string, val object.Object) {
+	i.env.Set(name, val)
+}
+
+// Get the value bound to a global or builtin
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	val := i.env.Get(name)
+	return val, val != nil
+}
+
+// Invoke the Monkey function bound to fnName with the given args
+func (i *Interpreter) Call(fnName string, args ...object.Object) (object.Object, error) {
+	fn, ok := i.Get(fnName)
+	if !ok {
+		return nil, fmt.Errorf("undefined function %s", fnName)
+	}
+
+	if fn.Type() != object.FUNCTION_OBJ && fn.Type() != object.BUILTIN_OBJ {
+		return nil, fmt.Errorf("%s is not a function, Got=%s", fnName, fn.Type())
+	}
+
+	return result(evaluator.ApplyFunction(fn, args))
+}
+
+// Convert an evaluated object into a Go result, Error objects become a *RuntimeError
+func result(obj object.Object) (object.Object, error) {
+	if errObj, ok := obj.(*object.Error); ok {
+		return nil, &RuntimeError{Err: errObj}
+	}
+
+	if obj == nil {
+		return evaluator.NULL, nil
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,296 @@
+package monkey
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"monkey/evaluator"
+	"monkey/object"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInterpreterEval(t *testing.T) {
+	interp := New()
+
+	if _, err := interp.Eval("let add = fn(a, b) { a + b };"); err != nil {
+		t.Fatalf("Eval returned error: %s", err)
+	}
+
+	// Bindings persist between calls
+	val, err := interp.Eval("add(1, 2)")
+	if err != nil {
+		t.Fatalf("Eval returned error: %s", err)
+	}
+
+	if val.Inspect() != "3" {
+		t.Errorf("Wrong result. Got=%s, expected=3", val.Inspect())
+	}
+
+	val, err = interp.Eval("let x = 1;")
+	if err != nil || val.Type() != object.NULL_OBJ {
+		t.Errorf("Statement without value did not produce null. Got=%+v, err=%v", val, err)
+	}
+}
+
+func TestInterpreterErrors(t *testing.T) {
+	var out bytes.Buffer
+	interp := New(WithStdout(&out))
+
+	_, err := interp.Eval("let = 5;")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected *ParseError. Got=%T (%v)", err, err)
+	}
+
+	if len(parseErr.Errors) == 0 {
+		t.Errorf("ParseError has no messages")
+	}
+
+	_, err = interp.Eval("1 + true")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("Expected *RuntimeError. Got=%T (%v)", err, err)
+	}
+
+	if runtimeErr.Err.Message != "Infix expression type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("Wrong error message. Got=%q", runtimeErr.Err.Message)
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("Errors were printed. Got=%q", out.String())
+	}
+}
+
+func TestInterpreterLimits(t *testing.T) {
+	interp := New(WithLimits(evaluator.Limits{MaxDepth: 50}))
+
+	_, err := interp.Eval("let f = fn(x) { 1 + f(x) }; f(1)")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("Expected *RuntimeError. Got=%T (%v)", err, err)
+	}
+
+	if runtimeErr.Err.Message != "Call depth limit exceeded, more than 50 nested function calls" {
+		t.Errorf("Wrong error message. Got=%q", runtimeErr.Err.Message)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := interp.EvalContext(ctx, "1 + 1"); !errors.As(err, &runtimeErr) {
+		t.Fatalf("Cancelled evaluation did not fail. Got=%v", err)
+	}
+
+	// A tail-recursive loop never nests, with the default limits it runs until the context is done
+	runaway, cancelRunaway := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelRunaway()
+
+	_, err = New().EvalContext(runaway, "let f = fn(x) { f(x) }; f(1)")
+	if !errors.As(err, &runtimeErr) || runtimeErr.Err.Message != "Evaluation timed out" {
+		t.Errorf("Tail-recursive loop was not stopped by its context. Got=%v", err)
+	}
+
+	// The interpreter is still usable after a limit was hit
+	val, err := interp.Eval("f")
+	if err != nil || val.Type() != object.FUNCTION_OBJ {
+		t.Errorf("Bindings lost after exceeding a limit. Got=%v, err=%v", val, err)
+	}
+}
+
+func TestInterpreterOptimizer(t *testing.T) {
+	interp := New(WithOptimizer())
+
+	val, err := interp.Eval("let sq = fn(x) { x * x }; if (1 < 2) { sq(3) + 2 * 3 } else { 0 }")
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+
+	if val.Inspect() != "15" {
+		t.Errorf("Wrong result. Got=%s, expected=15", val.Inspect())
+	}
+
+	_, err = interp.Eval("let f = fn() { 1 / 0 }; f()")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Err.Message != "Division by zero: 1 / 0" {
+		t.Errorf("Division by zero not kept. Got=%v", err)
+	}
+
+	// Names in the branches the optimizer drops are reported as without it
+	dropped := []struct {
+		src     string
+		message string
+		output  string
+	}{
+		{"if (false) { nope }", "Unknown Identifier nope", ""},
+		{`puts("a"); if (false) { let x = 1 }; x`, "Unknown Identifier x", "\"a\"\n"},
+	}
+
+	for _, tt := range dropped {
+		for _, optimize := range []bool{false, true} {
+			var out bytes.Buffer
+			opts := []Option{WithStdout(&out)}
+			if optimize {
+				opts = append(opts, WithOptimizer())
+			}
+
+			_, err := New(opts...).Eval(tt.src)
+			if !errors.As(err, &runtimeErr) || runtimeErr.Err.Message != tt.message {
+				t.Errorf("Wrong error for %q, optimized=%t. Got=%v, expected=%s", tt.src, optimize, err, tt.message)
+			}
+			if out.String() != tt.output {
+				t.Errorf("Wrong output for %q, optimized=%t. Got=%q, expected=%q", tt.src, optimize, out.String(), tt.output)
+			}
+		}
+	}
+}
+
+func TestInterpreterTypeChecks(t *testing.T) {
+	src := `let f = fn(a: int) { a }; f("one")`
+
+	_, err := New().Eval(src)
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Err.Message != "Type mismatch for parameter a of f, Got=STRING, expected=INTEGER" {
+		t.Errorf("Annotation not checked. Got=%v", err)
+	}
+
+	val, err := New(WithoutTypeChecks()).Eval(src)
+	if err != nil || val.Inspect() != `"one"` {
+		t.Errorf("Annotation checked with WithoutTypeChecks. Got=%v, err=%v", val, err)
+	}
+
+	// Inlining must not skip the check
+	if _, err := New(WithOptimizer()).Eval(src); !errors.As(err, &runtimeErr) {
+		t.Errorf("Annotation not checked with WithOptimizer. Got=%v", err)
+	}
+}
+
+func TestInterpreterSetGetCall(t *testing.T) {
+	interp := New()
+	interp.Set("base", &object.Integer{Value: 10})
+
+	if _, err := interp.Eval("let scale = fn(x) { x * base };"); err != nil {
+		t.Fatalf("Eval returned error: %s", err)
+	}
+
+	val, err := interp.Call("scale", &object.Integer{Value: 4})
+	if err != nil {
+		t.Fatalf("Call returned error: %s", err)
+	}
+
+	if val.Inspect() != "40" {
+		t.Errorf("Wrong result. Got=%s, expected=40", val.Inspect())
+	}
+
+	if _, ok := interp.Get("scale"); !ok {
+		t.Errorf("Get did not find scale")
+	}
+
+	if _, ok := interp.Get("missing"); ok {
+		t.Errorf("Get found missing")
+	}
+
+	if _, err := interp.Call("missing"); err == nil {
+		t.Errorf("Call of missing function did not fail")
+	}
+
+	if _, err := interp.Call("base"); err == nil {
+		t.Errorf("Call of non function did not fail")
+	}
+
+	if _, err := interp.Call("scale"); err == nil {
+		t.Errorf("Call with wrong arity did not fail")
+	}
+}
+
+func TestInterpreterOptions(t *testing.T) {
+	var out bytes.Buffer
+	greet := &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return &object.String{Value: "hello " + args[0].Inspect()}
+		},
+	}
+
+	interp := New(WithStdout(&out), WithBuiltin("greet", greet))
+
+	if _, err := interp.Eval(`puts(greet(1))`); err != nil {
+		t.Fatalf("Eval returned error: %s", err)
+	}
+
+	if out.String() != "\"hello 1\"\n" {
+		t.Errorf("Wrong output. Got=%q", out.String())
+	}
+
+	a, _ := New(WithSeed(3)).Eval("rand_int(0, 1000000)")
+	b, _ := New(WithSeed(3)).Eval("rand_int(0, 1000000)")
+	if a.Inspect() != b.Inspect() {
+		t.Errorf("Same seed produced different values. Got=%s and %s", a.Inspect(), b.Inspect())
+	}
+}
+
+func TestInterpreterEvalFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.monk")
+	os.WriteFile(path, []byte("let x = 2; x * 21"), 0o644)
+
+	val, err := New().EvalFile(path)
+	if err != nil {
+		t.Fatalf("EvalFile returned error: %s", err)
+	}
+
+	if val.Inspect() != "42" {
+		t.Errorf("Wrong result. Got=%s, expected=42", val.Inspect())
+	}
+
+	if _, err := New().EvalFile(filepath.Join(t.TempDir(), "missing.monk")); err == nil {
+		t.Errorf("EvalFile of missing file did not fail")
+	}
+}
+
+func TestInterpreterHostFunction(t *testing.T) {
+	type user struct {
+		Name string `monkey:"name"`
+		Age  int    `monkey:"age"`
+	}
+
+	interp := New()
+	interp.Set("find_user", object.MustWrapFunc(func(id int) (*user, error) {
+		if id != 1 {
+			return nil, errors.New("user not found")
+		}
+		return &user{Name: "ada", Age: 36}, nil
+	}))
+
+	val, err := interp.Eval(`find_user(1).name`)
+	if err != nil || val.Inspect() != `"ada"` {
+		t.Errorf("Wrong result. Got=%+v, err=%v", val, err)
+	}
+
+	if _, err := interp.Eval(`find_user(2)`); err == nil || err.Error() != "user not found" {
+		t.Errorf("Host error was not returned. Got=%v", err)
+	}
+
+	val, _ = interp.Eval(`find_user(1)`)
+	var back user
+	if err := object.ToGo(val, &back); err != nil || back.Age != 36 {
+		t.Errorf("ToGo failed. Got=%+v, err=%v", back, err)
+	}
+}
+
+func TestInterpreterRegistry(t *testing.T) {
+	var out bytes.Buffer
+	interp := New(WithStdout(&out), WithoutBuiltin("puts"), WithoutBuiltin("time"))
+
+	if _, err := interp.Eval(`puts(1)`); err == nil || err.Error() != "Unknown Identifier puts" {
+		t.Errorf("Removed builtin was callable. Got=%v", err)
+	}
+
+	if _, ok := interp.Registry().Lookup("time.now"); ok {
+		t.Errorf("Removed module is still registered")
+	}
+
+	if _, ok := interp.Registry().Lookup("len"); !ok {
+		t.Errorf("Default builtin was removed")
+	}
+}
@@ -1,20 +1,31 @@
 package lexer
 
-import "monkey/token"
+import (
+	"monkey/token"
+	"strings"
+)
 
 type Lexer struct {
-	input   string
-	line    int
-	column  int
-	currPos int // Index of current char in input string
-	nextPos int // Index of next char to examine
-	ch      byte
+	input    string
+	filename string
+	line     int
+	column   int
+	currPos  int // Index of current char in input string
+	nextPos  int // Index of next char to examine
+	ch       byte
+	comments []token.Token // Comments skipped so far, in source order
 }
 
 func New(input string) *Lexer {
+	return NewFile("", input)
+}
+
+// Create a Lexer for source read from the named file, used for token positions
+func NewFile(filename, input string) *Lexer {
 	l := &Lexer{
-		input: input,
-		line:  1,
+		input:    input,
+		filename: filename,
+		line:     1,
 	}
 
 	l.advanceChar()
@@ -25,10 +36,17 @@ func New(input string) *Lexer {
 func (l *Lexer) NextToken() token.Token {
 	l.eatWhitespace()
 
+	// Comments are not tokens of the grammar, they are kept aside for tools such as the formatter
+	for l.ch == '/' && l.nextPos < len(l.input) && l.input[l.nextPos] == '/' {
+		l.comments = append(l.comments, l.readComment())
+		l.eatWhitespace()
+	}
+
 	pos := token.Position{
-		Filename: "test.monk",
+		Filename: l.filename,
 		Line:     l.line,
 		Column:   l.column,
+		Offset:   l.currPos,
 	}
 
 	// Multi byte token if first character is a number/letter/quote
@@ -43,7 +61,13 @@ func (l *Lexer) NextToken() token.Token {
 
 	} else if !notQuote(l.ch) { //not(notQuote) == isQuote :/
 		l.advanceChar()
-		str := l.readWord(notQuote)
+		str := l.readWord(func(ch byte) bool { return notQuote(ch) && ch != 0 })
+
+		// Unterminated string runs to the end of the input
+		if l.ch == 0 {
+			return newTokenStr(token.ILLEGAL, `"`+str, pos)
+		}
+
 		l.advanceChar()
 		return newTokenStr(token.STRING, str, pos)
 	}
@@ -65,6 +89,9 @@ func (l *Lexer) NextToken() token.Token {
 
 		l.advanceChar()
 		return newToken(token.BANG, '!', pos)
+	} else if l.ch == '-' && l.peekCharIs('>') {
+		l.advanceChar()
+		return newTokenStr(token.ARROW, "->", pos)
 	}
 
 	// Single byte tokens (one char in length)
@@ -100,6 +127,8 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.COLON, l.ch, pos)
 	case ',':
 		tok = newToken(token.COMMA, l.ch, pos)
+	case '.':
+		tok = newToken(token.DOT, l.ch, pos)
 	case 0:
 		tok = newToken(token.EOF, l.ch, pos)
 	default:
@@ -110,6 +139,24 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
+// Comments skipped by NextToken so far, in source order
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+// Read a line comment, excluding the newline which ends it
+func (l *Lexer) readComment() token.Token {
+	pos := token.Position{
+		Filename: l.filename,
+		Line:     l.line,
+		Column:   l.column,
+		Offset:   l.currPos,
+	}
+
+	text := l.readWord(func(ch byte) bool { return ch != '\n' && ch != 0 })
+	return newTokenStr(token.COMMENT, strings.TrimRight(text, " \t\r"), pos)
+}
+
 func (l *Lexer) advanceChar() {
 	if l.nextPos >= len(l.input) {
 		l.ch = 0
@@ -308,3 +308,105 @@ func TestMultiByteTokens(t *testing.T) {
 		}
 	}
 }
+
+func TestMemberAccess(t *testing.T) {
+	input := "regex.match"
+	expected := []struct {
+		expType    token.TokenType
+		expLiteral string
+	}{
+		{token.IDENTIFIER, "regex"},
+		{token.DOT, "."},
+		{token.IDENTIFIER, "match"},
+		{token.EOF, string(byte(0))},
+	}
+
+	l := New(input)
+	for idx, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt.expType {
+			t.Fatalf("test[%d]: Invalid TokenType. Got %s, Expected %s", idx, tok.Type, tt.expType)
+		}
+		if tok.Literal != tt.expLiteral {
+			t.Fatalf("test[%d]: Invalid Literal. Got %s, Expected %s", idx, tok.Literal, tt.expLiteral)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := "// leading\nlet x = 10 / 2; // trailing  \n//\nx"
+	expected := []struct {
+		expType    token.TokenType
+		expLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENTIFIER, "x"},
+		{token.ASSIGN, "="},
+		{token.NUMBER, "10"},
+		{token.SLASH, "/"},
+		{token.NUMBER, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "x"},
+		{token.EOF, string(byte(0))},
+	}
+
+	l := New(input)
+	for idx, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt.expType {
+			t.Fatalf("test[%d]: Invalid TokenType. Got %s, Expected %s", idx, tok.Type, tt.expType)
+		}
+		if tok.Literal != tt.expLiteral {
+			t.Fatalf("test[%d]: Invalid Literal. Got %s, Expected %s", idx, tok.Literal, tt.expLiteral)
+		}
+	}
+
+	comments := []struct {
+		expLiteral string
+		line       int
+		column     int
+	}{
+		{"// leading", 1, 1},
+		{"// trailing", 2, 17},
+		{"//", 3, 1},
+	}
+
+	if len(l.Comments()) != len(comments) {
+		t.Fatalf("Wrong number of comments. Got=%d, expected=%d", len(l.Comments()), len(comments))
+	}
+
+	for idx, tt := range comments {
+		comment := l.Comments()[idx]
+		if comment.Type != token.COMMENT || comment.Literal != tt.expLiteral {
+			t.Errorf("comment[%d]: Got %s %q, Expected %q", idx, comment.Type, comment.Literal, tt.expLiteral)
+		}
+		if comment.Position.Line != tt.line || comment.Position.Column != tt.column {
+			t.Errorf("comment[%d]: Wrong position. Got=%d:%d, expected=%d:%d", idx, comment.Position.Line, comment.Position.Column, tt.line, tt.column)
+		}
+	}
+}
+
+func TestUnterminatedString(t *testing.T) {
+	l := New(`let s = "abc`)
+	for _, expType := range []token.TokenType{token.LET, token.IDENTIFIER, token.ASSIGN, token.ILLEGAL, token.EOF} {
+		tok := l.NextToken()
+		if tok.Type != expType {
+			t.Fatalf("Invalid TokenType. Got %s (%q), Expected %s", tok.Type, tok.Literal, expType)
+		}
+	}
+}
+
+func TestArrow(t *testing.T) {
+	expected := []token.TokenType{
+		token.RPAREN, token.ARROW, token.IDENTIFIER, token.IDENTIFIER, token.MINUS, token.GT, token.NUMBER,
+		token.MINUS, token.MINUS, token.NUMBER, token.EOF,
+	}
+
+	l := New(") -> int a - > 1 --1")
+	for idx, expType := range expected {
+		tok := l.NextToken()
+		if tok.Type != expType {
+			t.Fatalf("test[%d]: Invalid TokenType. Got %s, Expected %s", idx, tok.Type, expType)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+// Package lint reports likely mistakes in a parsed program without evaluating it
+package lint
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/resolver"
+	"monkey/token"
+	"sort"
+	"strings"
+)
+
+// Names of the checks, reported with each Diagnostic
+const (
+	UNDEFINED   = "undefined"   // Identifier which is not bound in any enclosing scope nor a builtin, or is used before its let
+	UNUSED      = "unused"      // Let or parameter of a function which is never referenced
+	UNREACHABLE = "unreachable" // Statement after a return or throw in the same block
+	SHADOW      = "shadow"      // Binding which hides a builtin
+	MISMATCH    = "mismatch"    // Comparison of operands of different types, an error at runtime
+	ARITY       = "arity"       // Call with the wrong number of args to a known function
+)
+
+type Diagnostic struct {
+	Position token.Position
+	Check    string
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s (%s)", d.Position, d.Message, d.Check)
+}
+
+// Variable of a scope, declared by lets, parameters or a catch clause
+type binding struct {
+	pos   token.Position
+	param bool
+	catch bool
+	lets  int
+	fn    *ast.FnLiteral // Function the variable holds when its only binding is a let of a function literal
+	used  bool
+	bound bool // Its parameter, catch clause or a let has been walked
+}
+
+// Bindings of a scope, see resolver.DeclareLets
+type scope struct {
+	parent   *scope
+	bindings map[string]*binding
+	names    []string // In order of declaration
+	function bool     // Body of a function, which may run after any let of the scopes around it
+}
+
+func newScope(parent *scope, function bool) *scope {
+	return &scope{parent: parent, bindings: map[string]*binding{}, function: function}
+}
+
+type linter struct {
+	builtins    *evaluator.Registry
+	known       map[string]bool // Registry.Defined of builtins
+	diagnostics []Diagnostic
+}
+
+// Check program for mistakes, knowing the builtins of the registry, which may be nil.
+// Top level lets are not reported as unused since a later program may refer to them
+func Lint(program *ast.Program, builtins *evaluator.Registry) []Diagnostic {
+	l := &linter{builtins: builtins, known: builtins.Defined()}
+
+	global := newScope(nil, false)
+	l.declareLets(program, global)
+	ast.Walk(&visitor{l: l, scope: global}, program)
+
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		a, b := l.diagnostics[i].Position, l.diagnostics[j].Position
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	return l.diagnostics
+}
+
+func (l *linter) report(pos token.Position, check string, format string, args ...any) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{Position: pos, Check: check, Message: fmt.Sprintf(format, args...)})
+}
+
+// Declare the variable named by ident in sc
+func (l *linter) declare(sc *scope, ident *ast.Identifier) *binding {
+	if l.known[ident.Value] {
+		l.report(ident.Pos(), SHADOW, "Builtin %s is shadowed", ident.Value)
+	}
+
+	b, ok := sc.bindings[ident.Value]
+	if !ok {
+		b = &binding{pos: ident.Pos()}
+		sc.bindings[ident.Value] = b
+		sc.names = append(sc.names, ident.Value)
+	}
+
+	return b
+}
+
+// Declare every let in the scope of node
+func (l *linter) declareLets(node ast.Node, sc *scope) {
+	resolver.DeclareLets(node, func(let *ast.LetStatement) {
+		b := l.declare(sc, let.Name)
+		b.lets++
+
+		b.fn, _ = let.Value.(*ast.FnLiteral)
+		if b.lets > 1 || b.param || b.catch {
+			b.fn = nil
+		}
+	})
+}
+
+// Report the unused lets and parameters of a scope which has been walked
+func (l *linter) close(sc *scope) {
+	for _, name := range sc.names {
+		b := sc.bindings[name]
+		if b.used || b.catch || strings.HasPrefix(name, "_") {
+			continue
+		}
+
+		if b.param {
+			l.report(b.pos, UNUSED, "Unused parameter %s", name)
+		} else {
+			l.report(b.pos, UNUSED, "Unused let %s", name)
+		}
+	}
+}
+
+type visitor struct {
+	l     *linter
+	scope *scope
+}
+
+func (v *visitor) Visit(astNode ast.Node) ast.Visitor {
+	switch node := astNode.(type) {
+	case *ast.Program:
+		v.checkUnreachable(node.Statements)
+
+	case *ast.BlockStatement:
+		v.checkUnreachable(node.Statements)
+
+	case *ast.LetStatement:
+		// The name is a declaration rather than a reference, bound once the value is
+		ast.Walk(v, node.Value)
+		v.scope.bindings[node.Name.Value].bound = true
+		return nil
+
+	case *ast.Identifier:
+		b := v.lookup(node.Value)
+		if defined := b != nil && v.bound(node.Value) || b == nil && v.l.known[node.Value]; !defined {
+			v.l.report(node.Pos(), UNDEFINED, "Unknown Identifier %s", node.Value)
+		}
+
+	case *ast.IndexExpression:
+		if module, member, ok := v.member(node); ok && !v.l.known[module+"."+member] {
+			v.l.report(node.Index.Pos(), UNDEFINED, "Unknown member %s of module %s", member, module)
+		}
+
+	case *ast.InfixExpression:
+		v.checkComparison(node)
+
+	case *ast.CallExpression:
+		v.checkArity(node)
+
+	case *ast.FnLiteral:
+		inner := &visitor{l: v.l, scope: newScope(v.scope, true)}
+		for _, param := range node.Parameters {
+			b := v.l.declare(inner.scope, param)
+			b.param, b.bound = true, true
+		}
+		v.l.declareLets(node.Body, inner.scope)
+
+		ast.Walk(inner, node.Body)
+		v.l.close(inner.scope)
+		return nil
+
+	case *ast.TryExpression:
+		ast.Walk(v, node.Block)
+
+		if node.Catch != nil {
+			inner := &visitor{l: v.l, scope: newScope(v.scope, false)}
+			b := v.l.declare(inner.scope, node.Param)
+			b.catch, b.bound = true, true
+			v.l.declareLets(node.Catch, inner.scope)
+
+			ast.Walk(inner, node.Catch)
+			v.l.close(inner.scope)
+		}
+
+		if node.Finally != nil {
+			ast.Walk(v, node.Finally)
+		}
+		return nil
+	}
+
+	return v
+}
+
+// Innermost variable called name, marked as used. Nil for builtins and unknown names
+func (v *visitor) lookup(name string) *binding {
+	for sc := v.scope; sc != nil; sc = sc.parent {
+		if b, ok := sc.bindings[name]; ok {
+			b.used = true
+			return b
+		}
+	}
+
+	return nil
+}
+
+// Whether the innermost variable called name is bound when a reference from here is evaluated: its let
+// came before, or the reference is in a function which is only called later
+func (v *visitor) bound(name string) bool {
+	for sc := v.scope; sc != nil; sc = sc.parent {
+		if b, ok := sc.bindings[name]; ok {
+			return b.bound
+		}
+		if sc.function {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Qualified name of the builtin expr refers to, if it is an unshadowed builtin or module member
+func (v *visitor) builtin(expr ast.Expression) (string, bool) {
+	if ident, ok := expr.(*ast.Identifier); ok {
+		return ident.Value, v.lookup(ident.Value) == nil && v.l.known[ident.Value]
+	}
+
+	if index, ok := expr.(*ast.IndexExpression); ok {
+		if module, member, ok := v.member(index); ok {
+			return module + "." + member, v.l.known[module+"."+member]
+		}
+	}
+
+	return "", false
+}
+
+// Module and member name of an access to a builtin module, e.g. math.abs
+func (v *visitor) member(index *ast.IndexExpression) (string, string, bool) {
+	member, ok := index.Index.(*ast.StringLiteral)
+	if !ok || index.Token.Type != token.DOT {
+		return "", "", false
+	}
+
+	module, ok := v.builtin(index.Left)
+	if !ok {
+		return "", "", false
+	}
+
+	// Members of anything but a module, like the fields of a builtin's result, are unknown
+	if v.l.builtins != nil {
+		if _, isBuiltin := v.l.builtins.Lookup(module); isBuiltin {
+			return "", "", false
+		}
+	}
+
+	return module, member.Value, true
+}
+
+// Position of the first token of expr, rather than of its operator
+func start(expr ast.Expression) token.Position {
+	switch expr := expr.(type) {
+	case *ast.IndexExpression:
+		return start(expr.Left)
+	case *ast.InfixExpression:
+		return start(expr.Left)
+	case *ast.CallExpression:
+		return start(expr.Fn)
+	}
+
+	return expr.Pos()
+}
+
+func (v *visitor) checkUnreachable(stmts []ast.Statement) {
+	for idx, stmt := range stmts[:max(len(stmts)-1, 0)] {
+		switch stmt.(type) {
+		case *ast.ReturnStatement:
+			v.l.report(stmts[idx+1].Pos(), UNREACHABLE, "Unreachable code after return")
+			return
+
+		case *ast.ThrowStatement:
+			v.l.report(stmts[idx+1].Pos(), UNREACHABLE, "Unreachable code after throw")
+			return
+		}
+	}
+}
+
+func (v *visitor) checkComparison(infix *ast.InfixExpression) {
+	switch infix.Operator {
+	case "==", "!=", "<", ">":
+	default:
+		return
+	}
+
+	left, right := literalType(infix.Left), literalType(infix.Right)
+	if left != "" && right != "" && left != right {
+		v.l.report(infix.Pos(), MISMATCH, "Comparison type mismatch: %s %s %s", left, infix.Operator, right)
+	}
+}
+
+// Type of the value of expr when it is evident from the syntax alone
+func literalType(astExpr ast.Expression) string {
+	switch expr := astExpr.(type) {
+	case *ast.IntLiteral:
+		return "INTEGER"
+	case *ast.StringLiteral:
+		return "STRING"
+	case *ast.BoolLiteral:
+		return "BOOLEAN"
+	case *ast.ArrayLiteral:
+		return "ARRAY"
+	case *ast.HashLiteral:
+		return "HASH"
+	case *ast.FnLiteral:
+		return "FUNCTION"
+
+	case *ast.PrefixExpression:
+		if expr.Operator == "!" {
+			return "BOOLEAN"
+		}
+		if _, ok := expr.Operand.(*ast.IntLiteral); ok {
+			return "INTEGER"
+		}
+	}
+
+	return ""
+}
+
+func (v *visitor) checkArity(call *ast.CallExpression) {
+	if ident, ok := call.Fn.(*ast.Identifier); ok {
+		for sc := v.scope; sc != nil; sc = sc.parent {
+			b, ok := sc.bindings[ident.Value]
+			if !ok {
+				continue
+			}
+
+			if b.fn != nil && len(b.fn.Parameters) != len(call.Args) {
+				v.l.report(start(call.Fn), ARITY, "Invalid number of args to %s, Got=%d, expected=%d", ident.Value, len(call.Args), len(b.fn.Parameters))
+			}
+			return
+		}
+	}
+
+	if fn, ok := call.Fn.(*ast.FnLiteral); ok && len(fn.Parameters) != len(call.Args) {
+		v.l.report(start(call.Fn), ARITY, "Invalid number of args to function, Got=%d, expected=%d", len(call.Args), len(fn.Parameters))
+		return
+	}
+
+	name, ok := v.builtin(call.Fn)
+	if !ok || v.l.builtins == nil {
+		return
+	}
+
+	builtin, ok := v.l.builtins.Lookup(name)
+	if !ok {
+		return
+	}
+
+	least := len(builtin.Params) - builtin.Optional
+	if builtin.Variadic {
+		least = min(least, len(builtin.Params)-1)
+	}
+
+	switch {
+	case builtin.Variadic && len(call.Args) < least:
+		v.l.report(start(call.Fn), ARITY, "Invalid number of args to %s, Got=%d, expected at least %d", name, len(call.Args), least)
+
+	case !builtin.Variadic && (len(call.Args) < least || len(call.Args) > len(builtin.Params)):
+		expected := fmt.Sprint(len(builtin.Params))
+		if least != len(builtin.Params) {
+			expected = fmt.Sprintf("%d to %d", least, len(builtin.Params))
+		}
+		v.l.report(start(call.Fn), ARITY, "Invalid number of args to %s, Got=%d, expected=%s", name, len(call.Args), expected)
+	}
+}
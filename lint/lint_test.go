@@ -0,0 +1,100 @@
+package lint
+
+import (
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/parser"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		// Clean programs
+		{`let x = 5; puts(x)`, nil},
+		{`let f = fn(n) { if (n == 0) { 1 } else { n * f(n - 1) } }; f(5)`, nil},
+		{`let adder = fn(x) { fn(y) { x + y } }; adder(1)(2)`, nil},
+		{`let f = fn() { g() }; let g = fn() { 1 }; f()`, nil},
+		{`try { throw 1 } catch (e) { 2 }`, nil},
+		{`let f = fn(_unused) { 1 }; f(1)`, nil},
+		{`math.abs(-1); time.format(time.now()); puts()`, nil},
+
+		// Undefined
+		{`let x = 1; y`, []string{"1:12: Unknown Identifier y (undefined)"}},
+		{`fn(x) { x + z }(1)`, []string{"1:13: Unknown Identifier z (undefined)"}},
+		{`try { 1 } catch (e) { 2 }; e`, []string{"1:28: Unknown Identifier e (undefined)"}},
+		{`math.abz(1)`, []string{"1:6: Unknown member abz of module math (undefined)"}},
+		{`let math = {"abz": 1}; math.abz`, []string{"1:5: Builtin math is shadowed (shadow)"}},
+		// Used before the let binding it, unless from a function called later
+		{`x; let x = 1`, []string{"1:1: Unknown Identifier x (undefined)"}},
+		{`let x = x; 1`, []string{"1:9: Unknown Identifier x (undefined)"}},
+		{`let f = fn() { let r = y; let y = 1; r }; f()`, []string{"1:24: Unknown Identifier y (undefined)"}},
+		{`let x = 1; let f = fn() { let r = x; let x = 2; r }; f()`, []string{"1:35: Unknown Identifier x (undefined)"}},
+		{`try { 1 } catch (e) { z; let z = e }`, []string{"1:23: Unknown Identifier z (undefined)"}},
+		{`let f = fn() { let g = fn() { y }; let y = 1; g() }; f()`, nil},
+		{`try { 1 } catch (e) { let g = fn() { z }; let z = e; g() }`, nil},
+
+		// Unused
+		{`let f = fn(a, b) { a }; f(1, 2)`, []string{"1:15: Unused parameter b (unused)"}},
+		{`let f = fn() { let x = 1; 2 }; f()`, []string{"1:20: Unused let x (unused)"}},
+		{`try { 1 } catch (e) { let x = 1; e }`, []string{"1:27: Unused let x (unused)"}},
+		{`let f = fn() { if (true) { let x = 1 }; x }; f()`, nil},
+
+		// Unreachable
+		{`let f = fn() { return 1; puts(2) }; f()`, []string{"1:26: Unreachable code after return (unreachable)"}},
+		{`if (true) { throw 1; 2; 3 }`, []string{"1:22: Unreachable code after throw (unreachable)"}},
+
+		// Shadowed builtins
+		{`let len = 1; len`, []string{"1:5: Builtin len is shadowed (shadow)"}},
+		{`fn(puts) { puts }(1)`, []string{"1:4: Builtin puts is shadowed (shadow)"}},
+
+		// Mismatched comparisons
+		{`1 == "1"`, []string{"1:3: Comparison type mismatch: INTEGER == STRING (mismatch)"}},
+		{`-1 < true; !x != [1]`, []string{
+			"1:4: Comparison type mismatch: INTEGER < BOOLEAN (mismatch)",
+			"1:13: Unknown Identifier x (undefined)",
+			"1:15: Comparison type mismatch: BOOLEAN != ARRAY (mismatch)",
+		}},
+		{`let x = 1; x == "1"; 1 + 1 == 2`, nil},
+
+		// Arity
+		{`let f = fn(a, b) { a + b }; f(1)`, []string{"1:29: Invalid number of args to f, Got=1, expected=2 (arity)"}},
+		{`fn(x) { x }(1, 2)`, []string{"1:1: Invalid number of args to function, Got=2, expected=1 (arity)"}},
+		{`len("a", "b")`, []string{"1:1: Invalid number of args to len, Got=2, expected=1 (arity)"}},
+		{`time.format()`, []string{"1:1: Invalid number of args to time.format, Got=0, expected=1 to 2 (arity)"}},
+		{`let f = fn(a) { a }; let f = fn() { 1 }; f()`, nil},
+		{`let g = fn(f) { f(1, 2) }; g(len)`, nil},
+		{`let len = fn() { 1 }; len()`, []string{"1:5: Builtin len is shadowed (shadow)"}},
+	}
+
+	builtins := evaluator.DefaultRegistry(evaluator.Config{})
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("Parser errors for %q: %v", tt.input, p.Errors())
+		}
+
+		var got []string
+		for _, diag := range Lint(program, builtins) {
+			got = append(got, diag.String())
+		}
+
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("Wrong diagnostics for %q.\nGot=%q\nexpected=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestLintWithoutBuiltins(t *testing.T) {
+	program := parser.New(lexer.New(`puts(1)`)).ParseProgram()
+
+	diags := Lint(program, nil)
+	if len(diags) != 1 || diags[0].Check != UNDEFINED {
+		t.Errorf("Wrong diagnostics. Got=%v", diags)
+	}
+}
@@ -0,0 +1,163 @@
+package lsp
+
+import (
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"monkey/typecheck"
+	"strings"
+	"unicode/utf8"
+)
+
+// Open document, parsed and indexed each time its text changes
+type document struct {
+	uri   string
+	text  string
+	lines []int // Offset of the start of each line
+
+	program  *ast.Program
+	errors   []string
+	errorPos []token.Position
+	info     *typecheck.Info // Inferred types, nil when the document has syntax errors
+
+	scopes   []*scope   // Program first, each scope before the ones nested in it
+	mentions []*mention // In source order
+}
+
+func newDocument(uri, text string, builtins *evaluator.Registry) *document {
+	d := &document{uri: uri, text: text, lines: []int{0}}
+	for idx := 0; idx < len(text); idx++ {
+		if text[idx] == '\n' {
+			d.lines = append(d.lines, idx+1)
+		}
+	}
+
+	p := parser.New(lexer.NewFile(uri, text))
+	d.program = p.ParseProgram()
+	d.errors, d.errorPos = p.Errors(), p.ErrorPositions()
+
+	// Statements of a program with syntax errors may be incomplete, they are indexed but not type checked
+	if len(d.errors) == 0 {
+		d.info, _ = typecheck.Check(d.program, builtins)
+	}
+
+	d.index(builtins)
+	return d
+}
+
+/*** Positions ***/
+
+// Byte offset of pos, clamped to the document
+func (d *document) offset(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lines) {
+		return len(d.text)
+	}
+
+	offset := d.lines[pos.Line]
+	for units := 0; units < pos.Character && offset < len(d.text) && d.text[offset] != '\n'; {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		units += utf16Len(r)
+		offset += size
+	}
+
+	return offset
+}
+
+// LSP position of the byte offset, whose character counts UTF-16 code units
+func (d *document) position(offset int) Position {
+	offset = min(max(offset, 0), len(d.text))
+
+	line := len(d.lines) - 1
+	for d.lines[line] > offset {
+		line--
+	}
+
+	units := 0
+	for _, r := range d.text[d.lines[line]:offset] {
+		units += utf16Len(r)
+	}
+
+	return Position{Line: line, Character: units}
+}
+
+func (d *document) span(start, end int) Range {
+	return Range{Start: d.position(start), End: d.position(end)}
+}
+
+// Range of the word starting at pos, or of its single character when it does not start a word
+func (d *document) word(pos token.Position) Range {
+	end := pos.Offset
+	for end < len(d.text) && isWordChar(d.text[end]) {
+		end++
+	}
+
+	if end == pos.Offset && end < len(d.text) && d.text[end] != '\n' {
+		_, size := utf8.DecodeRuneInString(d.text[end:])
+		end += size
+	}
+
+	return d.span(pos.Offset, end)
+}
+
+// Number of UTF-16 code units encoding r
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+func isWordChar(ch byte) bool {
+	return ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9'
+}
+
+/*** Queries ***/
+
+// Mention of a symbol or builtin at offset, including right after its last character
+func (d *document) mentionAt(offset int) *mention {
+	for _, m := range d.mentions {
+		if m.start <= offset && offset <= m.end {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// Innermost scope containing offset
+func (d *document) scopeAt(offset int) *scope {
+	innermost := d.scopes[0]
+	for _, sc := range d.scopes[1:] {
+		if sc.contains(offset) {
+			innermost = sc
+		}
+	}
+
+	return innermost
+}
+
+// Text of the comment on the lines right above pos, without the slashes
+func (d *document) comment(pos token.Position) string {
+	var lines []string
+
+	line := pos.Line - 1
+	for idx := len(d.program.Comments) - 1; idx >= 0; idx-- {
+		comment := d.program.Comments[idx]
+		if comment.Pos().Offset >= pos.Offset {
+			continue
+		}
+		if comment.Trailing || comment.Pos().Line != line {
+			break
+		}
+
+		lines = append([]string{strings.TrimSpace(strings.TrimPrefix(comment.Text(), "//"))}, lines...)
+		line--
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,240 @@
+package lsp
+
+import (
+	"monkey/printer"
+	"monkey/typecheck"
+	"sort"
+	"strings"
+)
+
+/*** Diagnostics ***/
+
+// Syntax errors of the document, each marking the word it was found at
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for idx, msg := range d.errors {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.word(d.errorPos[idx]),
+			Severity: severityError,
+			Source:   "monk",
+			Message:  msg,
+		})
+	}
+
+	return diagnostics
+}
+
+/*** Navigation ***/
+
+// Location of the first declaration of the variable at the position, null for builtins and unknown names
+func (s *Server) definition(params TextDocumentPositionParams) (*Location, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	m := d.mentionAt(d.offset(params.Position))
+	if m == nil || m.symbol == nil {
+		return nil, nil
+	}
+
+	decl := m.symbol.decl.Pos().Offset
+	return &Location{URI: d.uri, Range: d.span(decl, decl+len(m.symbol.name))}, nil
+}
+
+// Locations of every mention of the variable at the position, its declarations included when asked
+func (s *Server) references(params ReferenceParams) ([]Location, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := []Location{}
+
+	at := d.mentionAt(d.offset(params.Position))
+	if at == nil || at.symbol == nil {
+		return locations, nil
+	}
+
+	for _, m := range d.mentions {
+		if m.symbol == at.symbol && (!m.decl || params.Context.IncludeDeclaration) {
+			locations = append(locations, Location{URI: d.uri, Range: d.span(m.start, m.end)})
+		}
+	}
+
+	return locations, nil
+}
+
+/*** Hover ***/
+
+// Kind and inferred type of the variable at the position with its doc comment, or the signature of the builtin
+func (s *Server) hover(params TextDocumentPositionParams) (*Hover, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	m := d.mentionAt(d.offset(params.Position))
+	if m == nil {
+		return nil, nil
+	}
+
+	var header, doc string
+	switch {
+	case m.symbol != nil:
+		header = m.symbol.name
+		if t := d.typeOf(m); t != nil {
+			header += ": " + t.String()
+		}
+
+		if m.symbol.kind == "let" {
+			header = "let " + header
+		} else {
+			header = "(" + m.symbol.kind + ") " + header
+		}
+		doc = m.symbol.doc
+
+	default:
+		header = "module " + m.builtin
+		if s.builtins != nil {
+			if builtin, ok := s.builtins.Lookup(m.builtin); ok {
+				header, doc = builtin.Signature(), builtin.Doc
+			}
+		}
+	}
+
+	value := "```monkey\n" + header + "\n```"
+	if doc != "" {
+		value += "\n\n" + doc
+	}
+
+	span := d.span(m.start, m.end)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: value}, Range: &span}, nil
+}
+
+// Type inferred for the variable at m, nil when the document could not be checked
+func (d *document) typeOf(m *mention) typecheck.Type {
+	if d.info == nil {
+		return nil
+	}
+
+	if t := d.info.TypeOf(m.ident); t != nil {
+		return t
+	}
+
+	return d.info.TypeOf(m.symbol.decl)
+}
+
+/*** Completion ***/
+
+// Variables visible at the position and builtins whose name starts with the word before it,
+// or the members of the module when the word follows a dot
+func (s *Server) completion(params TextDocumentPositionParams) ([]CompletionItem, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := d.offset(params.Position)
+	start := offset
+	for start > 0 && isWordChar(d.text[start-1]) {
+		start--
+	}
+	prefix := d.text[start:offset]
+
+	if start > 0 && d.text[start-1] == '.' {
+		module := start - 1
+		for module > 0 && (isWordChar(d.text[module-1]) || d.text[module-1] == '.') {
+			module--
+		}
+
+		return s.builtinItems(d.text[module:start-1]+".", prefix, nil), nil
+	}
+
+	items := []CompletionItem{}
+	seen := map[string]bool{}
+
+	for sc := d.scopeAt(offset); sc != nil; sc = sc.parent {
+		for _, name := range sc.names {
+			sym := sc.symbols[name]
+
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			// The name being typed in a declaration is not a completion of itself
+			if !strings.HasPrefix(name, prefix) || sym.decl.Pos().Offset == start {
+				continue
+			}
+
+			item := CompletionItem{Label: name, Kind: completionVariable, Detail: sym.kind}
+			if d.info != nil {
+				if t := d.info.TypeOf(sym.decl); t != nil {
+					item.Detail = t.String()
+					if _, ok := t.(*typecheck.Function); ok {
+						item.Kind = completionFunction
+					}
+				}
+			}
+			items = append(items, item)
+		}
+	}
+
+	// Variables shadow the builtins of the same name
+	return append(items, s.builtinItems("", prefix, seen)...), nil
+}
+
+// Builtins and modules directly inside the module, whose qualified name starts with module, starting with prefix
+func (s *Server) builtinItems(module, prefix string, seen map[string]bool) []CompletionItem {
+	items := []CompletionItem{}
+	if s.builtins == nil {
+		return items
+	}
+
+	added := map[string]bool{}
+	for _, qualified := range s.builtins.Names() {
+		rest, ok := strings.CutPrefix(qualified, module)
+		if !ok {
+			continue
+		}
+
+		name, _, nested := strings.Cut(rest, ".")
+		if added[name] || seen[name] || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		added[name] = true
+
+		if nested {
+			items = append(items, CompletionItem{Label: name, Kind: completionModule, Detail: "module " + module + name})
+			continue
+		}
+
+		builtin, _ := s.builtins.Lookup(qualified)
+		items = append(items, CompletionItem{Label: name, Kind: completionFunction, Detail: builtin.Signature()})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	return items
+}
+
+/*** Formatting ***/
+
+// Edit replacing the whole document with its formatted text, null when it has syntax errors
+func (s *Server) formatting(params DocumentFormattingParams) ([]TextEdit, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	formatted, err := printer.Format([]byte(d.text))
+	if err != nil {
+		return nil, nil
+	}
+
+	if string(formatted) == d.text {
+		return []TextEdit{}, nil
+	}
+
+	return []TextEdit{{Range: d.span(0, len(d.text)), NewText: string(formatted)}}, nil
+}
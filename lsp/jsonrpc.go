@@ -0,0 +1,56 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"monkey/internal/frame"
+)
+
+// Stream of JSON-RPC messages, each preceded by a Content-Length header as LSP frames them
+type conn struct {
+	*frame.Reader
+	out io.Writer
+}
+
+func newConn(in io.Reader, out io.Writer) *conn {
+	return &conn{Reader: frame.NewReader(in), out: out}
+}
+
+func (c *conn) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return frame.Write(c.out, body)
+}
+
+func (c *conn) reply(id *json.RawMessage, result any) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	return c.write(&message{ID: id, Result: body})
+}
+
+func (c *conn) replyError(id *json.RawMessage, code int, format string, args ...any) error {
+	// A response to a message whose id could not be read has a null id
+	if id == nil {
+		null := json.RawMessage("null")
+		id = &null
+	}
+
+	return c.write(&message{ID: id, Error: &responseError{Code: code, Message: fmt.Sprintf(format, args...)}})
+}
+
+func (c *conn) notify(method string, params any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	return c.write(&message{Method: method, Params: body})
+}
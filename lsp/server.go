@@ -0,0 +1,176 @@
+// Package lsp implements a Language Server Protocol server for Monkey documents,
+// speaking JSON-RPC over a pair of streams such as stdin and stdout
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"monkey/evaluator"
+)
+
+type Server struct {
+	builtins  *evaluator.Registry
+	documents map[string]*document // Open documents by URI
+	conn      *conn
+
+	initialized bool
+	shutdown    bool
+}
+
+// Create a Server for documents which may use the builtins of the registry, which may be nil
+func NewServer(builtins *evaluator.Registry) *Server {
+	return &Server{builtins: builtins, documents: map[string]*document{}}
+}
+
+// Handle the messages read from in, writing responses and notifications to out, until the client exits or in ends.
+// An error is returned when a stream breaks or the client exits without shutting the server down
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.conn = newConn(in, out)
+
+	for {
+		body, err := s.conn.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			if err := s.conn.replyError(nil, parseError, "Invalid message: %s", err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("Exit without shutdown")
+			}
+			return nil
+		}
+
+		if msg.ID == nil {
+			err = s.notification(&msg)
+		} else {
+			err = s.request(&msg)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Handle a notification, which gets no response. Unknown ones are ignored as the protocol asks
+func (s *Server) notification(msg *message) error {
+	if !s.initialized || s.shutdown {
+		return nil
+	}
+
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if json.Unmarshal(msg.Params, &params) == nil {
+			return s.update(params.TextDocument.URI, params.TextDocument.Text)
+		}
+
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if json.Unmarshal(msg.Params, &params) == nil && len(params.ContentChanges) > 0 {
+			// Changes carry the full text, the last one is the current text
+			return s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if json.Unmarshal(msg.Params, &params) == nil {
+			delete(s.documents, params.TextDocument.URI)
+			return s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+		}
+	}
+
+	return nil
+}
+
+// Parse the new text of a document and publish its diagnostics
+func (s *Server) update(uri, text string) error {
+	d := newDocument(uri, text, s.builtins)
+	s.documents[uri] = d
+
+	return s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: d.diagnostics()})
+}
+
+// Handle a request and write its response
+func (s *Server) request(msg *message) error {
+	switch {
+	case s.shutdown:
+		return s.conn.replyError(msg.ID, invalidRequest, "Server is shut down")
+	case !s.initialized && msg.Method != "initialize":
+		return s.conn.replyError(msg.ID, serverNotInitialized, "Server is not initialized")
+	}
+
+	var result any
+	var err error
+
+	switch msg.Method {
+	case "initialize":
+		s.initialized = true
+		result = InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:           syncFull,
+				DefinitionProvider:         true,
+				ReferencesProvider:         true,
+				HoverProvider:              true,
+				CompletionProvider:         &CompletionOptions{TriggerCharacters: []string{"."}},
+				DocumentFormattingProvider: true,
+			},
+			ServerInfo: ServerInfo{Name: "monk"},
+		}
+
+	case "shutdown":
+		s.shutdown = true
+
+	case "textDocument/definition":
+		result, err = handle(s, msg.Params, (*Server).definition)
+	case "textDocument/references":
+		result, err = handle(s, msg.Params, (*Server).references)
+	case "textDocument/hover":
+		result, err = handle(s, msg.Params, (*Server).hover)
+	case "textDocument/completion":
+		result, err = handle(s, msg.Params, (*Server).completion)
+	case "textDocument/formatting":
+		result, err = handle(s, msg.Params, (*Server).formatting)
+
+	default:
+		return s.conn.replyError(msg.ID, methodNotFound, "Unknown method %s", msg.Method)
+	}
+
+	if err != nil {
+		return s.conn.replyError(msg.ID, invalidParams, "%s", err)
+	}
+
+	return s.conn.reply(msg.ID, result)
+}
+
+// Decode the params of a request and pass them to the method handling it
+func handle[P, R any](s *Server, raw json.RawMessage, method func(s *Server, params P) (R, error)) (any, error) {
+	var params P
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, fmt.Errorf("Invalid params: %w", err)
+	}
+
+	return method(s, params)
+}
+
+func (s *Server) document(uri string) (*document, error) {
+	d, ok := s.documents[uri]
+	if !ok {
+		return nil, fmt.Errorf("Unknown document %s", uri)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,463 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"monkey/evaluator"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Client talking to a Server serving in-process over pipes
+type client struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *conn
+	nextID int
+	done   chan error // Result of Serve
+}
+
+func startServer(t *testing.T) *client {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+
+	c := &client{t: t, in: inWriter, out: newConn(outReader, nil), done: make(chan error, 1)}
+	go func() {
+		err := NewServer(evaluator.DefaultRegistry(evaluator.Config{})).Serve(inReader, outWriter)
+		outWriter.Close()
+		c.done <- err
+	}()
+
+	t.Cleanup(func() { inWriter.Close() })
+	return c
+}
+
+func (c *client) send(msg *message) {
+	c.t.Helper()
+	if err := newConn(nil, c.in).write(msg); err != nil {
+		c.t.Fatalf("Failed to send %s: %s", msg.Method, err)
+	}
+}
+
+func (c *client) receive() *message {
+	c.t.Helper()
+	body, err := c.out.Read()
+	if err != nil {
+		c.t.Fatalf("Failed to receive: %s", err)
+	}
+
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatalf("Invalid message %s: %s", body, err)
+	}
+
+	return &msg
+}
+
+func (c *client) notify(method string, params any) {
+	c.t.Helper()
+	body, _ := json.Marshal(params)
+	c.send(&message{Method: method, Params: body})
+}
+
+// Send a request and return its response
+func (c *client) request(method string, params any) *message {
+	c.t.Helper()
+	c.nextID++
+	id := json.RawMessage(fmt.Sprint(c.nextID))
+
+	body, _ := json.Marshal(params)
+	c.send(&message{ID: &id, Method: method, Params: body})
+
+	msg := c.receive()
+	if msg.ID == nil || string(*msg.ID) != string(id) {
+		c.t.Fatalf("Expected the response to %s, Got=%+v", method, msg)
+	}
+
+	return msg
+}
+
+// Send a request and decode its result into result
+func (c *client) call(method string, params any, result any) {
+	c.t.Helper()
+	msg := c.request(method, params)
+	if msg.Error != nil {
+		c.t.Fatalf("Unexpected error for %s: %s", method, msg.Error.Message)
+	}
+
+	if err := json.Unmarshal(msg.Result, result); err != nil {
+		c.t.Fatalf("Invalid result for %s %s: %s", method, msg.Result, err)
+	}
+}
+
+func (c *client) initialize() {
+	c.t.Helper()
+	var result InitializeResult
+	c.call("initialize", map[string]any{"capabilities": map[string]any{}}, &result)
+}
+
+// Open a document and return the diagnostics published for it
+func (c *client) open(uri, text string) []Diagnostic {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, LanguageID: "monkey", Version: 1, Text: text}})
+	return c.diagnostics(uri)
+}
+
+func (c *client) diagnostics(uri string) []Diagnostic {
+	c.t.Helper()
+	msg := c.receive()
+	if msg.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("Expected diagnostics, Got=%+v", msg)
+	}
+
+	var params PublishDiagnosticsParams
+	json.Unmarshal(msg.Params, &params)
+	if params.URI != uri {
+		c.t.Fatalf("Diagnostics of the wrong document, Got=%s, expected=%s", params.URI, uri)
+	}
+
+	return params.Diagnostics
+}
+
+func at(uri string, line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: line, Character: character}}
+}
+
+func span(line, start, end int) Range {
+	return Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: end}}
+}
+
+func TestLifecycle(t *testing.T) {
+	c := startServer(t)
+
+	if msg := c.request("textDocument/hover", at("file:///a.monk", 0, 0)); msg.Error == nil || msg.Error.Code != serverNotInitialized {
+		t.Errorf("Request before initialize not rejected, Got=%+v", msg)
+	}
+
+	var result InitializeResult
+	c.call("initialize", map[string]any{"capabilities": map[string]any{}}, &result)
+	if !result.Capabilities.HoverProvider || result.Capabilities.TextDocumentSync != syncFull || result.ServerInfo.Name != "monk" {
+		t.Errorf("Wrong capabilities, Got=%+v", result)
+	}
+
+	// Notifications the server does not know are ignored, unknown requests are not
+	c.notify("$/setTrace", map[string]any{"value": "off"})
+	if msg := c.request("workspace/symbol", map[string]any{}); msg.Error == nil || msg.Error.Code != methodNotFound {
+		t.Errorf("Unknown method not rejected, Got=%+v", msg)
+	}
+
+	if msg := c.request("textDocument/hover", at("file:///missing.monk", 0, 0)); msg.Error == nil || msg.Error.Message != "Unknown document file:///missing.monk" {
+		t.Errorf("Unknown document not rejected, Got=%+v", msg)
+	}
+
+	if msg := c.request("shutdown", nil); msg.Error != nil || string(msg.Result) != "null" {
+		t.Errorf("Wrong response to shutdown, Got=%+v", msg)
+	}
+	if msg := c.request("textDocument/hover", at("file:///a.monk", 0, 0)); msg.Error == nil || msg.Error.Code != invalidRequest {
+		t.Errorf("Request after shutdown not rejected, Got=%+v", msg)
+	}
+
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("Serve failed after exit: %s", err)
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+	c.notify("exit", nil)
+
+	if err := <-c.done; err == nil || err.Error() != "Exit without shutdown" {
+		t.Errorf("Wrong error, Got=%v", err)
+	}
+}
+
+func TestInvalidMessages(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Error of Serve, or of the response when it keeps serving
+	}{
+		{"Content-Length: 3\r\n\r\n{]}", "Invalid message: invalid character ']' looking for beginning of object key string"},
+		{"Content-Type: text\r\n\r\n{}", "Missing Content-Length header"},
+		{"Content-Length: ten\r\n\r\n", `Invalid Content-Length "ten"`},
+		{"Content-Length 2\r\n\r\n{}", `Invalid header "Content-Length 2"`},
+		{"Content-Length: 10\r\n\r\n{}", "Unexpected end of message: unexpected EOF"},
+		{"Content-Length: 2\r\n", "Unexpected end of header: EOF"},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+		err := NewServer(nil).Serve(strings.NewReader(tt.input), &out)
+
+		if err == nil {
+			body, _ := newConn(strings.NewReader(out.String()), nil).Read()
+			var msg message
+			json.Unmarshal(body, &msg)
+
+			if msg.Error == nil || msg.Error.Code != parseError || msg.Error.Message != tt.expected || !strings.Contains(out.String(), `"id":null`) {
+				t.Errorf("Wrong response to %q, Got=%s", tt.input, out.String())
+			}
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("Wrong error for %q, Got=%q, expected=%q", tt.input, err, tt.expected)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+
+	uri := "file:///a.monk"
+	diagnostics := c.open(uri, "let x = 1;\nlet = 2;\nlet y = @")
+	expected := []Diagnostic{
+		{Range: span(1, 4, 5), Severity: severityError, Source: "monk", Message: "expectPeek found unexpected peek, Got=Assign Expected=Identifier. Line 2, Column 5"},
+		{Range: span(1, 4, 5), Severity: severityError, Source: "monk", Message: "No prefix parser function found for token Assign. Line 2 Column 5"},
+		{Range: span(2, 8, 9), Severity: severityError, Source: "monk", Message: "No prefix parser function found for token Illegal. Line 3 Column 9"},
+	}
+	if !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("Wrong diagnostics.\nGot=%+v\nexpected=%+v", diagnostics, expected)
+	}
+
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{URI: uri},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "let x = 1;"}},
+	})
+	if diagnostics := c.diagnostics(uri); len(diagnostics) != 0 {
+		t.Errorf("Diagnostics not cleared by a fix, Got=%+v", diagnostics)
+	}
+
+	c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+	if diagnostics := c.diagnostics(uri); len(diagnostics) != 0 {
+		t.Errorf("Diagnostics not cleared on close, Got=%+v", diagnostics)
+	}
+}
+
+const navigation = `// Adds two numbers
+// Works on strings too
+let add = fn(a, b) { a + b };
+let x = add(1, 2);
+let f = fn(x) { x * add(x, 1) };
+try { f(x) } catch (e) { len(e) + math.abs(x) }`
+
+func TestDefinition(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+	c.open("file:///nav.monk", navigation)
+
+	tests := []struct {
+		line, character int
+		expected        *Range
+	}{
+		{3, 8, &Range{Start: Position{2, 4}, End: Position{2, 7}}},  // add
+		{3, 11, &Range{Start: Position{2, 4}, End: Position{2, 7}}}, // Right after add
+		{2, 5, &Range{Start: Position{2, 4}, End: Position{2, 7}}},  // The declaration itself
+		{2, 25, &Range{Start: Position{2, 16}, End: Position{2, 17}}},
+		{4, 16, &Range{Start: Position{4, 11}, End: Position{4, 12}}}, // Parameter shadowing the global x
+		{5, 8, &Range{Start: Position{3, 4}, End: Position{3, 5}}},
+		{5, 29, &Range{Start: Position{5, 20}, End: Position{5, 21}}}, // Caught error
+		{5, 25, nil}, // len
+		{5, 40, nil}, // math.abs
+		{3, 13, nil}, // Literal
+	}
+
+	for _, tt := range tests {
+		var got *Location
+		c.call("textDocument/definition", at("file:///nav.monk", tt.line, tt.character), &got)
+
+		switch {
+		case tt.expected == nil && got != nil:
+			t.Errorf("Unexpected definition at %d:%d, Got=%+v", tt.line, tt.character, got)
+		case tt.expected != nil && (got == nil || got.Range != *tt.expected || got.URI != "file:///nav.monk"):
+			t.Errorf("Wrong definition at %d:%d, Got=%+v, expected=%+v", tt.line, tt.character, got, tt.expected)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+	c.open("file:///nav.monk", navigation)
+
+	tests := []struct {
+		line, character    int
+		includeDeclaration bool
+		expected           []Range
+	}{
+		{3, 8, true, []Range{span(2, 4, 7), span(3, 8, 11), span(4, 20, 23)}},
+		{3, 8, false, []Range{span(3, 8, 11), span(4, 20, 23)}},
+		{4, 11, true, []Range{span(4, 11, 12), span(4, 16, 17), span(4, 24, 25)}},
+		{3, 4, false, []Range{span(5, 8, 9), span(5, 43, 44)}},
+		{5, 25, true, []Range{}},
+	}
+
+	for _, tt := range tests {
+		params := ReferenceParams{TextDocumentPositionParams: at("file:///nav.monk", tt.line, tt.character)}
+		params.Context.IncludeDeclaration = tt.includeDeclaration
+
+		var got []Location
+		c.call("textDocument/references", params, &got)
+
+		ranges := []Range{}
+		for _, loc := range got {
+			ranges = append(ranges, loc.Range)
+		}
+
+		if !reflect.DeepEqual(ranges, tt.expected) {
+			t.Errorf("Wrong references at %d:%d, Got=%+v, expected=%+v", tt.line, tt.character, ranges, tt.expected)
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+	c.open("file:///nav.monk", navigation)
+
+	tests := []struct {
+		line, character int
+		expected        string
+	}{
+		{2, 5, "```monkey\nlet add: fn(t5, t5) -> t5\n```\n\nAdds two numbers\nWorks on strings too"},
+		{3, 8, "```monkey\nlet add: fn(int, int) -> int\n```\n\nAdds two numbers\nWorks on strings too"},
+		{2, 13, "```monkey\n(parameter) a: t5\n```"},
+		{4, 4, "```monkey\nlet f: fn(int) -> int\n```"},
+		{5, 20, "```monkey\n(catch) e: any\n```"},
+		{5, 25, "```monkey\nlen(value)\n```\n\nLength of a String or Array"},
+		{5, 35, "```monkey\nmodule math\n```"},
+		{5, 39, "```monkey\nmath.abs(x: INTEGER)\n```\n\nAbsolute value"},
+		{0, 3, ""},
+	}
+
+	for _, tt := range tests {
+		var got *Hover
+		c.call("textDocument/hover", at("file:///nav.monk", tt.line, tt.character), &got)
+
+		value := ""
+		if got != nil {
+			value = got.Contents.Value
+		}
+
+		if value != tt.expected {
+			t.Errorf("Wrong hover at %d:%d.\nGot=%q\nexpected=%q", tt.line, tt.character, value, tt.expected)
+		}
+	}
+}
+
+func TestHoverWithSyntaxErrors(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+	c.open("file:///a.monk", "let x = 1;\nx +")
+
+	var got *Hover
+	c.call("textDocument/hover", at("file:///a.monk", 1, 0), &got)
+
+	// Still indexed, though not type checked
+	if got == nil || got.Contents.Value != "```monkey\nlet x\n```" || *got.Range != span(1, 0, 1) {
+		t.Errorf("Wrong hover, Got=%+v", got)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+
+	src := "let apply = fn(f, arg) { f(arg) };\nlet answer = 42;\nlet len = fn(x) { 0 };\nfn(alpha) { a };\nmath.\nmath.ab\nre\nle"
+	c.open("file:///a.monk", src)
+
+	tests := []struct {
+		line, character int
+		expected        []string // Label: detail
+	}{
+		{3, 13, []string{"alpha: t8", "apply: fn(fn(t5) -> t6, t5) -> t6", "answer: int", "args: args()"}},
+		{4, 5, []string{"abs: math.abs(x: INTEGER)", "clamp: math.clamp(x: INTEGER, low: INTEGER, high: INTEGER)", "floor: math.floor(x: INTEGER)", "max: math.max(...values)", "min: math.min(...values)", "pow: math.pow(base: INTEGER, exp: INTEGER)", "sqrt: math.sqrt(x: INTEGER)"}},
+		{5, 7, []string{"abs: math.abs(x: INTEGER)"}},
+		{6, 2, []string{"read_file: read_file(path: STRING)", "regex: module regex", "rest: rest(arr: ARRAY)"}},
+		// The builtin len is shadowed, and the name being declared is not completed
+		{7, 2, []string{"len: fn(t7) -> int"}},
+		{2, 7, []string{}},
+	}
+
+	for _, tt := range tests {
+		var items []CompletionItem
+		c.call("textDocument/completion", at("file:///a.monk", tt.line, tt.character), &items)
+
+		got := []string{}
+		for _, item := range items {
+			got = append(got, item.Label+": "+item.Detail)
+		}
+
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Wrong completions at %d:%d.\nGot=%q\nexpected=%q", tt.line, tt.character, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c := startServer(t)
+	c.initialize()
+
+	tests := []struct {
+		input    string
+		expected string // Text of the edit, none when already formatted and null with syntax errors
+	}{
+		{"let x=1\n// Twice\nlet  y = x*2", "let x = 1;\n// Twice\nlet y = x * 2;\n"},
+		{"let x = 1;\n", "none"},
+		{"let x = ", "null"},
+	}
+
+	for idx, tt := range tests {
+		uri := fmt.Sprintf("file:///%d.monk", idx)
+		c.open(uri, tt.input)
+
+		msg := c.request("textDocument/formatting", DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+		var edits []TextEdit
+		json.Unmarshal(msg.Result, &edits)
+
+		got := string(msg.Result)
+		switch {
+		case len(edits) == 1:
+			got = edits[0].NewText
+			lines := strings.Split(tt.input, "\n")
+			if edits[0].Range != (Range{End: Position{Line: len(lines) - 1, Character: len(lines[len(lines)-1])}}) {
+				t.Errorf("Edit of %q does not span the document, Got=%+v", tt.input, edits[0].Range)
+			}
+		case got == "[]":
+			got = "none"
+		}
+
+		if got != tt.expected {
+			t.Errorf("Wrong formatting of %q, Got=%q, expected=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	d := newDocument("file:///a.monk", "let s = \"héllo 😀\";\nlet t = s", nil)
+
+	tests := []struct {
+		offset   int
+		position Position
+	}{
+		{0, Position{0, 0}},
+		{10, Position{0, 10}},
+		{12, Position{0, 11}}, // After the 2 byte é, a single code unit
+		{20, Position{0, 17}}, // After the 4 byte emoji, a pair of code units
+		{23, Position{1, 0}},
+		{32, Position{1, 9}},
+	}
+
+	for _, tt := range tests {
+		if got := d.position(tt.offset); got != tt.position {
+			t.Errorf("Wrong position of offset %d, Got=%+v, expected=%+v", tt.offset, got, tt.position)
+		}
+		if got := d.offset(tt.position); got != tt.offset {
+			t.Errorf("Wrong offset of %+v, Got=%d, expected=%d", tt.position, got, tt.offset)
+		}
+	}
+}
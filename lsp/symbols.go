@@ -0,0 +1,187 @@
+package lsp
+
+import (
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/resolver"
+	"monkey/token"
+	"sort"
+)
+
+// Variable declared by lets, the parameters of a function or a catch clause
+type symbol struct {
+	name string
+	kind string          // let, parameter or catch
+	decl *ast.Identifier // First declaration in the source
+	doc  string          // Comment on the lines right above the first let
+}
+
+// Symbols of a scope, see resolver.DeclareLets
+type scope struct {
+	parent     *scope
+	symbols    map[string]*symbol
+	names      []string // In order of declaration
+	start, end int      // Offsets of the source spanned by the scope
+}
+
+func (sc *scope) contains(offset int) bool {
+	return sc.start <= offset && offset <= sc.end
+}
+
+// Identifier or member name in the source, naming a symbol or a builtin
+type mention struct {
+	start, end int             // Offsets of the first and past the last byte
+	ident      *ast.Identifier // Nil for members of a module
+	decl       bool            // Whether ident declares the symbol rather than refers to it
+	symbol     *symbol
+	builtin    string // Qualified name of the builtin or module when there is no symbol
+}
+
+type indexer struct {
+	doc   *document
+	known map[string]bool // Registry.Defined of builtins
+	scope *scope
+}
+
+// Build the scopes and mentions of the document from its program
+func (d *document) index(builtins *evaluator.Registry) {
+	ix := &indexer{doc: d, known: builtins.Defined()}
+
+	ix.scope = ix.push(nil, 0, token.Position{})
+	ix.declareLets(d.program)
+	ast.Walk(ix, d.program)
+
+	// Members are named before the module they belong to
+	sort.SliceStable(d.mentions, func(i, j int) bool { return d.mentions[i].start < d.mentions[j].start })
+}
+
+// Enter a scope nested in parent, spanning from start to the closing brace at rbrace
+func (ix *indexer) push(parent *scope, start int, rbrace token.Position) *scope {
+	// The program, and blocks cut short by a syntax error, run to the end of the document
+	end := len(ix.doc.text)
+	if rbrace.IsValid() {
+		end = rbrace.Offset + 1
+	}
+
+	sc := &scope{parent: parent, symbols: map[string]*symbol{}, start: start, end: end}
+	ix.doc.scopes = append(ix.doc.scopes, sc)
+	return sc
+}
+
+// Declare the variable named by ident in the current scope, a repeated declaration names the same symbol
+func (ix *indexer) declare(ident *ast.Identifier, kind string) {
+	if _, ok := ix.scope.symbols[ident.Value]; ok {
+		return
+	}
+
+	ix.scope.symbols[ident.Value] = &symbol{name: ident.Value, kind: kind, decl: ident}
+	ix.scope.names = append(ix.scope.names, ident.Value)
+}
+
+// Declare every let in the scope of node, documented by the comment above the first
+func (ix *indexer) declareLets(node ast.Node) {
+	resolver.DeclareLets(node, func(let *ast.LetStatement) {
+		if _, ok := ix.scope.symbols[let.Name.Value]; !ok {
+			ix.declare(let.Name, "let")
+			ix.scope.symbols[let.Name.Value].doc = ix.doc.comment(let.Pos())
+		}
+	})
+}
+
+// Innermost symbol called name visible from the current scope
+func (ix *indexer) lookup(name string) *symbol {
+	for sc := ix.scope; sc != nil; sc = sc.parent {
+		if sym, ok := sc.symbols[name]; ok {
+			return sym
+		}
+	}
+
+	return nil
+}
+
+// Record ident as naming the symbol it declares or refers to, or the builtin
+func (ix *indexer) mention(ident *ast.Identifier, decl bool) {
+	m := &mention{start: ident.Pos().Offset, end: ident.Pos().Offset + len(ident.Value), ident: ident, decl: decl}
+
+	if m.symbol = ix.lookup(ident.Value); m.symbol == nil {
+		if !ix.known[ident.Value] {
+			return
+		}
+		m.builtin = ident.Value
+	}
+
+	ix.doc.mentions = append(ix.doc.mentions, m)
+}
+
+// Qualified name of the builtin or module expr refers to, if it is an unshadowed builtin or module member
+func (ix *indexer) builtin(expr ast.Expression) (string, bool) {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		return expr.Value, ix.lookup(expr.Value) == nil && ix.known[expr.Value]
+
+	case *ast.IndexExpression:
+		member, ok := expr.Index.(*ast.StringLiteral)
+		if !ok || expr.Token.Type != token.DOT {
+			return "", false
+		}
+
+		module, ok := ix.builtin(expr.Left)
+		return module + "." + member.Value, ok && ix.known[module+"."+member.Value]
+	}
+
+	return "", false
+}
+
+func (ix *indexer) Visit(astNode ast.Node) ast.Visitor {
+	switch node := astNode.(type) {
+	case *ast.LetStatement:
+		ix.mention(node.Name, true)
+		if node.Value != nil {
+			ast.Walk(ix, node.Value)
+		}
+		return nil
+
+	case *ast.Identifier:
+		ix.mention(node, false)
+
+	case *ast.IndexExpression:
+		if qualified, ok := ix.builtin(node); ok {
+			member := node.Index.(*ast.StringLiteral)
+			ix.doc.mentions = append(ix.doc.mentions, &mention{start: member.Pos().Offset, end: member.Pos().Offset + len(member.Value), builtin: qualified})
+		}
+
+	case *ast.FnLiteral:
+		inner := &indexer{doc: ix.doc, known: ix.known}
+		inner.scope = inner.push(ix.scope, node.Pos().Offset, node.Body.Rbrace)
+		for _, param := range node.Parameters {
+			inner.declare(param, "parameter")
+		}
+		inner.declareLets(node.Body)
+
+		for _, param := range node.Parameters {
+			inner.mention(param, true)
+		}
+		ast.Walk(inner, node.Body)
+		return nil
+
+	case *ast.TryExpression:
+		ast.Walk(ix, node.Block)
+
+		if node.Catch != nil {
+			inner := &indexer{doc: ix.doc, known: ix.known}
+			inner.scope = inner.push(ix.scope, node.Param.Pos().Offset, node.Catch.Rbrace)
+			inner.declare(node.Param, "catch")
+			inner.declareLets(node.Catch)
+
+			inner.mention(node.Param, true)
+			ast.Walk(inner, node.Catch)
+		}
+
+		if node.Finally != nil {
+			ast.Walk(ix, node.Finally)
+		}
+		return nil
+	}
+
+	return ix
+}
@@ -0,0 +1,599 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Struct fields are converted using the name in their `monkey:"name"` tag, or the field name if untagged.
+// A tag of "-" skips the field
+const structTag = "monkey"
+
+var (
+	objectType = reflect.TypeOf((*Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	timeType   = reflect.TypeOf(time.Time{})
+)
+
+// Convert a Go value into the equivalent Monkey object.
+// Supports nil, bools, integers, strings, slices, arrays, maps, structs, pointers, time.Time and functions.
+// Values which already are an Object are returned unchanged.
+// Pointers, maps and slices which contain themselves are reported as errors
+func FromGo(val any) (Object, error) {
+	if val == nil {
+		return NULL, nil
+	}
+
+	return fromValue(reflect.ValueOf(val), map[visit]bool{})
+}
+
+// Pointer, map or slice being converted, a slice by its backing array and length
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// Mark the pointer, map or slice v as being converted, failing when it already is as it then contains itself.
+// The caller unmarks it once done
+func enterValue(active map[visit]bool, v reflect.Value) (visit, error) {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+
+	if active[key] {
+		return key, fmt.Errorf("%s contains itself", v.Type())
+	}
+
+	active[key] = true
+	return key, nil
+}
+
+// Convert v, active holding the pointers, maps and slices which v is inside of
+func fromValue(v reflect.Value, active map[visit]bool) (Object, error) {
+	if v.Type().Implements(objectType) {
+		if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+			return NULL, nil
+		}
+		return v.Interface().(Object), nil
+	}
+
+	if v.Type() == timeType {
+		return &Time{Value: v.Interface().(time.Time)}, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return TRUE, nil
+		}
+		return FALSE, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: v.Int()}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows INTEGER", v.Uint())
+		}
+		return &Integer{Value: int64(v.Uint())}, nil
+
+	case reflect.String:
+		return &String{Value: v.String()}, nil
+
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return NULL, nil
+		}
+
+		if v.Kind() == reflect.Pointer {
+			seen, err := enterValue(active, v)
+			if err != nil {
+				return nil, err
+			}
+			defer delete(active, seen)
+		}
+		return fromValue(v.Elem(), active)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return NULL, nil
+			}
+
+			seen, err := enterValue(active, v)
+			if err != nil {
+				return nil, err
+			}
+			defer delete(active, seen)
+		}
+
+		arr := &Array{Value: make([]Object, v.Len())}
+		for idx := range arr.Value {
+			elem, err := fromValue(v.Index(idx), active)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", idx, err)
+			}
+			arr.Value[idx] = elem
+		}
+		return arr, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return NULL, nil
+		}
+
+		seen, err := enterValue(active, v)
+		if err != nil {
+			return nil, err
+		}
+		defer delete(active, seen)
+
+		hash := &Hash{Pairs: map[HashKey]HashPair{}}
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := fromValue(iter.Key(), active)
+			if err != nil {
+				return nil, fmt.Errorf("map key: %w", err)
+			}
+
+			hashable, ok := key.(Hashable)
+			if !ok {
+				return nil, fmt.Errorf("map key %s is not HashAble", key.Type())
+			}
+
+			val, err := fromValue(iter.Value(), active)
+			if err != nil {
+				return nil, fmt.Errorf("[%s]: %w", key.Inspect(), err)
+			}
+
+			hash.Pairs[hashable.HashKey()] = HashPair{Key: key, Val: val}
+		}
+		return hash, nil
+
+	case reflect.Struct:
+		hash := &Hash{Pairs: map[HashKey]HashPair{}}
+		for _, field := range structFields(v.Type()) {
+			val, err := fromValue(v.FieldByIndex(field.index), active)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", field.name, err)
+			}
+
+			key := &String{Value: field.name}
+			hash.Pairs[key.HashKey()] = HashPair{Key: key, Val: val}
+		}
+		return hash, nil
+
+	case reflect.Func:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return WrapFunc(v.Interface())
+
+	default:
+		return nil, fmt.Errorf("unsupported Go type %s", v.Type())
+	}
+}
+
+// Convert a Monkey object into the Go value pointed to by target.
+// Hashes convert to maps or structs, Arrays to slices or arrays, and NULL to the zero value.
+// An interface{} target receives int64, string, bool, []any, map[string]any (or map[any]any
+// when a key is not a String), time.Time, nil or the Object itself for functions.
+// Arrays and Hashes which contain themselves, as Go hosts can build, are reported as errors
+func ToGo(obj Object, target any) error {
+	if target == nil {
+		return errors.New("target must be a non-nil pointer, Got=nil")
+	}
+
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, Got=%s", ptr.Type())
+	}
+
+	return toValue(obj, ptr.Elem(), map[Object]bool{})
+}
+
+// Mark an Array or Hash as being converted, failing when it already is as it then contains itself.
+// The caller unmarks it once done, an object found twice but not within itself is converted twice
+func enter(active map[Object]bool, obj Object) error {
+	if active[obj] {
+		return fmt.Errorf("%s contains itself", obj.Type())
+	}
+
+	active[obj] = true
+	return nil
+}
+
+// Nil, or a nil pointer in an Object interface
+func isNilObject(obj Object) bool {
+	if obj == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(obj)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+// Convert obj into v, active holding the Arrays and Hashes which obj is inside of
+func toValue(obj Object, v reflect.Value, active map[Object]bool) error {
+	t := v.Type()
+
+	if isNilObject(obj) {
+		return fmt.Errorf("cannot convert nil Object to %s", t)
+	}
+
+	if t.Kind() == reflect.Interface && t != objectType && t.NumMethod() == 0 {
+		val, err := toInterface(obj, active)
+		if err != nil {
+			return err
+		}
+
+		if val == nil {
+			v.Set(reflect.Zero(t))
+		} else {
+			v.Set(reflect.ValueOf(val))
+		}
+		return nil
+	}
+
+	if reflect.TypeOf(obj).AssignableTo(t) {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+
+	if obj.Type() == NULL_OBJ {
+		v.Set(reflect.Zero(t))
+		return nil
+	}
+
+	if t.Kind() == reflect.Pointer {
+		elem := reflect.New(t.Elem())
+		if err := toValue(obj, elem.Elem(), active); err != nil {
+			return err
+		}
+
+		v.Set(elem)
+		return nil
+	}
+
+	switch o := obj.(type) {
+	case *Boolean:
+		if t.Kind() == reflect.Bool {
+			v.SetBool(o.Value)
+			return nil
+		}
+
+	case *String:
+		if t.Kind() == reflect.String {
+			v.SetString(o.Value)
+			return nil
+		}
+
+	case *Time:
+		if t == timeType {
+			v.Set(reflect.ValueOf(o.Value))
+			return nil
+		}
+
+	case *Integer:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.OverflowInt(o.Value) {
+				return fmt.Errorf("%d overflows %s", o.Value, t)
+			}
+			v.SetInt(o.Value)
+			return nil
+
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if o.Value < 0 || v.OverflowUint(uint64(o.Value)) {
+				return fmt.Errorf("%d overflows %s", o.Value, t)
+			}
+			v.SetUint(uint64(o.Value))
+			return nil
+		}
+
+	case *Array:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			if err := enter(active, o); err != nil {
+				return err
+			}
+			defer delete(active, o)
+		}
+
+		switch t.Kind() {
+		case reflect.Slice:
+			slice := reflect.MakeSlice(t, len(o.Value), len(o.Value))
+			for idx, elem := range o.Value {
+				if err := toValue(elem, slice.Index(idx), active); err != nil {
+					return fmt.Errorf("[%d]: %w", idx, err)
+				}
+			}
+			v.Set(slice)
+			return nil
+
+		case reflect.Array:
+			if len(o.Value) != t.Len() {
+				return fmt.Errorf("ARRAY of length %d does not fit %s", len(o.Value), t)
+			}
+			for idx, elem := range o.Value {
+				if err := toValue(elem, v.Index(idx), active); err != nil {
+					return fmt.Errorf("[%d]: %w", idx, err)
+				}
+			}
+			return nil
+		}
+
+	case *Hash:
+		if t.Kind() == reflect.Map || t.Kind() == reflect.Struct {
+			if err := enter(active, o); err != nil {
+				return err
+			}
+			defer delete(active, o)
+		}
+
+		switch t.Kind() {
+		case reflect.Map:
+			m := reflect.MakeMapWithSize(t, len(o.Pairs))
+			for _, pair := range o.Pairs {
+				key := reflect.New(t.Key()).Elem()
+				if err := toValue(pair.Key, key, active); err != nil {
+					return fmt.Errorf("key %s: %w", pair.Key.Inspect(), err)
+				}
+
+				val := reflect.New(t.Elem()).Elem()
+				if err := toValue(pair.Val, val, active); err != nil {
+					return fmt.Errorf("[%s]: %w", pair.Key.Inspect(), err)
+				}
+
+				m.SetMapIndex(key, val)
+			}
+			v.Set(m)
+			return nil
+
+		case reflect.Struct:
+			for _, field := range structFields(t) {
+				key := &String{Value: field.name}
+				pair, ok := o.Pairs[key.HashKey()]
+				if !ok {
+					continue
+				}
+
+				if err := toValue(pair.Val, v.FieldByIndex(field.index), active); err != nil {
+					return fmt.Errorf("%s: %w", field.name, err)
+				}
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot convert %s to %s", obj.Type(), t)
+}
+
+// Natural Go representation of an object for interface{} targets
+func toInterface(obj Object, active map[Object]bool) (any, error) {
+	if isNilObject(obj) {
+		return nil, errors.New("cannot convert nil Object")
+	}
+
+	switch o := obj.(type) {
+	case *Null:
+		return nil, nil
+	case *Boolean:
+		return o.Value, nil
+	case *Integer:
+		return o.Value, nil
+	case *String:
+		return o.Value, nil
+	case *Time:
+		return o.Value, nil
+
+	case *Array:
+		if err := enter(active, o); err != nil {
+			return nil, err
+		}
+		defer delete(active, o)
+
+		arr := make([]any, len(o.Value))
+		for idx, elem := range o.Value {
+			val, err := toInterface(elem, active)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", idx, err)
+			}
+			arr[idx] = val
+		}
+		return arr, nil
+
+	case *Hash:
+		if err := enter(active, o); err != nil {
+			return nil, err
+		}
+		defer delete(active, o)
+
+		stringKeys := true
+		for _, pair := range o.Pairs {
+			if pair.Key.Type() != STRING_OBJ {
+				stringKeys = false
+				break
+			}
+		}
+
+		if stringKeys {
+			m := make(map[string]any, len(o.Pairs))
+			for _, pair := range o.Pairs {
+				val, err := toInterface(pair.Val, active)
+				if err != nil {
+					return nil, fmt.Errorf("[%s]: %w", pair.Key.Inspect(), err)
+				}
+				m[pair.Key.(*String).Value] = val
+			}
+			return m, nil
+		}
+
+		m := make(map[any]any, len(o.Pairs))
+		for _, pair := range o.Pairs {
+			key, _ := toInterface(pair.Key, active)
+			val, err := toInterface(pair.Val, active)
+			if err != nil {
+				return nil, fmt.Errorf("[%s]: %w", pair.Key.Inspect(), err)
+			}
+			m[key] = val
+		}
+		return m, nil
+
+	case *Error:
+		return nil, errors.New(o.Message)
+
+	default:
+		// Functions, builtins and modules have no Go equivalent, hand the object itself to the host
+		return obj, nil
+	}
+}
+
+type structField struct {
+	name  string
+	index []int
+}
+
+// Exported fields of a struct with the names used for their Hash keys
+func structFields(t reflect.Type) []structField {
+	fields := []structField{}
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup(structTag); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		fields = append(fields, structField{name: name, index: field.Index})
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+	return fields
+}
+
+// Wrap a Go function as a Builtin.
+// Args are converted with ToGo into the function's parameter types, with the count checked against its signature.
+// The function may return nothing, a value, an error, or a value and an error. A returned error becomes an Error object
+func WrapFunc(fn any) (*Builtin, error) {
+	fnVal := reflect.ValueOf(fn)
+	fnType := fnVal.Type()
+	if fnType.Kind() != reflect.Func || fnVal.IsNil() {
+		return nil, fmt.Errorf("WrapFunc requires a non-nil function, Got=%T", fn)
+	}
+
+	numOut := fnType.NumOut()
+	errOut := numOut > 0 && fnType.Out(numOut-1) == errorType
+	if numOut > 2 || (numOut == 2 && !errOut) {
+		return nil, fmt.Errorf("WrapFunc requires a function returning at most a value and an error, Got=%s", fnType)
+	}
+
+	params := make([]Param, fnType.NumIn())
+	for idx := range params {
+		paramType := fnType.In(idx)
+		if fnType.IsVariadic() && idx == len(params)-1 {
+			paramType = paramType.Elem()
+		}
+
+		params[idx] = Param{Name: fmt.Sprintf("arg%d", idx), Type: objectTypeOf(paramType)}
+	}
+
+	builtin := &Builtin{
+		Params:   params,
+		Variadic: fnType.IsVariadic(),
+		Fn: func(args ...Object) Object {
+			numIn := fnType.NumIn()
+			if fnType.IsVariadic() {
+				if len(args) < numIn-1 {
+					return &Error{Message: fmt.Sprintf("Invalid number of args, Got=%d, expected at least %d", len(args), numIn-1)}
+				}
+			} else if len(args) != numIn {
+				return &Error{Message: fmt.Sprintf("Invalid number of args, Got=%d, expected=%d", len(args), numIn)}
+			}
+
+			in := make([]reflect.Value, len(args))
+			for idx, arg := range args {
+				paramType := fnType.In(min(idx, numIn-1))
+				if fnType.IsVariadic() && idx >= numIn-1 {
+					paramType = paramType.Elem()
+				}
+
+				param := reflect.New(paramType).Elem()
+				if err := toValue(arg, param, map[Object]bool{}); err != nil {
+					return &Error{Message: fmt.Sprintf("Invalid arg %d: %s", idx, err)}
+				}
+				in[idx] = param
+			}
+
+			out := fnVal.Call(in)
+			if errOut {
+				if err := out[numOut-1]; !err.IsNil() {
+					return &Error{Message: err.Interface().(error).Error()}
+				}
+				out = out[:numOut-1]
+			}
+
+			if len(out) == 0 {
+				return NULL
+			}
+
+			result, err := fromValue(out[0], map[visit]bool{})
+			if err != nil {
+				return &Error{Message: fmt.Sprintf("Invalid return value: %s", err)}
+			}
+
+			return result
+		},
+	}
+
+	return builtin, nil
+}
+
+// ObjectType which converts to the Go type, empty if several object types can
+func objectTypeOf(t reflect.Type) ObjectType {
+	if t == timeType {
+		return TIME_OBJ
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return BOOLEAN_OBJ
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return INTEGER_OBJ
+	case reflect.String:
+		return STRING_OBJ
+	case reflect.Slice, reflect.Array:
+		return ARRAY_OBJ
+	case reflect.Map, reflect.Struct:
+		return HASH_OBJ
+	case reflect.Pointer:
+		return objectTypeOf(t.Elem())
+	default:
+		return ""
+	}
+}
+
+// Like WrapFunc but panics if fn cannot be wrapped, for registering host functions in one line
+func MustWrapFunc(fn any) *Builtin {
+	builtin, err := WrapFunc(fn)
+	if err != nil {
+		panic(err)
+	}
+
+	return builtin
+}
@@ -0,0 +1,310 @@
+package object
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testAddress struct {
+	City string `monkey:"city"`
+	Zip  int    `monkey:"zip"`
+}
+
+type testUser struct {
+	Name     string       `monkey:"name"`
+	Age      uint8        `monkey:"age"`
+	Admin    bool         `monkey:"admin"`
+	Tags     []string     `monkey:"tags"`
+	Address  *testAddress `monkey:"address"`
+	Password string       `monkey:"-"`
+	Nickname string
+	internal int
+}
+
+func TestFromGo(t *testing.T) {
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{42, "42"},
+		{uint16(7), "7"},
+		{"hi", `"hi"`},
+		{[]int{1, 2}, `[1,2,]`},
+		{[2]bool{true, false}, `[true,false,]`},
+		{[]string(nil), "null"},
+		{(*testAddress)(nil), "null"},
+		{map[string]int{"a": 1}, `{"a": 1,}`},
+		{testAddress{City: "Paris", Zip: 75}, `{"city": "Paris","zip": 75,}`},
+		{&Integer{Value: 3}, "3"},
+		{time.Unix(0, 0).UTC(), `time("1970-01-01T00:00:00Z")`},
+	}
+
+	for _, tt := range tests {
+		obj, err := FromGo(tt.input)
+		if err != nil {
+			t.Errorf("FromGo(%#v) returned error: %s", tt.input, err)
+			continue
+		}
+
+		// Hashes are unordered, only compare single pair outputs exactly
+		if hash, ok := obj.(*Hash); ok && len(hash.Pairs) > 1 {
+			continue
+		}
+
+		if obj.Inspect() != tt.expected {
+			t.Errorf("FromGo(%#v) wrong result. Got=%s, expected=%s", tt.input, obj.Inspect(), tt.expected)
+		}
+	}
+
+	if _, err := FromGo(uint64(1 << 63)); err == nil {
+		t.Errorf("FromGo did not report uint64 overflow")
+	}
+
+	if _, err := FromGo(make(chan int)); err == nil {
+		t.Errorf("FromGo did not reject channel")
+	}
+}
+
+func TestFromGoNullIdentity(t *testing.T) {
+	obj, _ := FromGo(nil)
+	if obj != NULL {
+		t.Errorf("FromGo(nil) is not the NULL instance")
+	}
+
+	obj, _ = FromGo(false)
+	if obj != FALSE {
+		t.Errorf("FromGo(false) is not the FALSE instance")
+	}
+}
+
+func TestRoundTripStruct(t *testing.T) {
+	user := testUser{
+		Name:     "ada",
+		Age:      36,
+		Admin:    true,
+		Tags:     []string{"a", "b"},
+		Address:  &testAddress{City: "London", Zip: 1},
+		Password: "secret",
+		Nickname: "countess",
+		internal: 5,
+	}
+
+	obj, err := FromGo(user)
+	if err != nil {
+		t.Fatalf("FromGo returned error: %s", err)
+	}
+
+	hash := obj.(*Hash)
+	if _, ok := hash.Pairs[(&String{Value: "Password"}).HashKey()]; ok {
+		t.Errorf("Skipped field was converted")
+	}
+	if _, ok := hash.Pairs[(&String{Value: "Nickname"}).HashKey()]; !ok {
+		t.Errorf("Untagged field was not converted by name")
+	}
+
+	var back testUser
+	if err := ToGo(obj, &back); err != nil {
+		t.Fatalf("ToGo returned error: %s", err)
+	}
+
+	user.Password = ""
+	user.internal = 0
+	if !reflect.DeepEqual(user, back) {
+		t.Errorf("Round trip mismatch. Got=%+v, expected=%+v", back, user)
+	}
+}
+
+func TestToGo(t *testing.T) {
+	arr := &Array{Value: []Object{&Integer{Value: 1}, &String{Value: "x"}, NULL}}
+
+	var anything any
+	if err := ToGo(arr, &anything); err != nil {
+		t.Fatalf("ToGo returned error: %s", err)
+	}
+
+	if !reflect.DeepEqual(anything, []any{int64(1), "x", nil}) {
+		t.Errorf("Wrong interface conversion. Got=%#v", anything)
+	}
+
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+	key := &String{Value: "k"}
+	hash.Pairs[key.HashKey()] = HashPair{Key: key, Val: TRUE}
+
+	var m map[string]bool
+	if err := ToGo(hash, &m); err != nil || !m["k"] {
+		t.Errorf("Wrong map conversion. Got=%#v, err=%v", m, err)
+	}
+
+	var small int8
+	if err := ToGo(&Integer{Value: 300}, &small); err == nil {
+		t.Errorf("ToGo did not report int8 overflow")
+	}
+
+	var unsigned uint
+	if err := ToGo(&Integer{Value: -1}, &unsigned); err == nil {
+		t.Errorf("ToGo did not report negative uint")
+	}
+
+	var str string
+	if err := ToGo(&Integer{Value: 1}, &str); err == nil {
+		t.Errorf("ToGo did not report type mismatch")
+	}
+
+	if err := ToGo(TRUE, str); err == nil {
+		t.Errorf("ToGo did not reject non pointer target")
+	}
+
+	var obj Object
+	if err := ToGo(arr, &obj); err != nil || obj != arr {
+		t.Errorf("ToGo into Object did not pass the object through. Got=%#v, err=%v", obj, err)
+	}
+}
+
+func TestToGoErrors(t *testing.T) {
+	loop := &Array{}
+	loop.Value = []Object{&Integer{Value: 1}, loop}
+
+	loopHash := &Hash{Pairs: map[HashKey]HashPair{}}
+	key := &String{Value: "self"}
+	loopHash.Pairs[key.HashKey()] = HashPair{Key: key, Val: &Array{Value: []Object{loopHash}}}
+
+	var nilArray *Array
+	var anything any
+	var ints []int
+	var nested []any
+	var m map[string]any
+	var n int
+
+	tests := []struct {
+		obj      Object
+		target   any
+		expected string
+	}{
+		{TRUE, nil, "target must be a non-nil pointer, Got=nil"},
+		{TRUE, (*bool)(nil), "target must be a non-nil pointer, Got=*bool"},
+		{TRUE, n, "target must be a non-nil pointer, Got=int"},
+		{nil, &n, "cannot convert nil Object to int"},
+		{nilArray, &ints, "cannot convert nil Object to []int"},
+		{&Array{Value: []Object{nil}}, &anything, "[0]: cannot convert nil Object"},
+		{loop, &anything, "[1]: ARRAY contains itself"},
+		{loop, &nested, "[1]: ARRAY contains itself"},
+		{loopHash, &m, `["self"]: [0]: HASH contains itself`},
+	}
+
+	for _, tt := range tests {
+		err := ToGo(tt.obj, tt.target)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("Wrong error. Got=%v, expected=%q", err, tt.expected)
+		}
+	}
+
+	// An object found twice, but not inside itself, is no cycle
+	shared := &Array{Value: []Object{&Integer{Value: 2}}}
+	twice := &Array{Value: []Object{shared, shared}}
+	if err := ToGo(twice, &anything); err != nil || !reflect.DeepEqual(anything, []any{[]any{int64(2)}, []any{int64(2)}}) {
+		t.Errorf("Wrong conversion of a shared Array. Got=%#v, err=%v", anything, err)
+	}
+}
+
+type linked struct {
+	Next *linked
+	N    int
+}
+
+func TestFromGoCycles(t *testing.T) {
+	self := &linked{N: 1}
+	self.Next = self
+
+	m := map[string]any{}
+	m["self"] = m
+
+	s := []any{1, nil}
+	s[1] = s
+
+	tests := []struct {
+		val      any
+		expected string
+	}{
+		{self, "Next: *object.linked contains itself"},
+		{&linked{Next: self}, "Next: Next: *object.linked contains itself"},
+		{m, `["self"]: map[string]interface {} contains itself`},
+		{s, "[1]: []interface {} contains itself"},
+	}
+
+	for _, tt := range tests {
+		_, err := FromGo(tt.val)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("Wrong error for %T. Got=%v, expected=%q", tt.val, err, tt.expected)
+		}
+	}
+
+	// Values found twice, but not inside themselves, are no cycle
+	shared := &linked{N: 2}
+	pair := []*linked{shared, shared}
+	whole := []int{1, 2, 3}
+	obj, err := FromGo([]any{pair, whole, whole[:2]})
+	if err != nil {
+		t.Fatalf("Shared values reported as a cycle: %s", err)
+	}
+
+	var back []any
+	if err := ToGo(obj, &back); err != nil {
+		t.Fatal(err)
+	}
+	node := map[string]any{"Next": nil, "N": int64(2)}
+	expected := []any{[]any{node, node}, []any{int64(1), int64(2), int64(3)}, []any{int64(1), int64(2)}}
+	if !reflect.DeepEqual(back, expected) {
+		t.Errorf("Wrong conversion of shared values. Got=%#v", back)
+	}
+}
+
+func TestWrapFunc(t *testing.T) {
+	add := MustWrapFunc(func(a, b int) int { return a + b })
+	if res := add.Fn(&Integer{Value: 2}, &Integer{Value: 3}); res.Inspect() != "5" {
+		t.Errorf("Wrong result. Got=%s", res.Inspect())
+	}
+
+	if res := add.Fn(&Integer{Value: 2}); res.Inspect() != "ERROR: Invalid number of args, Got=1, expected=2" {
+		t.Errorf("Wrong arity error. Got=%s", res.Inspect())
+	}
+
+	if res := add.Fn(&Integer{Value: 2}, &String{Value: "x"}); res.Inspect() != "ERROR: Invalid arg 1: cannot convert STRING to int" {
+		t.Errorf("Wrong type error. Got=%s", res.Inspect())
+	}
+
+	join := MustWrapFunc(func(sep string, parts ...string) string {
+		out := ""
+		for idx, part := range parts {
+			if idx > 0 {
+				out += sep
+			}
+			out += part
+		}
+		return out
+	})
+	if res := join.Fn(&String{Value: "-"}, &String{Value: "a"}, &String{Value: "b"}); res.Inspect() != `"a-b"` {
+		t.Errorf("Wrong variadic result. Got=%s", res.Inspect())
+	}
+
+	fail := MustWrapFunc(func() (int, error) { return 0, errors.New("service unavailable") })
+	if res := fail.Fn(); res.Inspect() != "ERROR: service unavailable" {
+		t.Errorf("Error was not converted. Got=%s", res.Inspect())
+	}
+
+	noop := MustWrapFunc(func() {})
+	if res := noop.Fn(); res != NULL {
+		t.Errorf("Function without results did not return NULL. Got=%s", res.Inspect())
+	}
+
+	if _, err := WrapFunc(42); err == nil {
+		t.Errorf("WrapFunc accepted a non function")
+	}
+
+	if _, err := WrapFunc(func() (int, int) { return 0, 0 }); err == nil {
+		t.Errorf("WrapFunc accepted two non error results")
+	}
+}
@@ -4,10 +4,15 @@ package object
 
 // Environment used to map identifiers to values
 //
-// Allows to reference outer Environments creating closures and variable scoping
+// Allows to reference outer Environments creating closures and variable scoping.
+// The global scope maps names to values, function calls and catch blocks use frames
+// of slots addressed by the resolver
 type Environment struct {
 	store map[string]Object
+	slots []Object
+	names []string // Variable name of each slot
 	outer *Environment
+	state any // Evaluation state shared with every enclosed Environment, owned by the evaluator
 }
 
 // Create a new empty env
@@ -23,11 +28,26 @@ func NewEnclosingEnvironment(outer *Environment) *Environment {
 	return &Environment{
 		outer: outer,
 		store: map[string]Object{},
+		state: outer.state,
+	}
+}
+
+// Create a frame with a slot for each of the names, wrapping the enclosing scope
+func NewFrame(outer *Environment, names []string) *Environment {
+	return &Environment{
+		outer: outer,
+		slots: make([]Object, len(names)),
+		names: names,
+		state: outer.state,
 	}
 }
 
 // Bind an identifier to the given value
 func (e *Environment) Set(key string, val Object) {
+	if e.store == nil {
+		e.store = map[string]Object{}
+	}
+
 	e.store[key] = val
 }
 
@@ -41,3 +61,64 @@ func (e *Environment) Get(key string) Object {
 
 	return val
 }
+
+// Get the value in the slot of the frame depth levels out, nil if it has not been bound yet
+func (e *Environment) Local(depth, slot int) Object {
+	frame := e
+	for ; depth > 0; depth-- {
+		frame = frame.outer
+	}
+
+	return frame.slots[slot]
+}
+
+// Bind the slot of the frame depth levels out to the given value
+func (e *Environment) SetLocal(depth, slot int, val Object) {
+	frame := e
+	for ; depth > 0; depth-- {
+		frame = frame.outer
+	}
+
+	frame.slots[slot] = val
+}
+
+// Names and values of the bound slots of the frame, nil for the global scope
+func (e *Environment) Locals() map[string]Object {
+	if e.slots == nil {
+		return nil
+	}
+
+	locals := map[string]Object{}
+	for slot, val := range e.slots {
+		if val != nil {
+			locals[e.names[slot]] = val
+		}
+	}
+
+	return locals
+}
+
+// Names and values set in the store of e rather than in slots, the variables of a global scope
+func (e *Environment) Bindings() map[string]Object {
+	bindings := map[string]Object{}
+	for name, val := range e.store {
+		bindings[name] = val
+	}
+
+	return bindings
+}
+
+// Enclosing Environment, nil for the outermost
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Evaluation state attached to the Environment
+func (e *Environment) State() any {
+	return e.state
+}
+
+// Attach evaluation state, inherited by Environments enclosing e which are created afterwards
+func (e *Environment) SetState(state any) {
+	e.state = state
+}
@@ -2,14 +2,20 @@ package object
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"hash/fnv"
 	"monkey/ast"
+	"monkey/token"
+	"regexp"
+	"strings"
+	"time"
 )
 
 type (
-	BuiltinFn  func(args ...Object) Object
-	ObjectType string
+	BuiltinFn        func(args ...Object) Object
+	BuiltinContextFn func(ctx context.Context, args ...Object) Object
+	ObjectType       string
 )
 
 type Hashable interface {
@@ -32,17 +38,72 @@ const (
 	BUILTIN_OBJ  = "BUILTIN"
 	ARRAY_OBJ    = "ARRAY"
 	HASH_OBJ     = "HASH"
+	MODULE_OBJ   = "MODULE"
+	REGEX_OBJ    = "REGEX"
+	TIME_OBJ     = "TIME"
+)
+
+// Single instance values, the evaluator compares them by identity
+var (
+	NULL  = &Null{}
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
 )
 
 /*** BuiltIn Object ***/
 
+// Parameter metadata of a Builtin, used for help and completion
+type Param struct {
+	Name string
+	Type ObjectType // Empty if any type is accepted
+}
+
 type Builtin struct {
-	Fn BuiltinFn
+	Name     string // Qualified name the builtin is registered under, e.g. math.abs
+	Params   []Param
+	Variadic bool   // Last param accepts any number of args
+	Optional int    // Number of trailing params which may be omitted
+	Doc      string // One line description
+	Fn       BuiltinFn
+
+	// Called by the evaluator instead of Fn when set, with the context of the evaluation
+	// so a builtin which blocks can be interrupted. Fn is still used by other callers
+	ContextFn BuiltinContextFn
 }
 
-func (b *Builtin) Inspect() string  { return "bb" }
+func (b *Builtin) Inspect() string  { return fmt.Sprintf("<builtin %s>", b.Signature()) }
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 
+// Call signature of the builtin, e.g. math.clamp(x: INTEGER, low: INTEGER, high: INTEGER)
+func (b *Builtin) Signature() string {
+	var out bytes.Buffer
+
+	out.WriteString(b.Name)
+	out.WriteString("(")
+	for idx, param := range b.Params {
+		if idx > 0 {
+			out.WriteString(", ")
+		}
+
+		if idx >= len(b.Params)-b.Optional {
+			out.WriteString("?")
+		}
+
+		if b.Variadic && idx == len(b.Params)-1 {
+			out.WriteString("...")
+		}
+
+		out.WriteString(param.Name)
+		if param.Type != "" {
+			out.WriteString(": ")
+			out.WriteString(string(param.Type))
+		}
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
 /*** Null Object ***/
 
 type Null struct{}
@@ -121,17 +182,70 @@ func (r *Return) Type() ObjectType { return RETURN_OBJ }
 /*** Error Object ***/
 
 type Error struct {
-	Message string
+	Message  string
+	Position token.Position // Where the error was raised, invalid if unknown
+	Stack    []Frame        // Calls the error propagated out of, innermost first
+	Value    Object         // Value given to throw, nil for errors raised by the interpreter or builtins
+	Internal bool           // Fault of the interpreter rather than the program, these cannot be caught
 }
 
-func (err *Error) Inspect() string  { return fmt.Sprintf("ERROR: %s", err.Message) }
+// Call of a function which an Error propagated out of
+type Frame struct {
+	Function string         // Name the function was bound to, empty for anonymous functions
+	Position token.Position // Position of the call expression, invalid for a function called by a builtin
+}
+
+// Frames printed at each end of a Trace, deeper stacks (runaway recursion) are elided in the middle
+const traceFrames = 10
+
 func (err *Error) Type() ObjectType { return ERROR_OBJ }
+func (err *Error) Inspect() string {
+	if err.Internal {
+		return fmt.Sprintf("INTERNAL ERROR: %s", err.Message)
+	}
+
+	return fmt.Sprintf("ERROR: %s", err.Message)
+}
+
+// Message followed by the position of the error and the call stack, one frame per line
+func (err *Error) Trace() string {
+	var out strings.Builder
+	out.WriteString(err.Inspect())
+
+	if err.Position.IsValid() {
+		fmt.Fprintf(&out, "\n    at %s", err.Position)
+	}
+
+	for idx, frame := range err.Stack {
+		if len(err.Stack) > 2*traceFrames && idx == traceFrames {
+			fmt.Fprintf(&out, "\n    ... %d more frames", len(err.Stack)-2*traceFrames)
+		}
+		if len(err.Stack) > 2*traceFrames && idx >= traceFrames && idx < len(err.Stack)-traceFrames {
+			continue
+		}
+
+		name := frame.Function
+		if name == "" {
+			name = "<anonymous>"
+		}
+
+		if frame.Position.IsValid() {
+			fmt.Fprintf(&out, "\n    in %s, called at %s", name, frame.Position)
+		} else {
+			fmt.Fprintf(&out, "\n    in %s, called by a builtin", name)
+		}
+	}
+
+	return out.String()
+}
 
 /*** Function Object ***/
 
 type Function struct {
+	Name       string // Name the function was first bound to with let, empty for anonymous functions
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
+	Locals     []string     // Names of the slots of the function's frame
 	Env        *Environment //Env which the function was declared
 }
 
@@ -140,11 +254,14 @@ func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
 	out.WriteString("fn(")
-	for _, param := range f.Parameters {
+	for idx, param := range f.Parameters {
+		if idx > 0 {
+			out.WriteString(", ")
+		}
 		out.WriteString(param.String())
 	}
 
-	out.WriteString(")")
+	out.WriteString(") ")
 	out.WriteString(f.Body.String())
 
 	return out.String()
@@ -200,3 +317,34 @@ func (h *Hash) Inspect() string {
 
 	return out.String()
 }
+
+/*** Module Object ***/
+
+// Named collection of builtins, members are accessed with module.member
+type Module struct {
+	Name    string
+	Members map[string]Object
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return fmt.Sprintf("<module %s>", m.Name) }
+
+/*** Regex Object ***/
+
+// Compiled regular expression, allowing a pattern to be reused without recompiling
+type Regex struct {
+	Value *regexp.Regexp
+}
+
+func (r *Regex) Type() ObjectType { return REGEX_OBJ }
+func (r *Regex) Inspect() string  { return fmt.Sprintf("regex(%q)", r.Value.String()) }
+
+/*** Time Object ***/
+
+// Instant in time, durations are represented as Integer milliseconds
+type Time struct {
+	Value time.Time
+}
+
+func (t *Time) Type() ObjectType { return TIME_OBJ }
+func (t *Time) Inspect() string  { return fmt.Sprintf("time(%q)", t.Value.Format(time.RFC3339Nano)) }
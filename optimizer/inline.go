@@ -0,0 +1,372 @@
+package optimizer
+
+import "monkey/ast"
+
+// Find the top level lets of functions which may be inlined.
+// The name must be bound nowhere else in the program, so every call of the name refers to the function,
+// and the body must be a single expression which only refers to the parameters and to names which cannot
+// be shadowed at a call site: globals bound once at the top level, or builtins
+func findCandidates(program *ast.Program) map[*ast.LetStatement]bool {
+	bindings := map[string]int{}
+	countBindings(program, bindings)
+
+	topLevel := map[string]bool{}
+	for _, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok {
+			topLevel[let.Name.Value] = true
+		}
+	}
+
+	candidates := map[*ast.LetStatement]bool{}
+	for _, stmt := range program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || bindings[let.Name.Value] != 1 {
+			continue
+		}
+
+		fn, ok := let.Value.(*ast.FnLiteral)
+		if !ok {
+			continue
+		}
+
+		params := map[string]bool{}
+		for _, param := range fn.Parameters {
+			params[param.Value] = true
+		}
+
+		safe := len(params) == len(fn.Parameters)
+		for name := range freeNames(fn) {
+			// References to its own name make the function recursive
+			if name == let.Name.Value || bindings[name] > 1 || (bindings[name] == 1 && !topLevel[name]) {
+				safe = false
+			}
+		}
+
+		if safe {
+			candidates[let] = true
+		}
+	}
+
+	return candidates
+}
+
+// Prepare an (optimized) function for inlining, nil if its body is not small enough
+func newInlinable(fn *ast.FnLiteral) *inlinable {
+	if len(fn.Body.Statements) != 1 {
+		return nil
+	}
+
+	exprStmt, ok := fn.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		return nil
+	}
+
+	// An inlined call would skip the runtime checks of annotated parameters
+	for _, param := range fn.Parameters {
+		if param.Type != nil {
+			return nil
+		}
+	}
+
+	inl := &inlinable{params: fn.Parameters, body: exprStmt.Expr, uses: map[string]int{}}
+	for _, param := range fn.Parameters {
+		inl.uses[param.Value] = 0
+	}
+
+	size, ok := inlineSize(inl.body, inl.uses)
+	if !ok || size > maxInlineNodes {
+		return nil
+	}
+
+	return inl
+}
+
+// Replace a call of an available function with a copy of its body, the parameters substituted by the args.
+// Args must be literals, or identifiers of parameters which are used, so evaluating them any number of times
+// (including not at all) behaves the same as evaluating them once before the call
+func (o *optimizer) inline(call *ast.CallExpression) ast.Expression {
+	ident, ok := call.Fn.(*ast.Identifier)
+	if !ok {
+		return call
+	}
+
+	inl, ok := o.available[ident.Value]
+	if !ok || o.inlining[ident.Value] || len(call.Args) != len(inl.params) {
+		return call
+	}
+
+	subst := map[string]ast.Expression{}
+	for idx, arg := range call.Args {
+		param := inl.params[idx].Value
+
+		switch arg.(type) {
+		case *ast.IntLiteral, *ast.StringLiteral, *ast.BoolLiteral:
+		case *ast.Identifier:
+			if inl.uses[param] == 0 {
+				return call
+			}
+		default:
+			return call
+		}
+
+		subst[param] = arg
+	}
+
+	o.inlining[ident.Value] = true
+	defer delete(o.inlining, ident.Value)
+
+	return o.expression(clone(inl.body, subst))
+}
+
+// Count the bindings of each name by lets, parameters and catch clauses anywhere in node
+func countBindings(node ast.Node, bindings map[string]int) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			bindings[node.Name.Value]++
+
+		case *ast.TryExpression:
+			if node.Catch != nil {
+				bindings[node.Param.Value]++
+			}
+
+		case *ast.FnLiteral:
+			for _, param := range node.Parameters {
+				bindings[param.Value]++
+			}
+		}
+
+		return true
+	})
+}
+
+// Names referenced in the body of fn other than its parameters
+func freeNames(fn *ast.FnLiteral) map[string]bool {
+	params := map[string]int{}
+	for _, param := range fn.Parameters {
+		params[param.Value] = 0
+	}
+
+	free := map[string]bool{}
+	for _, stmt := range fn.Body.Statements {
+		exprStmt, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			continue
+		}
+
+		collectNames(exprStmt.Expr, func(name string) {
+			if _, ok := params[name]; !ok {
+				free[name] = true
+			}
+		})
+	}
+
+	return free
+}
+
+// Call found with every identifier referenced in expr
+func collectNames(astExpr ast.Expression, found func(name string)) {
+	switch expr := astExpr.(type) {
+	case *ast.Identifier:
+		found(expr.Value)
+
+	case *ast.ArrayLiteral:
+		for _, elem := range expr.Elements {
+			collectNames(elem, found)
+		}
+
+	case *ast.HashLiteral:
+		for key, val := range expr.Pairs {
+			collectNames(key, found)
+			collectNames(val, found)
+		}
+
+	case *ast.IndexExpression:
+		collectNames(expr.Left, found)
+		collectNames(expr.Index, found)
+
+	case *ast.PrefixExpression:
+		collectNames(expr.Operand, found)
+
+	case *ast.InfixExpression:
+		collectNames(expr.Left, found)
+		collectNames(expr.Right, found)
+
+	case *ast.IfExpression:
+		collectNames(expr.Condition, found)
+		for _, block := range []*ast.BlockStatement{expr.Consequence, expr.Alternative} {
+			if block == nil {
+				continue
+			}
+
+			for _, stmt := range block.Statements {
+				if exprStmt, ok := stmt.(*ast.ExpressionStatement); ok {
+					collectNames(exprStmt.Expr, found)
+				}
+			}
+		}
+
+	case *ast.CallExpression:
+		collectNames(expr.Fn, found)
+		for _, arg := range expr.Args {
+			collectNames(arg, found)
+		}
+	}
+}
+
+// Number of nodes in an expression which can be copied to a call site, counting the uses of each parameter.
+// Function literals, try and statements other than expressions introduce scopes or bindings and cannot be inlined
+func inlineSize(astExpr ast.Expression, uses map[string]int) (int, bool) {
+	size := 1
+	add := func(exprs ...ast.Expression) bool {
+		for _, expr := range exprs {
+			n, ok := inlineSize(expr, uses)
+			if !ok {
+				return false
+			}
+			size += n
+		}
+
+		return true
+	}
+
+	switch expr := astExpr.(type) {
+	case *ast.IntLiteral, *ast.StringLiteral, *ast.BoolLiteral:
+		return size, true
+
+	case *ast.Identifier:
+		if _, ok := uses[expr.Value]; ok {
+			uses[expr.Value]++
+		}
+		return size, true
+
+	case *ast.ArrayLiteral:
+		ok := add(expr.Elements...)
+		return size, ok
+
+	case *ast.HashLiteral:
+		for key, val := range expr.Pairs {
+			if !add(key, val) {
+				return 0, false
+			}
+		}
+		return size, true
+
+	case *ast.IndexExpression:
+		ok := add(expr.Left, expr.Index)
+		return size, ok
+
+	case *ast.PrefixExpression:
+		ok := add(expr.Operand)
+		return size, ok
+
+	case *ast.InfixExpression:
+		ok := add(expr.Left, expr.Right)
+		return size, ok
+
+	case *ast.IfExpression:
+		if !add(expr.Condition) {
+			return 0, false
+		}
+
+		for _, block := range []*ast.BlockStatement{expr.Consequence, expr.Alternative} {
+			if block == nil {
+				continue
+			}
+
+			for _, stmt := range block.Statements {
+				exprStmt, ok := stmt.(*ast.ExpressionStatement)
+				if !ok || !add(exprStmt.Expr) {
+					return 0, false
+				}
+			}
+		}
+		return size, true
+
+	case *ast.CallExpression:
+		ok := add(expr.Fn) && add(expr.Args...)
+		return size, ok
+
+	default:
+		return 0, false
+	}
+}
+
+// Deep copy of an expression accepted by inlineSize, with identifiers in subst replaced by copies of their values.
+// Every call site needs its own nodes as the resolver records addresses in them
+func clone(astExpr ast.Expression, subst map[string]ast.Expression) ast.Expression {
+	switch expr := astExpr.(type) {
+	case *ast.IntLiteral:
+		copied := *expr
+		return &copied
+
+	case *ast.StringLiteral:
+		copied := *expr
+		return &copied
+
+	case *ast.BoolLiteral:
+		copied := *expr
+		return &copied
+
+	case *ast.Identifier:
+		if val, ok := subst[expr.Value]; ok {
+			return clone(val, nil)
+		}
+		return &ast.Identifier{Token: expr.Token, Value: expr.Value}
+
+	case *ast.ArrayLiteral:
+		elems := make([]ast.Expression, len(expr.Elements))
+		for idx, elem := range expr.Elements {
+			elems[idx] = clone(elem, subst)
+		}
+		return &ast.ArrayLiteral{Token: expr.Token, Elements: elems}
+
+	case *ast.HashLiteral:
+		pairs := make(map[ast.Expression]ast.Expression, len(expr.Pairs))
+		for key, val := range expr.Pairs {
+			pairs[clone(key, subst)] = clone(val, subst)
+		}
+		return &ast.HashLiteral{Token: expr.Token, Pairs: pairs}
+
+	case *ast.IndexExpression:
+		return &ast.IndexExpression{Token: expr.Token, Left: clone(expr.Left, subst), Index: clone(expr.Index, subst)}
+
+	case *ast.PrefixExpression:
+		return &ast.PrefixExpression{Token: expr.Token, Operator: expr.Operator, Operand: clone(expr.Operand, subst)}
+
+	case *ast.InfixExpression:
+		return &ast.InfixExpression{Token: expr.Token, Operator: expr.Operator, Left: clone(expr.Left, subst), Right: clone(expr.Right, subst)}
+
+	case *ast.IfExpression:
+		return &ast.IfExpression{
+			Token:       expr.Token,
+			Condition:   clone(expr.Condition, subst),
+			Consequence: cloneBlock(expr.Consequence, subst),
+			Alternative: cloneBlock(expr.Alternative, subst),
+		}
+
+	case *ast.CallExpression:
+		args := make([]ast.Expression, len(expr.Args))
+		for idx, arg := range expr.Args {
+			args[idx] = clone(arg, subst)
+		}
+		return &ast.CallExpression{Token: expr.Token, Fn: clone(expr.Fn, subst), Args: args}
+
+	default:
+		return astExpr
+	}
+}
+
+func cloneBlock(block *ast.BlockStatement, subst map[string]ast.Expression) *ast.BlockStatement {
+	if block == nil {
+		return nil
+	}
+
+	stmts := make([]ast.Statement, len(block.Statements))
+	for idx, stmt := range block.Statements {
+		exprStmt := stmt.(*ast.ExpressionStatement)
+		stmts[idx] = &ast.ExpressionStatement{Token: exprStmt.Token, Expr: clone(exprStmt.Expr, subst)}
+	}
+
+	return &ast.BlockStatement{Token: block.Token, Statements: stmts}
+}
@@ -0,0 +1,344 @@
+// Package optimizer rewrites parsed programs into cheaper equivalents before evaluation.
+// Rewrites never change the value a program produces or the errors it raises
+package optimizer
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/token"
+)
+
+// Largest function body, in nodes, which is inlined at its call sites
+const maxInlineNodes = 24
+
+// Function bound by a top level let which calls may be replaced with its body
+type inlinable struct {
+	params []*ast.Identifier
+	body   ast.Expression
+	uses   map[string]int // Number of references to each parameter in body
+}
+
+type optimizer struct {
+	candidates map[*ast.LetStatement]bool
+	available  map[string]*inlinable // Candidates whose let has been passed, so calls from here on see the function
+	inlining   map[string]bool       // Functions currently being inlined, guarding against mutual recursion
+}
+
+// Rewrite program in place, returning it. Undefined names in the branches it removes go unreported,
+// so programs are checked with the resolver before they are optimized. The rewrites are:
+//   - Constant folding of integer, string and boolean prefix and infix expressions
+//   - Removal of if branches whose condition is a literal, unless the removed branch holds a let
+//   - Inlining of small non-recursive functions bound by a let at the top level
+func Optimize(program *ast.Program) *ast.Program {
+	o := &optimizer{
+		candidates: findCandidates(program),
+		available:  map[string]*inlinable{},
+		inlining:   map[string]bool{},
+	}
+
+	program.Statements = o.statements(program.Statements)
+	return program
+}
+
+// Optimize a list of statements, splicing in the statements of the taken branch of literal if expressions
+func (o *optimizer) statements(stmts []ast.Statement) []ast.Statement {
+	optimized := make([]ast.Statement, 0, len(stmts))
+
+	for idx, stmt := range stmts {
+		last := idx == len(stmts)-1
+
+		if exprStmt, ok := stmt.(*ast.ExpressionStatement); ok {
+			if ifExpr, ok := exprStmt.Expr.(*ast.IfExpression); ok {
+				ifExpr.Condition = o.expression(ifExpr.Condition)
+
+				if truthy, ok := literalTruth(ifExpr.Condition); ok && !declaresLets(dropped(ifExpr, truthy)) {
+					taken := ifExpr.Alternative
+					if truthy {
+						taken = ifExpr.Consequence
+					}
+
+					// Blocks share the scope they are in, so their statements can replace the if.
+					// An empty block as the last statement evaluates to nothing, unlike the statement before it
+					switch {
+					case taken != nil && len(taken.Statements) > 0:
+						optimized = append(optimized, o.statements(taken.Statements)...)
+						continue
+					case !last:
+						continue
+					}
+				}
+			}
+		}
+
+		optimized = append(optimized, o.statement(stmt))
+	}
+
+	return optimized
+}
+
+func (o *optimizer) statement(astStmt ast.Statement) ast.Statement {
+	switch stmt := astStmt.(type) {
+	case *ast.LetStatement:
+		stmt.Value = o.expression(stmt.Value)
+
+		if o.candidates[stmt] {
+			if fn, ok := stmt.Value.(*ast.FnLiteral); ok {
+				if inl := newInlinable(fn); inl != nil {
+					o.available[stmt.Name.Value] = inl
+				}
+			}
+		}
+
+	case *ast.ReturnStatement:
+		stmt.Value = o.expression(stmt.Value)
+
+	case *ast.ThrowStatement:
+		stmt.Value = o.expression(stmt.Value)
+
+	case *ast.ExpressionStatement:
+		stmt.Expr = o.expression(stmt.Expr)
+
+	case *ast.BlockStatement:
+		stmt.Statements = o.statements(stmt.Statements)
+	}
+
+	return astStmt
+}
+
+func (o *optimizer) block(block *ast.BlockStatement) *ast.BlockStatement {
+	if block != nil {
+		block.Statements = o.statements(block.Statements)
+	}
+
+	return block
+}
+
+func (o *optimizer) expression(astExpr ast.Expression) ast.Expression {
+	switch expr := astExpr.(type) {
+	case *ast.ArrayLiteral:
+		for idx, elem := range expr.Elements {
+			expr.Elements[idx] = o.expression(elem)
+		}
+
+	case *ast.HashLiteral:
+		pairs := make(map[ast.Expression]ast.Expression, len(expr.Pairs))
+		for key, val := range expr.Pairs {
+			pairs[o.expression(key)] = o.expression(val)
+		}
+		expr.Pairs = pairs
+
+	case *ast.IndexExpression:
+		expr.Left = o.expression(expr.Left)
+		expr.Index = o.expression(expr.Index)
+
+	case *ast.PrefixExpression:
+		expr.Operand = o.expression(expr.Operand)
+		return foldPrefix(expr)
+
+	case *ast.InfixExpression:
+		expr.Left = o.expression(expr.Left)
+		expr.Right = o.expression(expr.Right)
+		return foldInfix(expr)
+
+	case *ast.IfExpression:
+		expr.Condition = o.expression(expr.Condition)
+		expr.Consequence = o.block(expr.Consequence)
+		expr.Alternative = o.block(expr.Alternative)
+		return pruneIf(expr)
+
+	case *ast.TryExpression:
+		expr.Block = o.block(expr.Block)
+		expr.Catch = o.block(expr.Catch)
+		expr.Finally = o.block(expr.Finally)
+
+	case *ast.FnLiteral:
+		expr.Body = o.block(expr.Body)
+
+	case *ast.CallExpression:
+		expr.Fn = o.expression(expr.Fn)
+		for idx, arg := range expr.Args {
+			expr.Args[idx] = o.expression(arg)
+		}
+		return o.inline(expr)
+	}
+
+	return astExpr
+}
+
+/*** Constant Folding ***/
+
+// Fold a prefix expression of a literal, following the evaluator's rules for ! and -
+func foldPrefix(prefix *ast.PrefixExpression) ast.Expression {
+	switch prefix.Operator {
+	case "!":
+		switch operand := prefix.Operand.(type) {
+		case *ast.BoolLiteral:
+			return boolLiteral(!operand.Value, prefix.Token)
+		case *ast.IntLiteral, *ast.StringLiteral:
+			// Every value other than false and null is truthy
+			return boolLiteral(false, prefix.Token)
+		}
+
+	case "-":
+		if operand, ok := prefix.Operand.(*ast.IntLiteral); ok {
+			return intLiteral(-operand.Value, prefix.Token)
+		}
+	}
+
+	return prefix
+}
+
+// Fold an infix expression of two literals.
+// Expressions which raise an Error when evaluated (division by zero, mismatched types) are left alone
+func foldInfix(infix *ast.InfixExpression) ast.Expression {
+	switch left := infix.Left.(type) {
+	case *ast.IntLiteral:
+		right, ok := infix.Right.(*ast.IntLiteral)
+		if !ok {
+			return infix
+		}
+
+		switch infix.Operator {
+		case "+":
+			return intLiteral(left.Value+right.Value, infix.Token)
+		case "-":
+			return intLiteral(left.Value-right.Value, infix.Token)
+		case "*":
+			return intLiteral(left.Value*right.Value, infix.Token)
+		case "/":
+			if right.Value != 0 {
+				return intLiteral(left.Value/right.Value, infix.Token)
+			}
+		case "<":
+			return boolLiteral(left.Value < right.Value, infix.Token)
+		case ">":
+			return boolLiteral(left.Value > right.Value, infix.Token)
+		case "==":
+			return boolLiteral(left.Value == right.Value, infix.Token)
+		case "!=":
+			return boolLiteral(left.Value != right.Value, infix.Token)
+		}
+
+	case *ast.StringLiteral:
+		// Strings only support concatenation
+		if right, ok := infix.Right.(*ast.StringLiteral); ok && infix.Operator == "+" {
+			return &ast.StringLiteral{
+				Token: token.Token{Type: token.STRING, Literal: left.Value + right.Value, Position: infix.Token.Position},
+				Value: left.Value + right.Value,
+			}
+		}
+
+	case *ast.BoolLiteral:
+		right, ok := infix.Right.(*ast.BoolLiteral)
+		if !ok {
+			return infix
+		}
+
+		switch infix.Operator {
+		case "==":
+			return boolLiteral(left.Value == right.Value, infix.Token)
+		case "!=":
+			return boolLiteral(left.Value != right.Value, infix.Token)
+		}
+	}
+
+	return infix
+}
+
+func intLiteral(val int64, tok token.Token) *ast.IntLiteral {
+	return &ast.IntLiteral{
+		Token: token.Token{Type: token.NUMBER, Literal: fmt.Sprintf("%d", val), Position: tok.Position},
+		Value: val,
+	}
+}
+
+func boolLiteral(val bool, tok token.Token) *ast.BoolLiteral {
+	tokType := token.TokenType(token.FALSE)
+	if val {
+		tokType = token.TRUE
+	}
+
+	return &ast.BoolLiteral{
+		Token: token.Token{Type: tokType, Literal: fmt.Sprintf("%t", val), Position: tok.Position},
+		Value: val,
+	}
+}
+
+/*** Dead Branch Elimination ***/
+
+// Truthiness of a literal condition, ok is false if the expression is not a literal
+func literalTruth(expr ast.Expression) (truthy bool, ok bool) {
+	switch lit := expr.(type) {
+	case *ast.BoolLiteral:
+		return lit.Value, true
+	case *ast.IntLiteral, *ast.StringLiteral:
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// Remove the branch of an if expression which a literal condition never takes.
+// A taken branch of a single expression replaces the if altogether
+func pruneIf(ifExpr *ast.IfExpression) ast.Expression {
+	truthy, ok := literalTruth(ifExpr.Condition)
+	if !ok || declaresLets(dropped(ifExpr, truthy)) {
+		return ifExpr
+	}
+
+	taken := ifExpr.Alternative
+	if truthy {
+		taken = ifExpr.Consequence
+	}
+
+	if taken == nil {
+		// Evaluates to null
+		return &ast.IfExpression{
+			Token:       ifExpr.Token,
+			Condition:   boolLiteral(false, ifExpr.Token),
+			Consequence: &ast.BlockStatement{Token: ifExpr.Consequence.Token},
+		}
+	}
+
+	if len(taken.Statements) == 1 {
+		if exprStmt, ok := taken.Statements[0].(*ast.ExpressionStatement); ok {
+			return exprStmt.Expr
+		}
+	}
+
+	return &ast.IfExpression{
+		Token:       ifExpr.Token,
+		Condition:   boolLiteral(true, ifExpr.Token),
+		Consequence: taken,
+	}
+}
+
+// Branch of an if expression which is never taken when its condition has the given truth
+func dropped(ifExpr *ast.IfExpression, truthy bool) *ast.BlockStatement {
+	if truthy {
+		return ifExpr.Alternative
+	}
+
+	return ifExpr.Consequence
+}
+
+// Whether block holds a let outside of function bodies. Such a branch is kept even when never taken,
+// as without its let a use of the name would be reported before the program runs instead of when reached
+func declaresLets(block *ast.BlockStatement) bool {
+	if block == nil {
+		return false
+	}
+
+	found := false
+	ast.Inspect(block, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.LetStatement:
+			found = true
+		case *ast.FnLiteral:
+			return false
+		}
+		return !found
+	})
+
+	return found
+}
@@ -0,0 +1,137 @@
+package optimizer
+
+import (
+	"bytes"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	return program
+}
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Constant folding
+		{"1 + 2 * 3", "7"},
+		{"(10 - 4) / 2 > 2", "true"},
+		{"-(5 + 1) + 10", "4"},
+		{`"a" + "b" + "c"`, `"abc"`},
+		{"!true == false", "true"},
+		{"!5", "false"},
+		{"x + 2 * 3", "x + 6"},
+		{"1 / 0", "1 / 0"},
+		{"1 + true", "1 + true"},
+		{`"a" == "a"`, `"a" == "a"`},
+		{"true < false", "true < false"},
+
+		// Dead branches
+		{"if (1 < 2) { x } else { y }", "x"},
+		{"if (false) { x } else { y }", "y"},
+		{"if (false) { x }", "if (false) {}"},
+		{"if (true) { let a = 1; a }; 2", "let a = 1; a; 2"},
+		{"if (false) { x }; 2", "2"},
+		// Branches holding a let are kept, the name stays declared
+		{"if (false) { let x = 1 }; x", "if (false) { let x = 1 }; x"},
+		{"if (true) { 1 } else { let x = 2; x }", "if (true) { 1 } else { let x = 2; x }"},
+		{"if (false) { let f = fn() { 1 }; f() }; 2", "if (false) { let f = fn() { 1 }; f() }; 2"},
+		{"if (false) { fn() { let x = 1 } }; 2", "2"},
+		{"if (x) { 1 + 1 } else { 2 }", "if (x) { 2 } else { 2 }"},
+
+		// Inlining
+		{"let sq = fn(x) { x * x }; sq(3)", "let sq = fn(x) { x * x }; 9"},
+		{"let add = fn(a, b) { a + b }; let n = 1; add(n, 2)", "let add = fn(a, b) { a + b }; let n = 1; n + 2"},
+		{"let twice = fn(x) { x * 2 }; let quad = fn(x) { twice(twice(x)) }; quad(5)", "let twice = fn(x) { x * 2 }; let quad = fn(x) { twice(x * 2) }; 20"},
+		{"let f = fn(x) { if (x > 1) { 10 } else { 20 } }; f(5)", "let f = fn(x) { if (x > 1) { 10 } else { 20 } }; 10"},
+		// Calls before the let see no function
+		{"sq(3); let sq = fn(x) { x * x }", "sq(3); let sq = fn(x) { x * x }"},
+		// Recursive
+		{"let f = fn(x) { f(x) }; f(1)", "let f = fn(x) { f(x) }; f(1)"},
+		// Bound more than once
+		{"let sq = fn(x) { x * x }; let g = fn(sq) { sq(2) }; sq(2)", "let sq = fn(x) { x * x }; let g = fn(sq) { sq(2) }; sq(2)"},
+		// Args with side effects or unused identifiers
+		{"let sq = fn(x) { x * x }; sq(puts(2))", "let sq = fn(x) { x * x }; sq(puts(2))"},
+		{"let k = fn(x) { 1 }; k(missing)", "let k = fn(x) { 1 }; k(missing)"},
+		{"let k = fn(x) { 1 }; k(2)", "let k = fn(x) { 1 }; 1"},
+		// Annotated parameters are checked when the function is applied
+		{"let sq = fn(x: int) { x * x }; sq(3)", "let sq = fn(x: int) { x * x }; sq(3)"},
+		// Wrong number of args still raises the Error
+		{"let sq = fn(x) { x * x }; sq(1, 2)", "let sq = fn(x) { x * x }; sq(1, 2)"},
+		// Free names which may be shadowed at the call site are not inlined, g itself is
+		{"let f = fn() { y }; let g = fn(y) { f() }; g(1)", "let f = fn() { y }; let g = fn(y) { f() }; f()"},
+		// Mutual recursion stops at the first repeated function: a is inlined into b, which then calls itself
+		{"let a = fn(x) { b(x) }; let b = fn(x) { a(x) }; a(1)", "let a = fn(x) { b(x) }; let b = fn(x) { b(x) }; b(1)"},
+	}
+
+	for _, tt := range tests {
+		// The expected program is given as source, compared with the optimized one once both are printed
+		got := Optimize(parse(t, tt.input)).String()
+		expected := parse(t, tt.expected).String()
+
+		if got != expected {
+			t.Errorf("Wrong optimization of %q.\nGot=%q\nexpected=%q", tt.input, got, expected)
+		}
+
+		// Printing must keep every node for the comparison to mean anything
+		if reparsed := parse(t, got).String(); reparsed != got {
+			t.Errorf("Optimized program of %q does not print as its source.\nGot=%q\nreparsed=%q", tt.input, got, reparsed)
+		}
+	}
+}
+
+// Optimized programs evaluate to the same value or error as the originals
+func TestOptimizePreservesSemantics(t *testing.T) {
+	inputs := []string{
+		"1 + 2 * 3 - 4 / 2",
+		"1 / 0",
+		"let f = fn() { 1 / 0 }; f()",
+		"if (false) { 1 }",
+		"5; if (false) { 1 }",
+		"let f = fn(x) { if (true) { return x * 2; }; 0 }; f(4)",
+		"let sq = fn(x) { x * x }; let n = 7; sq(n) + sq(2)",
+		"let sq = fn(x) { x * x }; sq(1, 2)",
+		"let sq = fn(x) { x * x }; sq(true)",
+		"let pick = fn(c) { if (c) { 1 } else { 2 } }; [pick(true), pick(false), pick(0)]",
+		"let count = fn(n) { if (n == 0) { 0 } else { count(n - 1) } }; count(1 + 99)",
+		`let greet = fn(name) { "hi " + name }; greet("bob")`,
+		`try { if (true) { throw "x" }; 1 } catch (e) { e.message }`,
+		// Names in dropped branches
+		"if (false) { nope }",
+		`puts("a"); if (false) { let x = 1 }; x`,
+	}
+
+	for _, input := range inputs {
+		var expectedOut, optimizedOut bytes.Buffer
+		expected := evaluator.Eval(parse(t, input), evaluator.NewEnvironment(evaluator.Config{Stdout: &expectedOut}))
+
+		// Resolved before it is optimized, as the interpreter does
+		env := evaluator.NewEnvironment(evaluator.Config{Stdout: &optimizedOut})
+		program := parse(t, input)
+		var optimized object.Object
+		if errObj := evaluator.Resolve(program, env); errObj != nil {
+			optimized = errObj
+		} else {
+			optimized = evaluator.Eval(Optimize(program), env)
+		}
+
+		if expected.Inspect() != optimized.Inspect() {
+			t.Errorf("Optimization of %q changed the result. Got=%s, expected=%s", input, optimized.Inspect(), expected.Inspect())
+		}
+		if expectedOut.String() != optimizedOut.String() {
+			t.Errorf("Optimization of %q changed the output. Got=%q, expected=%q", input, optimizedOut.String(), expectedOut.String())
+		}
+	}
+}
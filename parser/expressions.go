@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"fmt"
 	"monkey/ast"
 	"monkey/token"
 	"strconv"
@@ -13,7 +12,7 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefixFn := p.prefixParsers[p.currToken.Type]
 	if prefixFn == nil {
 		// Do not know how to begin parsing an expression with this TokenType
-		p.errors = append(p.errors, fmt.Sprintf("No prefix parser function found for token %s. Line %d Column %d", p.currToken.Type, p.currToken.Position.Line, p.currToken.Position.Column))
+		p.addError(p.currToken.Position, "No prefix parser function found for token %s. Line %d Column %d", p.currToken.Type, p.currToken.Position.Line, p.currToken.Position.Column)
 		return nil
 	}
 
@@ -86,7 +85,7 @@ func (p *Parser) parseIntLiteral() ast.Expression {
 
 	val, err := strconv.ParseInt(p.currToken.Literal, 0, 64)
 	if err != nil {
-		p.errors = append(p.errors, fmt.Sprintf("Unable to parse %s to int literal", p.currToken.Literal))
+		p.addError(p.currToken.Position, "Unable to parse %s to int literal", p.currToken.Literal)
 		return nil
 	}
 
@@ -112,7 +111,7 @@ func (p *Parser) parseBoolLiteral() ast.Expression {
 
 	val, err := strconv.ParseBool(p.currToken.Literal)
 	if err != nil {
-		p.errors = append(p.errors, fmt.Sprintf("Unable to parse %s as a Boolean", p.currToken.Literal))
+		p.addError(p.currToken.Position, "Unable to parse %s as a Boolean", p.currToken.Literal)
 		return nil
 	}
 
@@ -148,6 +147,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		return nil
 	}
 
+	hash.Rbrace = p.currToken.Position
 	return hash
 }
 
@@ -157,6 +157,7 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	}
 
 	arr.Elements = p.parseExpressionList(token.RBRACKET)
+	arr.Rbracket = p.currToken.Position
 	return arr
 }
 
@@ -208,6 +209,52 @@ func (p *Parser) parseConditional() ast.Expression {
 	return ifExpr
 }
 
+// Parse currToken as a TryExpression which has the form:
+// try { } catch (<identifier>) { } finally { }
+// At least one of the catch and finally clauses must be present
+func (p *Parser) parseTryExpression() ast.Expression {
+	tryExpr := &ast.TryExpression{
+		Token: p.currToken,
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	tryExpr.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.advanceTokens()
+		if !p.expectPeek(token.LPAREN) || !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+
+		tryExpr.Param = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+		if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		tryExpr.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.advanceTokens()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		tryExpr.Finally = p.parseBlockStatement()
+	}
+
+	if tryExpr.Catch == nil && tryExpr.Finally == nil {
+		p.addError(tryExpr.Token.Position, "try requires a catch or finally clause. Line %d Column %d", tryExpr.Token.Position.Line, tryExpr.Token.Position.Column)
+		return nil
+	}
+
+	return tryExpr
+}
+
 // Parse currToken as a FnLiteral
 func (p *Parser) parseFnLiteral() ast.Expression {
 	fn := &ast.FnLiteral{
@@ -220,6 +267,14 @@ func (p *Parser) parseFnLiteral() ast.Expression {
 	}
 
 	fn.Parameters = p.parseFnParams()
+	if fn.Parameters == nil {
+		return nil
+	}
+
+	var ok bool
+	if fn.Result, ok = p.parseResultAnnotation(); !ok {
+		return nil
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -246,6 +301,9 @@ func (p *Parser) parseFnParams() []*ast.Identifier {
 	}
 
 	ident := p.parseIndentifier().(*ast.Identifier)
+	if !p.parseParamAnnotation(ident) {
+		return nil
+	}
 	params = append(params, ident)
 
 	for p.peekTokenIs(token.COMMA) {
@@ -255,6 +313,9 @@ func (p *Parser) parseFnParams() []*ast.Identifier {
 		}
 
 		ident := p.parseIndentifier().(*ast.Identifier)
+		if !p.parseParamAnnotation(ident) {
+			return nil
+		}
 		params = append(params, ident)
 	}
 
@@ -265,6 +326,14 @@ func (p *Parser) parseFnParams() []*ast.Identifier {
 	return params
 }
 
+// Parse the optional annotation of a parameter, as in fn(x: int)
+func (p *Parser) parseParamAnnotation(param *ast.Identifier) bool {
+	var ok bool
+	param.Type, ok = p.parseColonAnnotation()
+
+	return ok
+}
+
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	idx := &ast.IndexExpression{
 		Token: p.currToken,
@@ -283,6 +352,26 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return idx
 }
 
+// Current token is a DOT in an infix position.
+// Member access is sugar for indexing with a string key: <expression>.<identifier> == <expression>["<identifier>"]
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	idx := &ast.IndexExpression{
+		Token: p.currToken,
+		Left:  left,
+	}
+
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+
+	idx.Index = &ast.StringLiteral{
+		Token: p.currToken,
+		Value: p.currToken.Literal,
+	}
+
+	return idx
+}
+
 // Current token is LPAREN in an infix position
 // Previous should be an Identifier/FnLiteral
 func (p *Parser) parseCallExpression(fn ast.Expression) ast.Expression {
@@ -292,6 +381,7 @@ func (p *Parser) parseCallExpression(fn ast.Expression) ast.Expression {
 	}
 
 	callExpr.Args = p.parseExpressionList(token.RPAREN)
+	callExpr.Rparen = p.currToken.Position
 	return callExpr
 }
 
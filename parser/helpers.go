@@ -2,12 +2,29 @@ package parser
 
 import (
 	"fmt"
+	"monkey/ast"
 	"monkey/token"
 )
 
 func (p *Parser) currTokenIs(tokType token.TokenType) bool { return p.currToken.Type == tokType }
 func (p *Parser) peekTokenIs(tokType token.TokenType) bool { return p.nextToken.Type == tokType }
 
+// Errors encountered while parsing the program
+func (p *Parser) Errors() []string {
+	return p.errors
+}
+
+// Position in the source of each of Errors(), for tools which mark them in place
+func (p *Parser) ErrorPositions() []token.Position {
+	return p.positions
+}
+
+// Record an error found at pos
+func (p *Parser) addError(pos token.Position, format string, args ...any) {
+	p.errors = append(p.errors, fmt.Sprintf(format, args...))
+	p.positions = append(p.positions, pos)
+}
+
 func (p *Parser) ParserErrors() bool {
 	if len(p.errors) > 0 {
 		for _, err := range p.errors {
@@ -23,13 +40,21 @@ func (p *Parser) ParserErrors() bool {
 func (p *Parser) advanceTokens() {
 	p.currToken = p.nextToken
 	p.nextToken = p.lexer.NextToken()
+
+	// Comments skipped while lexing nextToken sit between it and currToken
+	for _, tok := range p.lexer.Comments()[len(p.comments):] {
+		p.comments = append(p.comments, &ast.Comment{
+			Token:    tok,
+			Trailing: p.currToken.Type != "" && tok.Position.Line == p.currToken.Position.Line,
+		})
+	}
 }
 
 // Determines if next token is of the expected type.
 // Advances token pointers if true, creates parser error otherwise
 func (p *Parser) expectPeek(expToken token.TokenType) bool {
 	if !p.peekTokenIs(expToken) {
-		p.errors = append(p.errors, fmt.Sprintf("expectPeek found unexpected peek, Got=%s Expected=%s. Line %d, Column %d", p.nextToken.Type, expToken, p.nextToken.Position.Line, p.nextToken.Position.Column))
+		p.addError(p.nextToken.Position, "expectPeek found unexpected peek, Got=%s Expected=%s. Line %d, Column %d", p.nextToken.Type, expToken, p.nextToken.Position.Line, p.nextToken.Position.Column)
 		return false
 	}
 
@@ -62,6 +87,7 @@ func (p *Parser) registerPrefixParsers() {
 	p.prefixParsers[token.NUMBER] = p.parseIntLiteral
 	p.prefixParsers[token.STRING] = p.parseStringLiteral
 	p.prefixParsers[token.IF] = p.parseConditional
+	p.prefixParsers[token.TRY] = p.parseTryExpression
 
 	p.prefixParsers[token.LBRACKET] = p.parseArrayLiteral
 	p.prefixParsers[token.LBRACE] = p.parseHashLiteral
@@ -88,6 +114,7 @@ func (p *Parser) registerInfixParsers() {
 	p.infixParsers[token.LPAREN] = p.parseCallExpression
 
 	p.infixParsers[token.LBRACKET] = p.parseIndexExpression
+	p.infixParsers[token.DOT] = p.parseMemberExpression
 
 	p.infixParsers[token.LT] = p.parseInfixExpression
 	p.infixParsers[token.GT] = p.parseInfixExpression
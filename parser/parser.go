@@ -18,6 +18,7 @@ var precedence = map[token.TokenType]int{
 	token.ASTERISK: PRODUCT,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX, // Highest
+	token.DOT:      INDEX,
 }
 
 // Order of precedence for expression evaluation
@@ -41,11 +42,13 @@ type (
 )
 
 type Parser struct {
-	lexer  *lexer.Lexer
-	errors []string
+	lexer     *lexer.Lexer
+	errors    []string
+	positions []token.Position // Where each of errors was found
 
 	currToken token.Token
 	nextToken token.Token
+	comments  []*ast.Comment
 
 	infixParsers  map[token.TokenType]InfixParseFn
 	prefixParsers map[token.TokenType]PrefixParseFn
@@ -84,6 +87,7 @@ func (p *Parser) ParseProgram() *ast.Program {
 		p.advanceTokens()
 	}
 
+	program.Comments = p.comments
 	return program
 }
 
@@ -96,6 +100,8 @@ func (p *Parser) parseStatement() ast.Statement {
 		stmt = p.parseLetStatement()
 	case token.RETURN:
 		stmt = p.parseReturnStatement()
+	case token.THROW:
+		stmt = p.parseThrowStatement()
 	default:
 		stmt = p.parseExpressionStatement()
 	}
@@ -122,6 +128,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		p.advanceTokens()
 	}
 
+	blkStmt.Rbrace = p.currToken.Position
 	return blkStmt
 }
 
@@ -141,6 +148,11 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 		Value: p.currToken.Literal,
 	}
 
+	var ok bool
+	if ls.Type, ok = p.parseColonAnnotation(); !ok {
+		return nil
+	}
+
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -172,6 +184,22 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return rs
 }
 
+// Parse the current token as ThrowStatement
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	ts := &ast.ThrowStatement{
+		Token: p.currToken,
+	}
+
+	p.advanceTokens()
+	ts.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.advanceTokens()
+	}
+
+	return ts
+}
+
 // Parse the current token as the start of an ExpressionStatement
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	es := &ast.ExpressionStatement{
@@ -265,6 +265,125 @@ func TestCallExpressionParsing(t *testing.T) {
 	testInfixExpression(t, exp.Args[2], 4, "+", 5)
 }
 
+func TestMemberExpressionParsing(t *testing.T) {
+	input := "regex.match(a, b)"
+	program := createParseProgram(t, input)
+	assertAstLength(t, program, 1)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("stmt is not %T. got=%T", &ast.ExpressionStatement{}, program.Statements[0])
+	}
+
+	call, ok := stmt.Expr.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not %T. got=%T", &ast.CallExpression{}, stmt.Expr)
+	}
+
+	member, ok := call.Fn.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("call.Fn is not %T. got=%T", &ast.IndexExpression{}, call.Fn)
+	}
+
+	testIdentifier(t, member.Left, "regex")
+
+	str, ok := member.Index.(*ast.StringLiteral)
+	if !ok || str.Value != "match" {
+		t.Fatalf("member.Index is not StringLiteral \"match\". got=%T (%+v)", member.Index, member.Index)
+	}
+
+	if member.String() != "(regex.match)" {
+		t.Errorf("member.String() wrong. got=%q", member.String())
+	}
+}
+
+func TestTryExpressionParsing(t *testing.T) {
+	input := `try { risky(x) } catch (e) { e } finally { cleanup() }`
+	program := createParseProgram(t, input)
+	assertAstLength(t, program, 1)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("stmt is not %T. got=%T", &ast.ExpressionStatement{}, program.Statements[0])
+	}
+
+	tryExpr, ok := stmt.Expr.(*ast.TryExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not %T. got=%T", &ast.TryExpression{}, stmt.Expr)
+	}
+
+	if len(tryExpr.Block.Statements) != 1 {
+		t.Errorf("try block wrong. got=%q", tryExpr.Block.String())
+	}
+
+	testIdentifier(t, tryExpr.Param, "e")
+
+	if tryExpr.Catch == nil || len(tryExpr.Catch.Statements) != 1 {
+		t.Errorf("catch block wrong. got=%+v", tryExpr.Catch)
+	}
+
+	if tryExpr.Finally == nil || len(tryExpr.Finally.Statements) != 1 {
+		t.Errorf("finally block wrong. got=%+v", tryExpr.Finally)
+	}
+
+	for _, input := range []string{"try { 1 } finally { 2 }", "try { 1 } catch (e) { 2 }"} {
+		createParseProgram(t, input)
+	}
+
+	p := New(lexer.New("try { 1 }"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("try without catch or finally did not produce an error")
+	}
+}
+
+func TestThrowStatement(t *testing.T) {
+	program := createParseProgram(t, `throw "bad input";`)
+	assertAstLength(t, program, 1)
+
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("stmt is not %T. got=%T", &ast.ThrowStatement{}, program.Statements[0])
+	}
+
+	str, ok := stmt.Value.(*ast.StringLiteral)
+	if !ok || str.Value != "bad input" {
+		t.Errorf("stmt.Value is not StringLiteral \"bad input\". got=%T (%+v)", stmt.Value, stmt.Value)
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := "// own line\nlet f = fn() { // after brace\n  1\n  // inside\n}; // after let"
+	program := createParseProgram(t, input)
+	assertAstLength(t, program, 1)
+
+	expected := []struct {
+		text     string
+		trailing bool
+	}{
+		{"// own line", false},
+		{"// after brace", true},
+		{"// inside", false},
+		{"// after let", true},
+	}
+
+	if len(program.Comments) != len(expected) {
+		t.Fatalf("Wrong number of comments. Got=%d, expected=%d", len(program.Comments), len(expected))
+	}
+
+	for idx, tt := range expected {
+		comment := program.Comments[idx]
+		if comment.Text() != tt.text || comment.Trailing != tt.trailing {
+			t.Errorf("comment[%d]: Got %q trailing=%t, expected %q trailing=%t", idx, comment.Text(), comment.Trailing, tt.text, tt.trailing)
+		}
+	}
+
+	body := program.Statements[0].(*ast.LetStatement).Value.(*ast.FnLiteral).Body
+	if body.Rbrace.Line != 5 || body.Rbrace.Column != 1 {
+		t.Errorf("Wrong closing brace position. Got=%s", body.Rbrace)
+	}
+}
+
 /*** Helpers ***/
 
 func createParseProgram(t *testing.T, input string) *ast.Program {
@@ -406,3 +525,74 @@ func testInfixExpression(t *testing.T, exp ast.Expression, left interface{}, ope
 
 	return true
 }
+
+func TestTypeAnnotations(t *testing.T) {
+	program := createParseProgram(t, `let x: int = 5; let f = fn(a: string, b: [int], c) -> {string: fn(int) -> bool} { a }; fn(g: fn()) -> fn() -> any { g }`)
+
+	let := program.Statements[0].(*ast.LetStatement)
+	if let.Type == nil || let.Type.String() != "int" || let.Type.Pos().Column != 8 {
+		t.Errorf("Wrong let annotation. Got=%v", let.Type)
+	}
+
+	fn := program.Statements[1].(*ast.LetStatement).Value.(*ast.FnLiteral)
+	for idx, expected := range []string{"string", "[int]", ""} {
+		got := ""
+		if fn.Parameters[idx].Type != nil {
+			got = fn.Parameters[idx].Type.String()
+		}
+
+		if got != expected {
+			t.Errorf("Wrong annotation of parameter %d. Got=%q, expected=%q", idx, got, expected)
+		}
+	}
+
+	if fn.Result == nil || fn.Result.String() != "{string: fn(int) -> bool}" {
+		t.Errorf("Wrong result annotation. Got=%v", fn.Result)
+	}
+
+	expected := "let x: int = 5;\n" +
+		"let f = fn(a: string, b: [int], c) -> {string: fn(int) -> bool} { a };\n" +
+		"fn(g: fn()) -> fn() -> any { g };\n"
+	if program.String() != expected {
+		t.Errorf("Wrong String(). Got=%q, expected=%q", program.String(), expected)
+	}
+}
+
+func TestTypeAnnotationErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let x: integer = 5`, "Unknown type integer. Line 1 Column 8"},
+		{`let x: = 5`, "Expected a type, Got=Assign. Line 1 Column 8"},
+		{`fn(a: [int) {}`, "expectPeek found unexpected peek, Got=Right-Paren Expected=Right-Bracket. Line 1, Column 11"},
+		{`fn(a: {string}) {}`, "expectPeek found unexpected peek, Got=Right-Brace Expected=Colon. Line 1, Column 14"},
+		{`fn() -> {}`, "Expected a type, Got=Right-Brace. Line 1 Column 10"},
+		{`fn(f: fn(int int)) {}`, "expectPeek found unexpected peek, Got=Identifier Expected=Comma. Line 1, Column 14"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("Wrong errors for %q. Got=%q, expected first=%q", tt.input, p.Errors(), tt.expected)
+		}
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	p := New(lexer.New("let = 1;\nlet x: integer = 2;\ntry { 3 }"))
+	p.ParseProgram()
+
+	expected := []string{"1:5", "1:5", "2:8", "2:16", "3:1"}
+	if len(p.ErrorPositions()) != len(p.Errors()) {
+		t.Fatalf("Wrong number of positions, Got=%d, expected=%d", len(p.ErrorPositions()), len(p.Errors()))
+	}
+
+	for idx, pos := range p.ErrorPositions() {
+		if idx >= len(expected) || pos.String() != expected[idx] {
+			t.Errorf("Wrong position of %q, Got=%s", p.Errors()[idx], pos)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"monkey/ast"
+	"monkey/token"
+	"slices"
+)
+
+// Parse the type annotation starting at the current token
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	ta := &ast.TypeAnnotation{Token: p.currToken}
+
+	switch p.currToken.Type {
+	case token.IDENTIFIER:
+		if !slices.Contains(ast.TypeNames, p.currToken.Literal) {
+			p.addError(p.currToken.Position, "Unknown type %s. Line %d Column %d", p.currToken.Literal, p.currToken.Position.Line, p.currToken.Position.Column)
+			return nil
+		}
+		ta.Name = p.currToken.Literal
+
+	case token.LBRACKET:
+		p.advanceTokens()
+		if ta.Elem = p.parseTypeAnnotation(); ta.Elem == nil {
+			return nil
+		}
+
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+
+	case token.LBRACE:
+		p.advanceTokens()
+		if ta.Key = p.parseTypeAnnotation(); ta.Key == nil {
+			return nil
+		}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.advanceTokens()
+		if ta.Value = p.parseTypeAnnotation(); ta.Value == nil {
+			return nil
+		}
+
+		if !p.expectPeek(token.RBRACE) {
+			return nil
+		}
+
+	case token.FUNCTION:
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+
+		ta.Params = []*ast.TypeAnnotation{}
+		for !p.peekTokenIs(token.RPAREN) {
+			if len(ta.Params) > 0 && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+
+			p.advanceTokens()
+			param := p.parseTypeAnnotation()
+			if param == nil {
+				return nil
+			}
+			ta.Params = append(ta.Params, param)
+		}
+		p.advanceTokens()
+
+		result, ok := p.parseResultAnnotation()
+		if !ok {
+			return nil
+		}
+		ta.Result = result
+
+	default:
+		p.addError(p.currToken.Position, "Expected a type, Got=%s. Line %d Column %d", p.currToken.Type, p.currToken.Position.Line, p.currToken.Position.Column)
+		return nil
+	}
+
+	return ta
+}
+
+// Parse the annotation following the next token when it is a colon, as in let x: int
+func (p *Parser) parseColonAnnotation() (*ast.TypeAnnotation, bool) {
+	if !p.peekTokenIs(token.COLON) {
+		return nil, true
+	}
+
+	p.advanceTokens()
+	p.advanceTokens()
+	ta := p.parseTypeAnnotation()
+
+	return ta, ta != nil
+}
+
+// Parse the annotation following the next token when it is an arrow, as in fn() -> int
+func (p *Parser) parseResultAnnotation() (*ast.TypeAnnotation, bool) {
+	if !p.peekTokenIs(token.ARROW) {
+		return nil, true
+	}
+
+	p.advanceTokens()
+	p.advanceTokens()
+	ta := p.parseTypeAnnotation()
+
+	return ta, ta != nil
+}
@@ -0,0 +1,568 @@
+// Package printer formats syntax trees as canonical Monkey source.
+// Output parses back into the same tree, and formatting formatted source leaves it unchanged
+package printer
+
+import (
+	"errors"
+	"io"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"strings"
+)
+
+const indentation = "    "
+
+// Longest statement, in bytes, which a block holding only that statement is printed on one line with
+const maxInlineBlock = 60
+
+// Precedence of each infix operator, as the parser binds them
+var infixPrecedence = map[string]int{
+	"==": parser.EQUALS,
+	"!=": parser.EQUALS,
+	"<":  parser.LESSGREATER,
+	">":  parser.LESSGREATER,
+	"+":  parser.SUM,
+	"-":  parser.SUM,
+	"*":  parser.PRODUCT,
+	"/":  parser.PRODUCT,
+}
+
+// Precedence of literals, identifiers and the keyword expressions, which never need parentheses
+const atomic = parser.INDEX + 1
+
+type printer struct {
+	out      *strings.Builder
+	indent   int
+	comments []*ast.Comment
+	next     int  // Index of the first comment not printed yet
+	lastLine int  // Source line of the last statement or comment printed
+	fresh    bool // Nothing printed yet in the current list of statements
+}
+
+// Parse src and print it canonically formatted, keeping its comments
+func Format(src []byte) ([]byte, error) {
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+
+	var out strings.Builder
+	if err := Fprint(&out, program); err != nil {
+		return nil, err
+	}
+
+	return []byte(out.String()), nil
+}
+
+// Write node to w as formatted source. The comments of a Program are printed with it,
+// placed before the statement or list item following them or at the end of the line they trailed.
+// Arrays, hashes and calls holding comments are printed with one item per line
+func Fprint(w io.Writer, node ast.Node) error {
+	p := &printer{out: &strings.Builder{}, fresh: true}
+
+	switch node := node.(type) {
+	case *ast.Program:
+		p.comments = node.Comments
+		p.statements(node.Statements, true)
+		p.flushComments(-1)
+		if p.out.Len() > 0 {
+			p.out.WriteString("\n")
+		}
+
+	case *ast.BlockStatement:
+		p.block(node)
+
+	case ast.Statement:
+		p.statement(node)
+
+	case ast.Expression:
+		p.expression(node, parser.LOWEST)
+	}
+
+	_, err := io.WriteString(w, p.out.String())
+	return err
+}
+
+func (p *printer) print(strs ...string) {
+	for _, str := range strs {
+		p.out.WriteString(str)
+	}
+}
+
+// Start a new line at the current indentation
+func (p *printer) line() {
+	if p.out.Len() > 0 {
+		p.out.WriteString("\n")
+	}
+	p.out.WriteString(strings.Repeat(indentation, p.indent))
+}
+
+// Keep a single blank line where the source had one or more before line.
+// Lists of statements never start with a blank line
+func (p *printer) separate(line int) {
+	if !p.fresh && p.lastLine > 0 && line > p.lastLine+1 {
+		p.out.WriteString("\n")
+	}
+}
+
+// Print the comments which come before offset in the source, all remaining comments when offset is negative.
+// Trailing comments stay at the end of the line printed last, other comments get lines of their own
+func (p *printer) flushComments(offset int) {
+	for ; p.next < len(p.comments); p.next++ {
+		comment := p.comments[p.next]
+		if offset >= 0 && comment.Pos().Offset >= offset {
+			return
+		}
+
+		if comment.Trailing && p.out.Len() > 0 {
+			p.print(" ", comment.Text())
+			continue
+		}
+
+		p.separate(comment.Pos().Line)
+		p.line()
+		p.print(comment.Text())
+
+		// A comment left over from inside an expression comes before the end of the statement printed last
+		p.lastLine = max(p.lastLine, comment.Pos().Line)
+		p.fresh = false
+	}
+}
+
+// Print the output of fn into a string instead of the output
+func (p *printer) render(fn func()) string {
+	saved := p.out
+	p.out = &strings.Builder{}
+	fn()
+
+	rendered := p.out.String()
+	p.out = saved
+
+	return rendered
+}
+
+/*** Statements ***/
+
+func (p *printer) statements(stmts []ast.Statement, top bool) {
+	for idx, stmt := range stmts {
+		p.flushComments(stmt.Pos().Offset)
+		p.separate(stmt.Pos().Line)
+		p.line()
+		p.statement(stmt)
+
+		var next ast.Statement
+		if idx < len(stmts)-1 {
+			next = stmts[idx+1]
+		}
+		if needsSemicolon(stmt, next, top) {
+			p.print(";")
+		}
+
+		p.lastLine = endLine(stmt)
+		p.fresh = false
+	}
+}
+
+// Let, return and throw always end with a semicolon, expression statements do unless they are the value of a block.
+// Expressions ending with a block only need one when the next statement would continue the expression
+func needsSemicolon(stmt ast.Statement, next ast.Statement, top bool) bool {
+	exprStmt, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return true
+	}
+
+	if next == nil && !top {
+		return false
+	}
+
+	switch exprStmt.Expr.(type) {
+	case *ast.IfExpression, *ast.TryExpression, *ast.FnLiteral:
+		// A statement starting with ( [ or - would be parsed as a call, index or subtraction of the one before
+		nextExpr, ok := next.(*ast.ExpressionStatement)
+		return ok && strings.ContainsRune("([-", leading(nextExpr.Expr))
+	default:
+		return true
+	}
+}
+
+func (p *printer) statement(astStmt ast.Statement) {
+	switch stmt := astStmt.(type) {
+	case *ast.LetStatement:
+		p.print("let ", stmt.Name.Value)
+		if stmt.Type != nil {
+			p.print(": ", stmt.Type.String())
+		}
+		p.print(" = ")
+		p.expression(stmt.Value, parser.LOWEST)
+
+	case *ast.ReturnStatement:
+		p.print("return ")
+		p.expression(stmt.Value, parser.LOWEST)
+
+	case *ast.ThrowStatement:
+		p.print("throw ")
+		p.expression(stmt.Value, parser.LOWEST)
+
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expr, parser.LOWEST)
+
+	case *ast.BlockStatement:
+		p.block(stmt)
+	}
+}
+
+// Print a block on one line when it holds a single short statement and no comments, otherwise one statement per line
+func (p *printer) block(block *ast.BlockStatement) {
+	if len(block.Statements) == 0 && !p.hasComments(block.Pos(), block.Rbrace) {
+		p.print("{}")
+		return
+	}
+
+	p.indent++
+
+	if len(block.Statements) == 1 && !p.hasComments(block.Pos(), block.Rbrace) {
+		stmt := block.Statements[0]
+		rendered := p.render(func() { p.statement(stmt) })
+		if needsSemicolon(stmt, nil, false) {
+			rendered += ";"
+		}
+
+		p.indent--
+		if !strings.Contains(rendered, "\n") && len(rendered) <= maxInlineBlock {
+			p.print("{ ", rendered, " }")
+			return
+		}
+
+		p.print("{")
+		p.indent++
+		p.line()
+		p.print(rendered)
+		p.indent--
+		p.line()
+		p.print("}")
+		return
+	}
+
+	p.print("{")
+	p.lastLine = block.Pos().Line
+	p.fresh = true
+
+	p.statements(block.Statements, false)
+	if block.Rbrace.IsValid() {
+		p.flushComments(block.Rbrace.Offset)
+	}
+
+	p.indent--
+	p.line()
+	p.print("}")
+	p.fresh = false
+}
+
+// Whether any comment not printed yet lies between the brackets at open and end
+func (p *printer) hasComments(open, end token.Position) bool {
+	if !end.IsValid() {
+		return false
+	}
+
+	for _, comment := range p.comments[p.next:] {
+		offset := comment.Pos().Offset
+		if offset > open.Offset && offset < end.Offset {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Whether a comment not printed yet belongs to the list, lying between its brackets but not in a block or list nested in it
+func (p *printer) listComments(list ast.Expression, end token.Position) bool {
+	if !p.hasComments(list.Pos(), end) {
+		return false
+	}
+
+	for _, comment := range p.comments[p.next:] {
+		offset := comment.Pos().Offset
+		if offset <= list.Pos().Offset || offset >= end.Offset {
+			continue
+		}
+
+		nested := false
+		ast.Inspect(list, func(node ast.Node) bool {
+			var close token.Position
+			switch node := node.(type) {
+			case *ast.BlockStatement:
+				close = node.Rbrace
+			case *ast.ArrayLiteral:
+				close = node.Rbracket
+			case *ast.HashLiteral:
+				close = node.Rbrace
+			case *ast.CallExpression:
+				close = node.Rparen
+			}
+
+			if node != list && close.IsValid() && offset > node.Pos().Offset && offset < close.Offset {
+				nested = true
+			}
+			return !nested
+		})
+
+		if !nested {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*** Expressions ***/
+
+// Print expr, in parentheses if it binds less tightly than min
+func (p *printer) expression(astExpr ast.Expression, min int) {
+	if precedenceOf(astExpr) < min {
+		p.print("(")
+		defer p.print(")")
+	}
+
+	switch expr := astExpr.(type) {
+	case *ast.Identifier:
+		p.print(expr.Value)
+
+	case *ast.IntLiteral:
+		p.print(expr.String())
+
+	case *ast.BoolLiteral:
+		p.print(expr.String())
+
+	case *ast.StringLiteral:
+		p.print(`"`, expr.Value, `"`)
+
+	case *ast.ArrayLiteral:
+		p.print("[")
+		p.list(expr, expr.Elements, expr.Rbracket, p.item)
+		p.print("]")
+
+	case *ast.HashLiteral:
+		p.print("{")
+		p.list(expr, expr.Keys(), expr.Rbrace, func(key ast.Expression) {
+			p.expression(key, parser.LOWEST)
+			p.print(": ")
+			p.expression(expr.Pairs[key], parser.LOWEST)
+		})
+		p.print("}")
+
+	case *ast.IndexExpression:
+		p.expression(expr.Left, parser.CALL)
+
+		if member, ok := expr.Index.(*ast.StringLiteral); ok && expr.Token.Type == token.DOT {
+			p.print(".", member.Value)
+			return
+		}
+
+		p.print("[")
+		p.expression(expr.Index, parser.LOWEST)
+		p.print("]")
+
+	case *ast.PrefixExpression:
+		p.print(expr.Operator)
+		p.expression(expr.Operand, parser.PREFIX)
+
+	case *ast.InfixExpression:
+		// Operators are left associative, so a right operand of the same precedence needs parentheses
+		prec := precedenceOf(expr)
+		p.expression(expr.Left, prec)
+		p.print(" ", expr.Operator, " ")
+		p.expression(expr.Right, prec+1)
+
+	case *ast.IfExpression:
+		p.print("if (")
+		p.expression(expr.Condition, parser.LOWEST)
+		p.print(") ")
+		p.block(expr.Consequence)
+
+		if expr.Alternative != nil {
+			p.print(" else ")
+			p.block(expr.Alternative)
+		}
+
+	case *ast.TryExpression:
+		p.print("try ")
+		p.block(expr.Block)
+
+		if expr.Catch != nil {
+			p.print(" catch (", expr.Param.Value, ") ")
+			p.block(expr.Catch)
+		}
+
+		if expr.Finally != nil {
+			p.print(" finally ")
+			p.block(expr.Finally)
+		}
+
+	case *ast.FnLiteral:
+		p.print("fn(")
+		for idx, param := range expr.Parameters {
+			if idx > 0 {
+				p.print(", ")
+			}
+			p.print(param.String())
+		}
+		p.print(") ")
+		if expr.Result != nil {
+			p.print("-> ", expr.Result.String(), " ")
+		}
+		p.block(expr.Body)
+
+	case *ast.CallExpression:
+		p.expression(expr.Fn, parser.CALL)
+		p.print("(")
+		p.list(expr, expr.Args, expr.Rparen, p.item)
+		p.print(")")
+	}
+}
+
+func (p *printer) item(expr ast.Expression) {
+	p.expression(expr, parser.LOWEST)
+}
+
+// Print the comma separated items of the list opened by node and closed at end, each with item.
+// A list holding comments gets a line for each item, preceded by the comments before it
+func (p *printer) list(node ast.Expression, items []ast.Expression, end token.Position, item func(ast.Expression)) {
+	if !p.listComments(node, end) {
+		for idx, expr := range items {
+			if idx > 0 {
+				p.print(", ")
+			}
+			item(expr)
+		}
+		return
+	}
+
+	p.indent++
+	p.lastLine = node.Pos().Line
+	p.fresh = true
+
+	for idx, expr := range items {
+		start := startOf(expr)
+		p.flushComments(start.Offset)
+		p.separate(start.Line)
+		p.line()
+		item(expr)
+		if idx < len(items)-1 {
+			p.print(",")
+		}
+
+		p.lastLine = endLine(expr)
+		p.fresh = false
+	}
+	p.flushComments(end.Offset)
+
+	p.indent--
+	p.line()
+}
+
+// Binding strength of an expression, calls and indexing both bind their left operand as tightly as CALL
+func precedenceOf(astExpr ast.Expression) int {
+	switch expr := astExpr.(type) {
+	case *ast.InfixExpression:
+		if prec, ok := infixPrecedence[expr.Operator]; ok {
+			return prec
+		}
+		return parser.LOWEST
+
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+
+	case *ast.IntLiteral:
+		// Negative values (from rewritten trees) print with a leading minus
+		if expr.Value < 0 {
+			return parser.PREFIX
+		}
+		return atomic
+
+	case *ast.CallExpression, *ast.IndexExpression:
+		return parser.CALL
+
+	default:
+		return atomic
+	}
+}
+
+// First character expr prints as, enough to tell whether it may continue the statement before it
+func leading(astExpr ast.Expression) rune {
+	switch expr := astExpr.(type) {
+	case *ast.InfixExpression:
+		if precedenceOf(expr.Left) < precedenceOf(expr) {
+			return '('
+		}
+		return leading(expr.Left)
+
+	case *ast.CallExpression:
+		if precedenceOf(expr.Fn) < parser.CALL {
+			return '('
+		}
+		return leading(expr.Fn)
+
+	case *ast.IndexExpression:
+		if precedenceOf(expr.Left) < parser.CALL {
+			return '('
+		}
+		return leading(expr.Left)
+
+	case *ast.PrefixExpression:
+		return rune(expr.Operator[0])
+
+	case *ast.IntLiteral:
+		if expr.Value < 0 {
+			return '-'
+		}
+
+	case *ast.ArrayLiteral:
+		return '['
+	}
+
+	return 0
+}
+
+// Source position of the first token of expr
+func startOf(astExpr ast.Expression) token.Position {
+	switch expr := astExpr.(type) {
+	case *ast.InfixExpression:
+		return startOf(expr.Left)
+	case *ast.CallExpression:
+		return startOf(expr.Fn)
+	case *ast.IndexExpression:
+		return startOf(expr.Left)
+	}
+
+	return astExpr.Pos()
+}
+
+// Last source line of a statement, as far as the positions in its tree show
+func endLine(node ast.Node) int {
+	last := 0
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case nil:
+			return false
+		case *ast.StringLiteral:
+			last = max(last, node.Pos().Line+strings.Count(node.Value, "\n"))
+		case *ast.BlockStatement:
+			last = max(last, node.Pos().Line, node.Rbrace.Line)
+		case *ast.ArrayLiteral:
+			last = max(last, node.Pos().Line, node.Rbracket.Line)
+		case *ast.HashLiteral:
+			last = max(last, node.Pos().Line, node.Rbrace.Line)
+		case *ast.CallExpression:
+			last = max(last, node.Pos().Line, node.Rparen.Line)
+		default:
+			last = max(last, node.Pos().Line)
+		}
+
+		return true
+	})
+
+	return last
+}
@@ -0,0 +1,364 @@
+package printer
+
+import (
+	"fmt"
+	"math/rand"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let   x=1+2*3", "let x = 1 + 2 * 3;\n"},
+		{"(1 + 2) * 3; 1 + (2 * 3); 1 - (2 - 3); (1 - 2) - 3", "(1 + 2) * 3;\n1 + 2 * 3;\n1 - (2 - 3);\n1 - 2 - 3;\n"},
+		{"-(-x); !(a == b); (-a)[0]; -a[0]; (f)(1)(2)", "--x;\n!(a == b);\n(-a)[0];\n-a[0];\nf(1)(2);\n"},
+		{`puts("hi", [1,2], {"b": 1, "a": 2}, h.key, h["key"])`, "puts(\"hi\", [1, 2], {\"b\": 1, \"a\": 2}, h.key, h[\"key\"]);\n"},
+		{"let sq = fn(x) { x * x }", "let sq = fn(x) { x * x };\n"},
+		{"let f = fn() { let a = 1; return a }", "let f = fn() {\n    let a = 1;\n    return a;\n};\n"},
+		{"if (x) { 1 } else { 2 }", "if (x) { 1 } else { 2 }\n"},
+		{"if (x) { 1 }; (-2)", "if (x) { 1 };\n-2;\n"},
+		{"if (x) { 1 }; y", "if (x) { 1 }\ny;\n"},
+		{"try { throw 1 } catch (e) { e } finally { puts(1) }", "try { throw 1; } catch (e) { e } finally { puts(1) }\n"},
+		{"fn(){}", "fn() {}\n"},
+		{"let x:int=1; fn(a:[int],b)->{string:fn(int)->bool}{a}", "let x: int = 1;\nfn(a: [int], b) -> {string: fn(int) -> bool} { a }\n"},
+		{"fn(x) { fn(y) { x + y } }", "fn(x) { fn(y) { x + y } }\n"},
+		{
+			"let long = fn(x) { puts(\"a rather long string to print on a single line\", x, x, x, x) }",
+			"let long = fn(x) {\n    puts(\"a rather long string to print on a single line\", x, x, x, x)\n};\n",
+		},
+
+		// Blank lines are kept, but at most one
+		{"let a = 1;\n\n\n\nlet b = 2;\nlet c = 3;", "let a = 1;\n\nlet b = 2;\nlet c = 3;\n"},
+		{"let f = fn() {\n\n  1;\n\n  2\n\n}", "let f = fn() {\n    1;\n\n    2\n};\n"},
+
+		// Comments
+		{"// header\n\nlet a = 1; // one\n// before b\nlet b = 2;\n// the end", "// header\n\nlet a = 1; // one\n// before b\nlet b = 2;\n// the end\n"},
+		{"let f = fn(x) { // trailing brace\n  x // value\n  // last\n}", "let f = fn(x) { // trailing brace\n    x // value\n    // last\n};\n"},
+		{"let f = fn(x) {\n  // only a comment\n}", "let f = fn(x) {\n    // only a comment\n};\n"},
+
+		// Comments inside lists stay in place, breaking the list over lines
+		{"let h = {\n  \"a\": 1, // one\n  // before b\n  \"b\": 2\n};\nlet x = 1;", "let h = {\n    \"a\": 1, // one\n    // before b\n    \"b\": 2\n};\nlet x = 1;\n"},
+		{"f(1, // one\n  [2, // two\n  3]);\nf(// none\n)", "f(\n    1, // one\n    [\n        2, // two\n        3\n    ]\n);\nf( // none\n);\n"},
+		{"map(xs, fn(x) {\n  // double\n  x * 2\n})", "map(xs, fn(x) {\n    // double\n    x * 2\n});\n"},
+
+		// A statement ends at its closing bracket
+		{"let h = {\n  \"a\": 1\n};\nlet x = [\n  1\n];\nf(\n)", "let h = {\"a\": 1};\nlet x = [1];\nf();\n"},
+	}
+
+	for _, tt := range tests {
+		formatted, err := Format([]byte(tt.input))
+		if err != nil {
+			t.Errorf("Format(%q) failed: %v", tt.input, err)
+			continue
+		}
+
+		if string(formatted) != tt.expected {
+			t.Errorf("Wrong formatting of %q.\nGot=%q\nexpected=%q", tt.input, formatted, tt.expected)
+		}
+
+		again, err := Format(formatted)
+		if err != nil || string(again) != string(formatted) {
+			t.Errorf("Formatting %q again changed it. Got=%q (err=%v)", formatted, again, err)
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	if _, err := Format([]byte("let = 1")); err == nil {
+		t.Errorf("Expected an error for invalid source")
+	}
+}
+
+func TestFprintNode(t *testing.T) {
+	program := parse(t, "let add = fn(a, b) { a + b }; add(1, 2 * 3)")
+
+	var out strings.Builder
+	if err := Fprint(&out, program.Statements[1].(*ast.ExpressionStatement).Expr); err != nil {
+		t.Fatalf("Fprint failed: %v", err)
+	}
+
+	if out.String() != "add(1, 2 * 3)" {
+		t.Errorf("Wrong output. Got=%q", out.String())
+	}
+}
+
+// Formatting random programs must not change their trees or lose comments, and must be idempotent
+func TestFormatRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for idx := 0; idx < 500; idx++ {
+		src := genProgram(rng)
+		original := parse(t, src)
+
+		formatted, err := Format([]byte(src))
+		if err != nil {
+			t.Fatalf("Format(%q) failed: %v", src, err)
+		}
+
+		reparsed := parse(t, string(formatted))
+		if dump(original) != dump(reparsed) {
+			t.Fatalf("Formatting changed the tree of %q.\nformatted=%q\nGot=%s\nexpected=%s", src, formatted, dump(reparsed), dump(original))
+		}
+
+		if comments(original) != comments(reparsed) {
+			t.Fatalf("Formatting changed the comments of %q.\nGot=%q\nexpected=%q", src, comments(reparsed), comments(original))
+		}
+
+		again, _ := Format(formatted)
+		if string(again) != string(formatted) {
+			t.Fatalf("Formatting is not idempotent for %q.\nfirst=%q\nsecond=%q", src, formatted, again)
+		}
+	}
+}
+
+func parse(t *testing.T, src string) *ast.Program {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parser errors for %q: %v", src, p.Errors())
+	}
+
+	return program
+}
+
+func comments(program *ast.Program) string {
+	texts := make([]string, len(program.Comments))
+	for idx, comment := range program.Comments {
+		texts[idx] = comment.Text()
+	}
+
+	return strings.Join(texts, "\n")
+}
+
+// Fully parenthesized form of a tree, ignoring positions
+func dump(node ast.Node) string {
+	switch node := node.(type) {
+	case *ast.Program:
+		return dumpStatements(node.Statements)
+	case *ast.BlockStatement:
+		return "{" + dumpStatements(node.Statements) + "}"
+	case *ast.LetStatement:
+		return fmt.Sprintf("(let %s %s)", node.Name.Value, dump(node.Value))
+	case *ast.ReturnStatement:
+		return fmt.Sprintf("(return %s)", dump(node.Value))
+	case *ast.ThrowStatement:
+		return fmt.Sprintf("(throw %s)", dump(node.Value))
+	case *ast.ExpressionStatement:
+		return dump(node.Expr)
+	case *ast.Identifier:
+		return node.Value
+	case *ast.IntLiteral:
+		return fmt.Sprintf("%d", node.Value)
+	case *ast.BoolLiteral:
+		return fmt.Sprintf("%t", node.Value)
+	case *ast.StringLiteral:
+		return fmt.Sprintf("%q", node.Value)
+	case *ast.ArrayLiteral:
+		return "[" + dumpList(node.Elements) + "]"
+	case *ast.HashLiteral:
+		keys := make([]ast.Expression, 0, len(node.Pairs))
+		for key := range node.Pairs {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Pos().Offset < keys[j].Pos().Offset })
+
+		pairs := make([]string, len(keys))
+		for idx, key := range keys {
+			pairs[idx] = dump(key) + ":" + dump(node.Pairs[key])
+		}
+		return "{" + strings.Join(pairs, " ") + "}"
+	case *ast.IndexExpression:
+		return fmt.Sprintf("(%s %s %s)", node.Token.Literal, dump(node.Left), dump(node.Index))
+	case *ast.PrefixExpression:
+		return fmt.Sprintf("(%s %s)", node.Operator, dump(node.Operand))
+	case *ast.InfixExpression:
+		return fmt.Sprintf("(%s %s %s)", node.Operator, dump(node.Left), dump(node.Right))
+	case *ast.IfExpression:
+		alt := "nil"
+		if node.Alternative != nil {
+			alt = dump(node.Alternative)
+		}
+		return fmt.Sprintf("(if %s %s %s)", dump(node.Condition), dump(node.Consequence), alt)
+	case *ast.TryExpression:
+		catch, finally := "nil", "nil"
+		if node.Catch != nil {
+			catch = node.Param.Value + " " + dump(node.Catch)
+		}
+		if node.Finally != nil {
+			finally = dump(node.Finally)
+		}
+		return fmt.Sprintf("(try %s %s %s)", dump(node.Block), catch, finally)
+	case *ast.FnLiteral:
+		params := make([]string, len(node.Parameters))
+		for idx, param := range node.Parameters {
+			params[idx] = param.Value
+		}
+		return fmt.Sprintf("(fn (%s) %s)", strings.Join(params, " "), dump(node.Body))
+	case *ast.CallExpression:
+		return fmt.Sprintf("(call %s %s)", dump(node.Fn), dumpList(node.Args))
+	default:
+		return fmt.Sprintf("<%T>", node)
+	}
+}
+
+func dumpStatements(stmts []ast.Statement) string {
+	dumped := make([]string, len(stmts))
+	for idx, stmt := range stmts {
+		dumped[idx] = dump(stmt)
+	}
+
+	return strings.Join(dumped, "; ")
+}
+
+func dumpList(exprs []ast.Expression) string {
+	dumped := make([]string, len(exprs))
+	for idx, expr := range exprs {
+		dumped[idx] = dump(expr)
+	}
+
+	return strings.Join(dumped, " ")
+}
+
+/*** Random Programs ***/
+
+var genOperators = []string{"+", "-", "*", "/", "<", ">", "==", "!="}
+
+func genProgram(rng *rand.Rand) string {
+	var out strings.Builder
+	genStatements(rng, &out, 1+rng.Intn(5), 3)
+
+	return out.String()
+}
+
+// Statements separated by semicolons, with comments and blank lines in between
+func genStatements(rng *rand.Rand, out *strings.Builder, count int, depth int) {
+	for idx := 0; idx < count; idx++ {
+		switch rng.Intn(6) {
+		case 0:
+			out.WriteString("// comment " + fmt.Sprint(idx) + "\n")
+		case 1:
+			out.WriteString("\n\n")
+		}
+
+		genStatement(rng, out, depth)
+
+		switch rng.Intn(4) {
+		case 0:
+			out.WriteString("; // trailing\n")
+		case 1:
+			out.WriteString(";\n")
+		default:
+			out.WriteString("; ")
+		}
+	}
+}
+
+func genStatement(rng *rand.Rand, out *strings.Builder, depth int) {
+	switch rng.Intn(5) {
+	case 0:
+		out.WriteString("let " + []string{"a", "b", "c"}[rng.Intn(3)] + " = ")
+	case 1:
+		out.WriteString("return ")
+	case 2:
+		out.WriteString("throw ")
+	}
+
+	genExpression(rng, out, depth)
+}
+
+func genBlock(rng *rand.Rand, out *strings.Builder, depth int) {
+	out.WriteString("{")
+	genStatements(rng, out, rng.Intn(3), depth)
+	out.WriteString("}")
+}
+
+func genExpression(rng *rand.Rand, out *strings.Builder, depth int) {
+	choice := rng.Intn(16)
+	if depth <= 0 {
+		choice = rng.Intn(4)
+	}
+
+	switch choice {
+	case 0:
+		out.WriteString(fmt.Sprint(rng.Intn(100)))
+	case 1:
+		out.WriteString([]string{`"str"`, `""`, `"two words"`}[rng.Intn(3)])
+	case 2:
+		out.WriteString([]string{"true", "false"}[rng.Intn(2)])
+	case 3:
+		out.WriteString([]string{"x", "y", "f"}[rng.Intn(3)])
+	case 4, 5:
+		genExpression(rng, out, depth-1)
+		out.WriteString(" " + genOperators[rng.Intn(len(genOperators))] + " ")
+		genExpression(rng, out, depth-1)
+	case 6:
+		out.WriteString("(")
+		genExpression(rng, out, depth-1)
+		out.WriteString(")")
+	case 7:
+		out.WriteString([]string{"-", "!"}[rng.Intn(2)])
+		genExpression(rng, out, depth-1)
+	case 8:
+		genExpression(rng, out, depth-1)
+		out.WriteString("(")
+		genList(rng, out, depth-1)
+		out.WriteString(")")
+	case 9:
+		genExpression(rng, out, depth-1)
+		out.WriteString("[")
+		genExpression(rng, out, depth-1)
+		out.WriteString("]")
+	case 10:
+		genExpression(rng, out, depth-1)
+		out.WriteString(".member")
+	case 11:
+		out.WriteString("[")
+		genList(rng, out, depth-1)
+		out.WriteString("]")
+	case 12:
+		out.WriteString("{")
+		for idx, count := 0, rng.Intn(3); idx < count; idx++ {
+			fmt.Fprintf(out, `"k%d": `, idx)
+			genExpression(rng, out, depth-1)
+			out.WriteString(", ")
+		}
+		out.WriteString("}")
+	case 13:
+		out.WriteString("if (")
+		genExpression(rng, out, depth-1)
+		out.WriteString(") ")
+		genBlock(rng, out, depth-1)
+		if rng.Intn(2) == 0 {
+			out.WriteString(" else ")
+			genBlock(rng, out, depth-1)
+		}
+	case 14:
+		out.WriteString("fn(" + []string{"", "a", "a, b"}[rng.Intn(3)] + ") ")
+		genBlock(rng, out, depth-1)
+	case 15:
+		out.WriteString("try ")
+		genBlock(rng, out, depth-1)
+		out.WriteString(" catch (e) ")
+		genBlock(rng, out, depth-1)
+		if rng.Intn(2) == 0 {
+			out.WriteString(" finally ")
+			genBlock(rng, out, depth-1)
+		}
+	}
+}
+
+func genList(rng *rand.Rand, out *strings.Builder, depth int) {
+	for idx, count := 0, rng.Intn(4); idx < count; idx++ {
+		if idx > 0 {
+			out.WriteString(", ")
+		}
+		genExpression(rng, out, depth)
+	}
+}
@@ -2,60 +2,66 @@ package repl
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"monkey"
 	"monkey/evaluator"
-	"monkey/lexer"
-	"monkey/object"
-	"monkey/parser"
-	"os"
 )
 
 const PROMPT = ">>"
 
-func File(name string) {
-	content, err := os.ReadFile(name)
-	if err != nil {
-		panic(err)
-	}
+// Evaluate the named file, writing program output and the result (or errors) to out
+func File(name string, out io.Writer) {
+	interp := monkey.New(monkey.WithStdout(out))
 
-	l := lexer.New(string(content))
-	p := parser.New(l)
-	program := p.ParseProgram()
-	if p.ParserErrors() {
+	val, err := interp.EvalFile(name)
+	if err != nil {
+		printError(out, err)
 		return
 	}
 
-	env := object.NewEnvironment()
-	val := evaluator.Eval(program, env)
-	if val != nil {
-		fmt.Println(val.Inspect())
-	} else {
-		fmt.Println("Evaluated to nil")
-	}
+	fmt.Fprintln(out, val.Inspect())
 }
 
-func Start() {
-	scanner := bufio.NewScanner(os.Stdin)
-	env := object.NewEnvironment()
+// Read lines from in and evaluate them, writing prompts, program output and results to out
+func Start(in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	interp := monkey.New(monkey.WithStdout(out))
 
 	for {
-		fmt.Printf("%s ", PROMPT)
+		fmt.Fprintf(out, "%s ", PROMPT)
 		if ok := scanner.Scan(); !ok {
 			return
 		}
 
-		input := scanner.Text()
-
-		l := lexer.New(input)
-		p := parser.New(l)
+		val, err := interp.Eval(scanner.Text())
+		if err != nil {
+			printError(out, err)
+			continue
+		}
 
-		program := p.ParseProgram()
-		p.ParserErrors()
+		// Like statements without a value, null results are not echoed
+		if val != evaluator.NULL {
+			fmt.Fprintln(out, val.Inspect())
+		}
+	}
+}
 
-		eval := evaluator.Eval(program, env)
-		if eval != nil {
-			// fmt.Println(eval.Type())
-			fmt.Println(eval.Inspect())
+func printError(out io.Writer, err error) {
+	var parseErr *monkey.ParseError
+	if errors.As(err, &parseErr) {
+		for _, msg := range parseErr.Errors {
+			fmt.Fprintf(out, "Parser Error: %s\n", msg)
 		}
+		return
 	}
+
+	var runtimeErr *monkey.RuntimeError
+	if errors.As(err, &runtimeErr) {
+		fmt.Fprintln(out, runtimeErr.Err.Trace())
+		return
+	}
+
+	fmt.Fprintf(out, "ERROR: %s\n", err)
 }
@@ -0,0 +1,42 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	in := strings.NewReader("let x = 5;\nputs(x)\nx * 2\nlet = 1\n")
+	var out bytes.Buffer
+
+	Start(in, &out)
+
+	expected := ">> " + // let x = 5; has no value
+		">> 5\n" + // puts output, result is null
+		">> 10\n" +
+		">> Parser Error: expectPeek found unexpected peek, Got=Assign Expected=Identifier. Line 1, Column 5\n" +
+		"Parser Error: No prefix parser function found for token Assign. Line 1 Column 5\n" +
+		">> "
+
+	if out.String() != expected {
+		t.Errorf("Wrong REPL output.\nGot=%q\nexpected=%q", out.String(), expected)
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.monk")
+	os.WriteFile(path, []byte("puts(\"hi\");\nlet add = fn(a, b) { a + b };\nadd(1, true)"), 0o644)
+
+	var out bytes.Buffer
+	File(path, &out)
+
+	expected := "\"hi\"\nERROR: Infix expression type mismatch: INTEGER + BOOLEAN\n" +
+		"    at " + path + ":2:24\n" +
+		"    in add, called at " + path + ":3:4\n"
+	if out.String() != expected {
+		t.Errorf("Wrong output.\nGot=%q\nexpected=%q", out.String(), expected)
+	}
+}
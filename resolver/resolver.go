@@ -0,0 +1,212 @@
+// Package resolver binds the identifiers of a parsed program to the variables they refer to before evaluation
+package resolver
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/token"
+)
+
+// Identifier which does not refer to any variable, global or builtin
+type Error struct {
+	Name     string
+	Position token.Position
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Unknown Identifier %s. Line %d Column %d", e.Name, e.Position.Line, e.Position.Column)
+}
+
+// Variables of a function body or catch block, see DeclareLets
+type scope struct {
+	slots  map[string]int
+	locals []string // Name of each slot
+}
+
+func (s *scope) declare(name string) {
+	if _, ok := s.slots[name]; !ok {
+		s.slots[name] = len(s.locals)
+		s.locals = append(s.locals, name)
+	}
+}
+
+func (s *scope) declareLet(let *ast.LetStatement) {
+	s.declare(let.Name.Value)
+}
+
+type resolver struct {
+	scopes  []*scope // Innermost last, empty at the global scope
+	globals map[string]bool
+	defined func(name string) bool
+	errors  []*Error
+}
+
+// Assign each local variable an address of (depth, slot) and record the slots of each function and catch block.
+// Variables are scoped to their function or catch block as a whole, so they can be referenced before their let
+// as long as they are bound by the time the reference is evaluated.
+// Names which are not local are globals, which must be declared by a let at the top level of node or
+// already be defined (by an earlier program or as builtins) as reported by defined
+func Resolve(node ast.Node, defined func(name string) bool) []*Error {
+	r := &resolver{
+		globals: map[string]bool{},
+		defined: defined,
+	}
+
+	DeclareLets(node, func(let *ast.LetStatement) { r.globals[let.Name.Value] = true })
+	r.resolve(node)
+
+	return r.errors
+}
+
+func (r *resolver) resolve(astNode ast.Node) {
+	switch node := astNode.(type) {
+	case *ast.Program:
+		for _, stmt := range node.Statements {
+			r.resolve(stmt)
+		}
+
+	case *ast.BlockStatement:
+		for _, stmt := range node.Statements {
+			r.resolve(stmt)
+		}
+
+	case *ast.LetStatement:
+		r.resolveIdentifier(node.Name)
+		r.resolve(node.Value)
+
+	case *ast.ReturnStatement:
+		r.resolve(node.Value)
+
+	case *ast.ThrowStatement:
+		r.resolve(node.Value)
+
+	case *ast.ExpressionStatement:
+		r.resolve(node.Expr)
+
+	case *ast.Identifier:
+		r.resolveIdentifier(node)
+
+	case *ast.ArrayLiteral:
+		for _, elem := range node.Elements {
+			r.resolve(elem)
+		}
+
+	case *ast.HashLiteral:
+		for key, val := range node.Pairs {
+			r.resolve(key)
+			r.resolve(val)
+		}
+
+	case *ast.IndexExpression:
+		r.resolve(node.Left)
+		r.resolve(node.Index)
+
+	case *ast.PrefixExpression:
+		r.resolve(node.Operand)
+
+	case *ast.InfixExpression:
+		r.resolve(node.Left)
+		r.resolve(node.Right)
+
+	case *ast.IfExpression:
+		r.resolve(node.Condition)
+		r.resolve(node.Consequence)
+		if node.Alternative != nil {
+			r.resolve(node.Alternative)
+		}
+
+	case *ast.TryExpression:
+		r.resolve(node.Block)
+
+		if node.Catch != nil {
+			sc := r.push()
+			sc.declare(node.Param.Value)
+			DeclareLets(node.Catch, sc.declareLet)
+
+			r.resolveIdentifier(node.Param)
+			r.resolve(node.Catch)
+
+			node.CatchLocals = r.pop().locals
+		}
+
+		if node.Finally != nil {
+			r.resolve(node.Finally)
+		}
+
+	case *ast.FnLiteral:
+		sc := r.push()
+		for _, param := range node.Parameters {
+			// Every parameter gets a slot, a repeated name refers to the last one
+			sc.slots[param.Value] = len(sc.locals)
+			sc.locals = append(sc.locals, param.Value)
+		}
+		DeclareLets(node.Body, sc.declareLet)
+
+		for _, param := range node.Parameters {
+			param.Addr = &ast.Address{Depth: 0, Slot: sc.slots[param.Value]}
+		}
+		r.resolve(node.Body)
+
+		node.Locals = r.pop().locals
+
+	case *ast.CallExpression:
+		r.resolve(node.Fn)
+		for _, arg := range node.Args {
+			r.resolve(arg)
+		}
+	}
+}
+
+// Address the innermost variable named by ident, or leave it to be looked up by name as a global
+func (r *resolver) resolveIdentifier(ident *ast.Identifier) {
+	for depth := 0; depth < len(r.scopes); depth++ {
+		sc := r.scopes[len(r.scopes)-1-depth]
+		if slot, ok := sc.slots[ident.Value]; ok {
+			ident.Addr = &ast.Address{Depth: depth, Slot: slot}
+			return
+		}
+	}
+
+	ident.Addr = nil
+	if !r.globals[ident.Value] && !r.defined(ident.Value) {
+		r.errors = append(r.errors, &Error{Name: ident.Value, Position: ident.Pos()})
+	}
+}
+
+func (r *resolver) push() *scope {
+	sc := &scope{slots: map[string]int{}}
+	r.scopes = append(r.scopes, sc)
+
+	return sc
+}
+
+func (r *resolver) pop() *scope {
+	sc := r.scopes[len(r.scopes)-1]
+	r.scopes = r.scopes[:len(r.scopes)-1]
+
+	return sc
+}
+
+// Call declare with every let in the scope of node, in source order. A variable is scoped to the function body,
+// catch block or program declaring it as a whole, the blocks of if and try share the scope they are in.
+// Function literals and catch blocks have scopes of their own and are not descended into
+func DeclareLets(node ast.Node, declare func(let *ast.LetStatement)) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			declare(node)
+
+		case *ast.FnLiteral:
+			return false
+
+		case *ast.TryExpression:
+			DeclareLets(node.Block, declare)
+			if node.Finally != nil {
+				DeclareLets(node.Finally, declare)
+			}
+			return false
+		}
+
+		return true
+	})
+}
@@ -0,0 +1,160 @@
+package resolver
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	return program
+}
+
+func noGlobals(name string) bool { return false }
+
+// Collect the identifiers of node in source order
+func identifiers(node ast.Node) []*ast.Identifier {
+	var idents []*ast.Identifier
+
+	var walk func(n ast.Node)
+	walk = func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.Program:
+			for _, stmt := range n.Statements {
+				walk(stmt)
+			}
+		case *ast.BlockStatement:
+			for _, stmt := range n.Statements {
+				walk(stmt)
+			}
+		case *ast.LetStatement:
+			idents = append(idents, n.Name)
+			walk(n.Value)
+		case *ast.ExpressionStatement:
+			walk(n.Expr)
+		case *ast.Identifier:
+			idents = append(idents, n)
+		case *ast.InfixExpression:
+			walk(n.Left)
+			walk(n.Right)
+		case *ast.FnLiteral:
+			idents = append(idents, n.Parameters...)
+			walk(n.Body)
+		case *ast.CallExpression:
+			walk(n.Fn)
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		}
+	}
+	walk(node)
+
+	return idents
+}
+
+func TestResolveAddresses(t *testing.T) {
+	input := "let g = 1; let f = fn(a, b) { let c = a; fn(d) { a + c + d + g } }; f(1, 2)"
+	program := parse(t, input)
+
+	if errs := Resolve(program, noGlobals); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	// nil for globals
+	expected := []*ast.Address{
+		nil,                 // let g
+		nil,                 // let f
+		{Depth: 0, Slot: 0}, // a
+		{Depth: 0, Slot: 1}, // b
+		{Depth: 0, Slot: 2}, // let c
+		{Depth: 0, Slot: 0}, // = a
+		{Depth: 0, Slot: 0}, // d
+		{Depth: 1, Slot: 0}, // a
+		{Depth: 1, Slot: 2}, // c
+		{Depth: 0, Slot: 0}, // d
+		nil,                 // g
+		nil,                 // f
+	}
+
+	idents := identifiers(program)
+	if len(idents) != len(expected) {
+		t.Fatalf("Wrong number of identifiers. Got=%d, expected=%d", len(idents), len(expected))
+	}
+
+	for idx, ident := range idents {
+		addr := expected[idx]
+		if (addr == nil) != (ident.Addr == nil) || (addr != nil && *addr != *ident.Addr) {
+			t.Errorf("Wrong address for identifier %d %s. Got=%+v, expected=%+v", idx, ident.Value, ident.Addr, addr)
+		}
+	}
+
+	fn := program.Statements[1].(*ast.LetStatement).Value.(*ast.FnLiteral)
+	if len(fn.Locals) != 3 || fn.Locals[0] != "a" || fn.Locals[1] != "b" || fn.Locals[2] != "c" {
+		t.Errorf("Wrong locals. Got=%v", fn.Locals)
+	}
+}
+
+func TestResolveCatchScope(t *testing.T) {
+	program := parse(t, "try { let a = 1 } catch (e) { let b = e; a + b }")
+	if errs := Resolve(program, noGlobals); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	tryExpr := program.Statements[0].(*ast.ExpressionStatement).Expr.(*ast.TryExpression)
+	if len(tryExpr.CatchLocals) != 2 || tryExpr.CatchLocals[0] != "e" || tryExpr.CatchLocals[1] != "b" {
+		t.Errorf("Wrong catch locals. Got=%v", tryExpr.CatchLocals)
+	}
+
+	if tryExpr.Param.Addr == nil || *tryExpr.Param.Addr != (ast.Address{Depth: 0, Slot: 0}) {
+		t.Errorf("Wrong address for catch param. Got=%+v", tryExpr.Param.Addr)
+	}
+}
+
+func TestDeclareLets(t *testing.T) {
+	program := parse(t, `let a = 1; if (a) { let b = fn() { let c = 2 } }; try { let d = 3 } catch (e) { let f = 4 } finally { let g = 5 }; [{1: if (true) { let h = 6 }}]`)
+
+	var names []string
+	DeclareLets(program, func(let *ast.LetStatement) { names = append(names, let.Name.Value) })
+
+	if strings.Join(names, ",") != "a,b,d,g,h" {
+		t.Errorf("Wrong lets. Got=%v, expected=[a b d g h]", names)
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"x", []string{"Unknown Identifier x. Line 1 Column 1"}},
+		{"let f = fn() { y + puts(z) }", []string{"Unknown Identifier y. Line 1 Column 16", "Unknown Identifier z. Line 1 Column 25"}},
+		{"let f = fn() { later() }; let later = fn() { 1 }", nil},
+		{"if (true) { let a = 1 }; a", nil},
+		{"let f = fn() { if (true) { let a = 1 }; a }; a", []string{"Unknown Identifier a. Line 1 Column 46"}},
+		{"try { 1 } catch (e) { e }; e", []string{"Unknown Identifier e. Line 1 Column 28"}},
+		{"puts(1)", nil},
+	}
+
+	for _, tt := range tests {
+		errs := Resolve(parse(t, tt.input), func(name string) bool { return name == "puts" })
+
+		if len(errs) != len(tt.expected) {
+			t.Errorf("Wrong errors for %q. Got=%v, expected=%v", tt.input, errs, tt.expected)
+			continue
+		}
+
+		for idx, err := range errs {
+			if err.Error() != tt.expected[idx] {
+				t.Errorf("Wrong error for %q. Got=%q, expected=%q", tt.input, err.Error(), tt.expected[idx])
+			}
+		}
+	}
+}
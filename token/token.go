@@ -1,5 +1,7 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
 type Position struct {
@@ -9,6 +11,20 @@ type Position struct {
 	Offset   int
 }
 
+// Positions are only known for nodes produced by the lexer, lines start at 1
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// Position as file:line:column, or line:column when the source has no file name
+func (p Position) String() string {
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
 type Token struct {
 	Type     TokenType
 	Literal  string
@@ -26,6 +42,7 @@ const (
 
 	EQUALITY = "Equality" // ==
 	NOTEQUAL = "NotEqual" // !=
+	ARROW    = "Arrow"    // ->
 
 	// Single byte tokens
 
@@ -46,6 +63,7 @@ const (
 	SEMICOLON = "Semicolon"     // ;
 	COLON     = "Colon"         // :
 	COMMA     = "Comma"         // ,
+	DOT       = "Dot"           // .
 
 	// Keywords
 
@@ -56,20 +74,29 @@ const (
 	FUNCTION = "Function"
 	TRUE     = "True"
 	FALSE    = "False"
+	TRY      = "Try"
+	CATCH    = "Catch"
+	FINALLY  = "Finally"
+	THROW    = "Throw"
 
+	COMMENT = "Comment" // Line comment, starting with // and running to the end of the line
 	ILLEGAL = "Illegal"
 	EOF     = "EOF"
 )
 
 // Map the language keywords, to their corresponding TokenType
 var keywords = map[string]TokenType{
-	"if":     IF,
-	"let":    LET,
-	"else":   ELSE,
-	"true":   TRUE,
-	"false":  FALSE,
-	"return": RETURN,
-	"fn":     FUNCTION,
+	"if":      IF,
+	"let":     LET,
+	"else":    ELSE,
+	"true":    TRUE,
+	"false":   FALSE,
+	"return":  RETURN,
+	"fn":      FUNCTION,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"throw":   THROW,
 }
 
 // Determine if the given identifier is a language keyword
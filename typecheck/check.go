@@ -0,0 +1,831 @@
+// Package typecheck infers the types of a parsed program and reports the mismatches which would fail at runtime.
+// Inference is Hindley-Milner style with let polymorphism for functions and other syntactic values, functions being
+// checked ahead of the code referring to them. Spots where Monkey allows values of different types, like the
+// branches of an if, get a union type, which is compatible with each of its members
+package typecheck
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/resolver"
+	"monkey/token"
+)
+
+// Mismatch found by the checker
+type Error struct {
+	Position token.Position
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s. Line %d Column %d", e.Message, e.Position.Line, e.Position.Column)
+}
+
+// Types inferred for a program
+type Info struct {
+	Types map[ast.Node]Type // Type of each expression and of each name declared by a let, parameter or catch clause
+}
+
+// Inferred type of node, nil when it was not checked
+func (i *Info) TypeOf(node ast.Node) Type {
+	return i.Types[node]
+}
+
+// Type schemes of the variables of a scope, see resolver.DeclareLets
+type scope struct {
+	parent  *scope
+	schemes map[string]*scheme
+	lets    map[string]int // Number of lets of each name checked so far
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{parent: parent, schemes: map[string]*scheme{}, lets: map[string]int{}}
+}
+
+type checker struct {
+	builtins *evaluator.Registry
+	known    map[string]bool // Registry.Defined of builtins
+
+	scope  *scope
+	result *Type                      // Result of the function being checked, joined with the value of each return
+	early  map[*ast.LetStatement]bool // Lets of functions checked ahead of the statements around them
+
+	nextVar int
+	trail   []trailEntry
+	info    *Info
+	errors  []*Error
+}
+
+// Infer the types of program, knowing the builtins of the registry, which may be nil.
+// Identifiers which are not defined are left to the resolver and have type any
+func Check(program *ast.Program, builtins *evaluator.Registry) (*Info, []*Error) {
+	c := &checker{
+		builtins: builtins,
+		known:    builtins.Defined(),
+		scope:    newScope(nil),
+		early:    map[*ast.LetStatement]bool{},
+		info:     &Info{Types: map[ast.Node]Type{}},
+	}
+
+	c.declareLets(program, c.scope)
+	c.functions(program.Statements)
+	c.statements(program.Statements)
+
+	return c.info, c.errors
+}
+
+func (c *checker) errorf(pos token.Position, format string, args ...any) {
+	c.errors = append(c.errors, &Error{Position: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) fresh() *Var {
+	c.nextVar++
+	return &Var{id: c.nextVar}
+}
+
+/*** Scopes ***/
+
+// Declare every let in the scope of node with a variable of its own, so that it can be referenced before the let
+func (c *checker) declareLets(node ast.Node, sc *scope) {
+	resolver.DeclareLets(node, func(let *ast.LetStatement) {
+		if _, ok := sc.schemes[let.Name.Value]; ok {
+			// A let of a parameter rebinds it rather than refining its type
+			sc.lets[let.Name.Value] = max(sc.lets[let.Name.Value], 1)
+			return
+		}
+		sc.schemes[let.Name.Value] = &scheme{t: c.fresh()}
+	})
+}
+
+func (c *checker) lookup(name string) (*scheme, bool) {
+	for sc := c.scope; sc != nil; sc = sc.parent {
+		if s, ok := sc.schemes[name]; ok {
+			return s, true
+		}
+	}
+
+	return nil, false
+}
+
+// Copy the type of s with fresh variables in place of its generalized ones
+func (c *checker) instantiate(s *scheme) Type {
+	if len(s.vars) == 0 {
+		return s.t
+	}
+
+	subst := map[*Var]Type{}
+	for _, v := range s.vars {
+		copied := c.fresh()
+		copied.addable, copied.equatable = v.addable, v.equatable
+		subst[v] = copied
+	}
+
+	return substitute(s.t, subst)
+}
+
+func substitute(t Type, subst map[*Var]Type) Type {
+	switch t := resolve(t).(type) {
+	case *Var:
+		if replaced, ok := subst[t]; ok {
+			return replaced
+		}
+		return t
+
+	case *Array:
+		return &Array{Elem: substitute(t.Elem, subst)}
+
+	case *Hash:
+		return &Hash{Key: substitute(t.Key, subst), Value: substitute(t.Value, subst)}
+
+	case *Function:
+		params := make([]Type, len(t.Params))
+		for idx, param := range t.Params {
+			params[idx] = substitute(param, subst)
+		}
+		return &Function{Params: params, Result: substitute(t.Result, subst)}
+
+	case *Union:
+		members := make([]Type, len(t.Types))
+		for idx, member := range t.Types {
+			members[idx] = substitute(member, subst)
+		}
+		return newUnion(members...)
+
+	default:
+		return t
+	}
+}
+
+// Scheme of t generalized over the variables which are not free in the enclosing scopes
+func (c *checker) generalize(t Type) *scheme {
+	bound := map[*Var]bool{}
+	for sc := c.scope; sc != nil; sc = sc.parent {
+		for _, s := range sc.schemes {
+			generalized := map[*Var]bool{}
+			for _, v := range s.vars {
+				generalized[v] = true
+			}
+
+			for _, v := range freeVars(s.t, nil) {
+				if !generalized[v] {
+					bound[v] = true
+				}
+			}
+		}
+	}
+
+	s := &scheme{t: t}
+	for _, v := range freeVars(t, nil) {
+		if !bound[v] {
+			s.vars = append(s.vars, v)
+		}
+	}
+
+	return s
+}
+
+// Unbound variables within t, appended to found in order of appearance
+func freeVars(t Type, found []*Var) []*Var {
+	switch t := resolve(t).(type) {
+	case *Var:
+		for _, v := range found {
+			if v == t {
+				return found
+			}
+		}
+		return append(found, t)
+
+	case *Array:
+		return freeVars(t.Elem, found)
+
+	case *Hash:
+		return freeVars(t.Value, freeVars(t.Key, found))
+
+	case *Function:
+		for _, param := range t.Params {
+			found = freeVars(param, found)
+		}
+		return freeVars(t.Result, found)
+
+	case *Union:
+		for _, member := range t.Types {
+			found = freeVars(member, found)
+		}
+	}
+
+	return found
+}
+
+/*** Statements ***/
+
+// Type of the value of a list of statements, never when they return or throw before their end
+func (c *checker) statements(stmts []ast.Statement) Type {
+	var result Type = Null
+	completes := true
+
+	for _, stmt := range stmts {
+		result = c.statement(stmt)
+		if result == Never {
+			completes = false
+		}
+	}
+
+	if !completes {
+		return Never
+	}
+
+	return result
+}
+
+func (c *checker) statement(astStmt ast.Statement) Type {
+	switch stmt := astStmt.(type) {
+	case *ast.LetStatement:
+		c.let(stmt)
+		return Null
+
+	case *ast.ReturnStatement:
+		t := c.expression(stmt.Value)
+		if c.result != nil {
+			*c.result = c.join(*c.result, t)
+		}
+		return Never
+
+	case *ast.ThrowStatement:
+		c.expression(stmt.Value)
+		return Never
+
+	case *ast.ExpressionStatement:
+		return c.expression(stmt.Expr)
+	}
+
+	return Any
+}
+
+func (c *checker) let(let *ast.LetStatement) {
+	if !c.early[let] {
+		c.letGroup([]*ast.LetStatement{let})
+	}
+}
+
+// Check the lets of a group whose values may refer to each other, then generalize them together
+func (c *checker) letGroup(group []*ast.LetStatement) {
+	types := make([]Type, len(group))
+	for idx, let := range group {
+		types[idx] = c.letValue(let)
+	}
+
+	// Only syntactic values are generalized, others may hold variables which are later refined
+	for _, let := range group {
+		delete(c.scope.schemes, let.Name.Value)
+	}
+	for idx, let := range group {
+		if generalizable(let.Value) {
+			c.scope.schemes[let.Name.Value] = c.generalize(types[idx])
+		} else {
+			c.scope.schemes[let.Name.Value] = &scheme{t: types[idx]}
+		}
+		c.info.Types[let.Name] = types[idx]
+	}
+}
+
+// Type of the value of let, unified with the variable declared for its name
+func (c *checker) letValue(let *ast.LetStatement) Type {
+	name := let.Name.Value
+	_, isFn := let.Value.(*ast.FnLiteral)
+
+	// The first let of a name refines the variable declared for it, which earlier references may have constrained.
+	// Later lets rebind the name, a function is bound before its body is checked so that it can call itself
+	var declared Type
+	if c.scope.lets[name] == 0 {
+		declared = c.scope.schemes[name].t
+	} else if isFn {
+		declared = c.fresh()
+		c.scope.schemes[name] = &scheme{t: declared}
+	}
+	c.scope.lets[name]++
+
+	t := c.expression(let.Value)
+	if let.Type != nil {
+		annotated := c.annotation(let.Type)
+		if !c.unify(annotated, t) {
+			c.errorf(let.Type.Pos(), "Type mismatch for %s, Got=%s, expected=%s", name, t, annotated)
+		}
+		t = annotated
+	}
+
+	if declared != nil && !c.unify(declared, t) {
+		c.errorf(let.Name.Pos(), "Type mismatch for %s, Got=%s, expected=%s", name, t, declared)
+	}
+
+	return t
+}
+
+// Whether evaluating expr only builds a value, whose type can then be generalized
+func generalizable(astExpr ast.Expression) bool {
+	switch expr := astExpr.(type) {
+	case *ast.FnLiteral, *ast.Identifier, *ast.IntLiteral, *ast.StringLiteral, *ast.BoolLiteral:
+		return true
+
+	case *ast.ArrayLiteral:
+		for _, elem := range expr.Elements {
+			if !generalizable(elem) {
+				return false
+			}
+		}
+		return true
+
+	case *ast.HashLiteral:
+		for key, val := range expr.Pairs {
+			if !generalizable(key) || !generalizable(val) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// Check the functions bound once by a let among stmts ahead of the statements, callees first, so that a reference
+// before the let sees a generalized function. Functions which refer to each other are checked as one group
+func (c *checker) functions(stmts []ast.Statement) {
+	count := map[string]int{}
+	resolver.DeclareLets(&ast.BlockStatement{Statements: stmts}, func(let *ast.LetStatement) { count[let.Name.Value]++ })
+
+	fns := map[string]*ast.LetStatement{}
+	var order []string
+	for _, stmt := range stmts {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || count[let.Name.Value] > 1 || c.scope.lets[let.Name.Value] > 0 {
+			continue
+		}
+		if _, isFn := let.Value.(*ast.FnLiteral); !isFn {
+			continue
+		}
+		fns[let.Name.Value] = let
+		order = append(order, let.Name.Value)
+	}
+
+	refs := map[string][]string{}
+	for _, name := range order {
+		ast.Inspect(fns[name].Value, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Identifier); ok && fns[ident.Value] != nil {
+				refs[name] = append(refs[name], ident.Value)
+			}
+			return true
+		})
+	}
+
+	for _, names := range components(order, refs) {
+		group := make([]*ast.LetStatement, len(names))
+		for idx, name := range names {
+			group[idx] = fns[name]
+			c.early[fns[name]] = true
+		}
+		c.letGroup(group)
+	}
+}
+
+// Strongly connected components of the graph of names with edges refs, each after those it refers to
+func components(names []string, refs map[string][]string) [][]string {
+	index := map[string]int{}
+	low := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	var result [][]string
+
+	var visit func(name string)
+	visit = func(name string) {
+		index[name], low[name] = len(index), len(index)
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, ref := range refs[name] {
+			if _, seen := index[ref]; !seen {
+				visit(ref)
+				low[name] = min(low[name], low[ref])
+			} else if onStack[ref] {
+				low[name] = min(low[name], index[ref])
+			}
+		}
+
+		if low[name] == index[name] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == name {
+					break
+				}
+			}
+			result = append(result, component)
+		}
+	}
+
+	for _, name := range names {
+		if _, seen := index[name]; !seen {
+			visit(name)
+		}
+	}
+
+	return result
+}
+
+/*** Expressions ***/
+
+func (c *checker) expression(expr ast.Expression) Type {
+	t := c.infer(expr)
+	c.info.Types[expr] = t
+
+	return t
+}
+
+func (c *checker) infer(astExpr ast.Expression) Type {
+	switch expr := astExpr.(type) {
+	case *ast.IntLiteral:
+		return Int
+	case *ast.StringLiteral:
+		return String
+	case *ast.BoolLiteral:
+		return Bool
+
+	case *ast.Identifier:
+		if s, ok := c.lookup(expr.Value); ok {
+			return c.instantiate(s)
+		}
+		return Any
+
+	case *ast.ArrayLiteral:
+		var elem Type = Never
+		for _, element := range expr.Elements {
+			elem = c.join(elem, c.expression(element))
+		}
+
+		if elem == Never {
+			elem = c.fresh()
+		}
+		return &Array{Elem: elem}
+
+	case *ast.HashLiteral:
+		return c.hashLiteral(expr)
+
+	case *ast.IndexExpression:
+		return c.index(expr)
+
+	case *ast.PrefixExpression:
+		operand := c.expression(expr.Operand)
+		if expr.Operator == "-" && !c.unify(operand, Int) {
+			c.errorf(expr.Pos(), "Invalid operand to -, Got=%s, expected=int", operand)
+			return Any
+		}
+
+		if expr.Operator == "!" {
+			return Bool
+		}
+		return Int
+
+	case *ast.InfixExpression:
+		return c.infix(expr)
+
+	case *ast.IfExpression:
+		c.expression(expr.Condition)
+
+		consequence := c.statements(expr.Consequence.Statements)
+		alternative := Type(Null)
+		if expr.Alternative != nil {
+			alternative = c.statements(expr.Alternative.Statements)
+		}
+		return c.join(consequence, alternative)
+
+	case *ast.TryExpression:
+		return c.try(expr)
+
+	case *ast.FnLiteral:
+		return c.fnLiteral(expr)
+
+	case *ast.CallExpression:
+		return c.call(expr)
+	}
+
+	return Any
+}
+
+func (c *checker) hashLiteral(hash *ast.HashLiteral) Type {
+	var key, value Type = Never, Never
+
+	for _, keyExpr := range hash.Keys() {
+		keyType := c.expression(keyExpr)
+		switch resolve(keyType).(type) {
+		case *Array, *Hash, *Function:
+			c.errorf(keyExpr.Pos(), "Key is not HashAble, Got=%s", keyType)
+		}
+
+		key = c.join(key, keyType)
+		value = c.join(value, c.expression(hash.Pairs[keyExpr]))
+	}
+
+	if key == Never {
+		key, value = c.fresh(), c.fresh()
+	}
+
+	return &Hash{Key: key, Value: value}
+}
+
+func (c *checker) index(index *ast.IndexExpression) Type {
+	member, isMember := index.Index.(*ast.StringLiteral)
+	isMember = isMember && index.Token.Type == token.DOT
+
+	if isMember {
+		if _, ok := c.builtin(index); ok {
+			return Any
+		}
+	}
+
+	left := c.expression(index.Left)
+	key := c.expression(index.Index)
+
+	switch collection := resolve(left).(type) {
+	case *Array:
+		if isMember {
+			c.errorf(index.Index.Pos(), "Array has no member %s", member.Value)
+		} else if !c.unify(key, Int) {
+			c.errorf(index.Index.Pos(), "Index is not an int, Got=%s", key)
+		}
+		return collection.Elem
+
+	case *Hash:
+		if !c.unify(key, collection.Key) {
+			c.errorf(index.Index.Pos(), "Invalid key for %s, Got=%s", collection, key)
+		}
+		return collection.Value
+
+	case *Var:
+		// Only a hash has members, an int index could be of an array or a hash
+		if resolve(key) == String {
+			value := c.fresh()
+			if c.unify(collection, &Hash{Key: String, Value: value}) {
+				return value
+			}
+		}
+		return Any
+
+	case *Union:
+		return Any
+	}
+
+	if left != Any {
+		c.errorf(index.Pos(), "Only Array/Hash/Module is index-able, Got=%s", left)
+	}
+	return Any
+}
+
+func (c *checker) infix(infix *ast.InfixExpression) Type {
+	left := c.expression(infix.Left)
+	right := c.expression(infix.Right)
+
+	mismatch := func() Type {
+		c.errorf(infix.Pos(), "Invalid operands to %s, Got=%s %s %s", infix.Operator, left, infix.Operator, right)
+		return Any
+	}
+
+	switch infix.Operator {
+	case "+":
+		if !c.unify(left, right) || !c.addable(left) {
+			return mismatch()
+		}
+		return left
+
+	case "-", "*", "/":
+		if !c.unify(left, Int) || !c.unify(right, Int) {
+			return mismatch()
+		}
+		return Int
+
+	case "<", ">":
+		if !c.unify(left, Int) || !c.unify(right, Int) {
+			return mismatch()
+		}
+		return Bool
+
+	case "==", "!=":
+		if !c.unify(left, right) || !c.equatable(left) {
+			return mismatch()
+		}
+		return Bool
+	}
+
+	return Any
+}
+
+func (c *checker) try(try *ast.TryExpression) Type {
+	result := c.statements(try.Block.Statements)
+
+	if try.Catch != nil {
+		outer := c.scope
+		c.scope = newScope(outer)
+		c.scope.schemes[try.Param.Value] = &scheme{t: Any}
+		c.scope.lets[try.Param.Value] = 1
+		c.info.Types[try.Param] = Any
+		c.declareLets(try.Catch, c.scope)
+		c.functions(try.Catch.Statements)
+
+		result = c.join(result, c.statements(try.Catch.Statements))
+		c.scope = outer
+	}
+
+	if try.Finally != nil {
+		c.statements(try.Finally.Statements)
+	}
+
+	return result
+}
+
+func (c *checker) fnLiteral(fn *ast.FnLiteral) Type {
+	outer, outerResult := c.scope, c.result
+	defer func() { c.scope, c.result = outer, outerResult }()
+
+	c.scope = newScope(outer)
+	params := make([]Type, len(fn.Parameters))
+	for idx, param := range fn.Parameters {
+		params[idx] = c.fresh()
+		if param.Type != nil {
+			params[idx] = c.annotation(param.Type)
+		}
+		c.scope.schemes[param.Value] = &scheme{t: params[idx]}
+		c.info.Types[param] = params[idx]
+	}
+	c.declareLets(fn.Body, c.scope)
+
+	var result Type = Never
+	c.result = &result
+	c.functions(fn.Body.Statements)
+	result = c.join(result, c.statements(fn.Body.Statements))
+
+	if fn.Result != nil {
+		annotated := c.annotation(fn.Result)
+		if !c.unify(annotated, result) {
+			c.errorf(fn.Result.Pos(), "Type mismatch for result of function, Got=%s, expected=%s", result, annotated)
+		}
+		result = annotated
+	}
+
+	return &Function{Params: params, Result: result}
+}
+
+// Type declared by an annotation, a function type without a result returns any
+func (c *checker) annotation(ta *ast.TypeAnnotation) Type {
+	switch ta.Token.Type {
+	case token.LBRACKET:
+		return &Array{Elem: c.annotation(ta.Elem)}
+
+	case token.LBRACE:
+		return &Hash{Key: c.annotation(ta.Key), Value: c.annotation(ta.Value)}
+
+	case token.FUNCTION:
+		params := make([]Type, len(ta.Params))
+		for idx, param := range ta.Params {
+			params[idx] = c.annotation(param)
+		}
+
+		var result Type = Any
+		if ta.Result != nil {
+			result = c.annotation(ta.Result)
+		}
+		return &Function{Params: params, Result: result}
+	}
+
+	switch ta.Name {
+	case "int":
+		return Int
+	case "bool":
+		return Bool
+	case "string":
+		return String
+	case "null":
+		return Null
+	}
+
+	return Any
+}
+
+func (c *checker) call(call *ast.CallExpression) Type {
+	if name, ok := c.builtin(call.Fn); ok {
+		return c.builtinCall(call, name)
+	}
+
+	fn := c.expression(call.Fn)
+	args := make([]Type, len(call.Args))
+	for idx, arg := range call.Args {
+		args[idx] = c.expression(arg)
+	}
+
+	name := "function"
+	if ident, ok := call.Fn.(*ast.Identifier); ok {
+		name = ident.Value
+	}
+
+	switch callee := resolve(fn).(type) {
+	case *Function:
+		if len(callee.Params) != len(args) {
+			c.errorf(call.Pos(), "Invalid number of args to %s, Got=%d, expected=%d", name, len(args), len(callee.Params))
+			return Any
+		}
+
+		for idx, arg := range args {
+			if !c.unify(callee.Params[idx], arg) {
+				c.errorf(call.Args[idx].Pos(), "Type mismatch for argument %d of %s, Got=%s, expected=%s", idx+1, name, arg, callee.Params[idx])
+			}
+		}
+		return callee.Result
+
+	case *Var:
+		result := c.fresh()
+		if !c.unify(callee, &Function{Params: args, Result: result}) {
+			c.errorf(call.Pos(), "Cannot call %s with %d args, Got=%s", name, len(args), fn)
+			return Any
+		}
+		return result
+
+	case *Union:
+		return Any
+	}
+
+	if fn != Any {
+		c.errorf(call.Pos(), "Cannot call %s, Got=%s", name, fn)
+	}
+	return Any
+}
+
+// Check the args of a call of a builtin against its params. The results of builtins are not typed
+func (c *checker) builtinCall(call *ast.CallExpression, name string) Type {
+	args := make([]Type, len(call.Args))
+	for idx, arg := range call.Args {
+		args[idx] = c.expression(arg)
+	}
+
+	builtin, ok := c.builtins.Lookup(name)
+	if !ok {
+		return Any
+	}
+
+	least := len(builtin.Params) - builtin.Optional
+	if builtin.Variadic {
+		least = min(least, len(builtin.Params)-1)
+	}
+
+	if len(args) < least || !builtin.Variadic && len(args) > len(builtin.Params) {
+		c.errorf(call.Pos(), "Invalid number of args to %s, Got=%d, expected=%s", name, len(args), builtin.Signature())
+		return Any
+	}
+
+	for idx, arg := range args {
+		param := builtin.Params[min(idx, len(builtin.Params)-1)]
+		if expected := c.objectType(param.Type); !c.unify(expected, arg) {
+			c.errorf(call.Args[idx].Pos(), "Type mismatch for argument %d of %s, Got=%s, expected=%s", idx+1, name, arg, expected)
+		}
+	}
+
+	return Any
+}
+
+// Type of the values of an ObjectType, any for those without a static type
+func (c *checker) objectType(objType object.ObjectType) Type {
+	switch objType {
+	case object.INTEGER_OBJ:
+		return Int
+	case object.BOOLEAN_OBJ:
+		return Bool
+	case object.STRING_OBJ:
+		return String
+	case object.ARRAY_OBJ:
+		return &Array{Elem: c.fresh()}
+	case object.HASH_OBJ:
+		return &Hash{Key: c.fresh(), Value: c.fresh()}
+	}
+
+	return Any
+}
+
+// Qualified name of the builtin expr refers to, if it is an unshadowed builtin or a member of a builtin module
+func (c *checker) builtin(astExpr ast.Expression) (string, bool) {
+	switch expr := astExpr.(type) {
+	case *ast.Identifier:
+		_, bound := c.lookup(expr.Value)
+		return expr.Value, !bound && c.known[expr.Value]
+
+	case *ast.IndexExpression:
+		member, ok := expr.Index.(*ast.StringLiteral)
+		if !ok || expr.Token.Type != token.DOT {
+			return "", false
+		}
+
+		module, ok := c.builtin(expr.Left)
+		return module + "." + member.Value, ok && c.known[module+"."+member.Value]
+	}
+
+	return "", false
+}
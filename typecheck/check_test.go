@@ -0,0 +1,159 @@
+package typecheck
+
+import (
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/parser"
+	"strings"
+	"testing"
+)
+
+func check(t *testing.T, src string) (*ast.Program, *Info, []*Error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parser errors for %q: %v", src, p.Errors())
+	}
+
+	info, errs := Check(program, evaluator.DefaultRegistry(evaluator.Config{}))
+	return program, info, errs
+}
+
+func TestInfer(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Type of the last statement
+	}{
+		{`1`, "int"},
+		{`"a" + "b"`, "string"},
+		{`!5`, "bool"},
+		{`1 < 2`, "bool"},
+		{`[1, 2]`, "[int]"},
+		{`[1, "a"]`, "[int | string]"},
+		{`{"a": 1}`, "{string: int}"},
+		{`{"a": 1}["a"]`, "int"},
+		{`{"a": [1]}.a`, "[int]"},
+		{`[[1]][0]`, "[int]"},
+		{`if (true) { 1 }`, "int | null"},
+		{`if (true) { 1 } else { 2 }`, "int"},
+		{`if (true) { 1 } else { "a" }`, "int | string"},
+		{`try { 1 } catch (e) { "a" }`, "int | string"},
+		{`let x = 5; x`, "int"},
+		{`fn(x) { x + 1 }`, "fn(int) -> int"},
+		{`fn(x, y) { x == y }`, "fn(t2, t2) -> bool"},
+		{`fn(h) { h.name }`, "fn({string: t2}) -> t2"},
+		{`fn() { return 1; }`, "fn() -> int"},
+		{`fn(x) { if (x) { return 1 }; "a" }`, "fn(t1) -> int | string"},
+		{`fn() { throw "no" }`, "fn() -> never"},
+		{`let id = fn(x) { x }; [id(1), id(2)]`, "[int]"},
+		{`let id = fn(x) { x }; id("a")`, "string"},
+		{`let add = fn(a, b) { a + b }; add("a", "b")`, "string"},
+		{`let fact = fn(n) { if (n == 0) { 1 } else { n * fact(n - 1) } }; fact`, "fn(int) -> int"},
+		{`let compose = fn(f, g) { fn(x) { g(f(x)) } }; compose(fn(x) { x * 2 }, fn(x) { x < 5 })`, "fn(int) -> bool"},
+		{`let f = fn() { g() }; let g = fn() { "a" }; f()`, "string"},
+		{`let a = fn() { [id(1), id("s")] }; let id = fn(x) { x }; a()`, "[int | string]"},
+		{`let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; odd`, "fn(int) -> bool"},
+		{`let f = fn(x) { x }; let g = f; [g(1), g("a")]`, "[int | string]"},
+		{`let xs = []; [xs, [1]]`, "[[int]]"},
+		{`let adder = fn(x) { fn(y) { x + y } }; adder(1)`, "fn(int) -> int"},
+		{`len("abc")`, "any"},
+		{`let x = if (true) { 1 } else { "a" }; x - 1`, "int"},
+
+		// Annotations
+		{`let x: any = 1; x`, "any"},
+		{`let xs: [int] = []; xs`, "[int]"},
+		{`fn(a: string, b) { b }`, "fn(string, t2) -> t2"},
+		{`fn(h: {string: int}) -> bool { h.a > 0 }`, "fn({string: int}) -> bool"},
+		{`fn(f: fn(int)) { f }`, "fn(fn(int) -> any) -> fn(int) -> any"},
+		{`let id = fn(x) -> int { x }; id`, "fn(int) -> int"},
+	}
+
+	for _, tt := range tests {
+		program, info, errs := check(t, tt.input)
+		if len(errs) > 0 {
+			t.Errorf("Unexpected errors for %q: %v", tt.input, errs)
+			continue
+		}
+
+		var last ast.Node = program.Statements[len(program.Statements)-1]
+		if stmt, ok := last.(*ast.ExpressionStatement); ok {
+			last = stmt.Expr
+		}
+
+		if got := info.TypeOf(last); got == nil || got.String() != tt.expected {
+			t.Errorf("Wrong type of %q. Got=%v, expected=%s", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`1 + "a"`, []string{"Invalid operands to +, Got=int + string. Line 1 Column 3"}},
+		{`true + true`, []string{"Invalid operands to +, Got=bool + bool. Line 1 Column 6"}},
+		{`"a" - "b"`, []string{"Invalid operands to -, Got=string - string. Line 1 Column 5"}},
+		{`"a" == "b"`, []string{"Invalid operands to ==, Got=string == string. Line 1 Column 5"}},
+		{`1 < true`, []string{"Invalid operands to <, Got=int < bool. Line 1 Column 3"}},
+		{`-"a"`, []string{"Invalid operand to -, Got=string, expected=int. Line 1 Column 1"}},
+		{`[1][true]`, []string{"Index is not an int, Got=bool. Line 1 Column 5"}},
+		{`{"a": 1}[1]`, []string{"Invalid key for {string: int}, Got=int. Line 1 Column 10"}},
+		{`[1].length`, []string{"Array has no member length. Line 1 Column 5"}},
+		{`5[0]`, []string{"Only Array/Hash/Module is index-able, Got=int. Line 1 Column 2"}},
+		{`{[1]: 2}`, []string{"Key is not HashAble, Got=[int]. Line 1 Column 2"}},
+		{`let f = fn(x) { x * 2 }; f("a")`, []string{"Type mismatch for argument 1 of f, Got=string, expected=int. Line 1 Column 28"}},
+		{`let f = fn(x) { x }; f(1, 2)`, []string{"Invalid number of args to f, Got=2, expected=1. Line 1 Column 23"}},
+		{`let x = 1; x(2)`, []string{"Cannot call x, Got=int. Line 1 Column 13"}},
+		{`fn(f) { f(1) + f("a") }`, []string{"Type mismatch for argument 1 of f, Got=string, expected=int. Line 1 Column 18"}},
+		{`let f = fn() { g(1) }; let g = fn(x) { x + "a" }`, []string{"Type mismatch for argument 1 of g, Got=int, expected=string. Line 1 Column 18"}},
+		{`let a = fn() { b(1) }; let b = fn(x) { if (x) { a() } else { x + "a" } }`, []string{"Type mismatch for argument 1 of b, Got=int, expected=string. Line 1 Column 18"}},
+		{`math.abs("x")`, []string{"Type mismatch for argument 1 of math.abs, Got=string, expected=int. Line 1 Column 10"}},
+		{`len(1, 2)`, []string{"Invalid number of args to len, Got=2, expected=len(value). Line 1 Column 4"}},
+		{`let x = if (true) { 1 } else { "a" }; x < true`, []string{"Invalid operands to <, Got=int | string < bool. Line 1 Column 41"}},
+
+		// Annotations
+		{`let x: int = "a"`, []string{"Type mismatch for x, Got=string, expected=int. Line 1 Column 8"}},
+		{`let f = fn(a: string) { a }; f(1)`, []string{"Type mismatch for argument 1 of f, Got=int, expected=string. Line 1 Column 32"}},
+		{`fn(a: int) { a + "b" }`, []string{"Invalid operands to +, Got=int + string. Line 1 Column 16"}},
+		{`fn() -> string { 1 }`, []string{"Type mismatch for result of function, Got=int, expected=string. Line 1 Column 9"}},
+	}
+
+	for _, tt := range tests {
+		_, _, errs := check(t, tt.input)
+
+		var got []string
+		for _, err := range errs {
+			got = append(got, err.Error())
+		}
+
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("Wrong errors for %q.\nGot=%q\nexpected=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+// Dynamic code which evaluates without errors is not reported
+func TestCheckDynamicPrograms(t *testing.T) {
+	tests := []string{
+		`let x = 1; let x = "a"; x + "b"`,
+		`let f = fn(x) { if (x > 0) { return "positive" }; x }; f(1); f(-1)`,
+		`let map = fn(arr, f) { if (len(arr) == 0) { [] } else { push(map(rest(arr), f), f(first(arr))) } }; map([1, 2], fn(x) { x * 2 })`,
+		`let h = {"a": 1, "b": "two"}; h["a"]; h.b`,
+		`let apply = fn(f, x) { f(x) }; apply(fn(x) { x + 1 }, 1); apply(fn(s) { s + "!" }, "a")`,
+		`try { throw "bad" } catch (e) { e + 1 }`,
+		`let config = json_parse("{}"); config.name + config.age`,
+		`let f = fn(_unused) { 1 }; f(f)`,
+		`puts(1, "a", [true]); print()`,
+		`let f = fn(x) { x }; let g = f; let a = g(1); let b = g("a");`,
+		`let a = fn() { [id(1), id("s")] }; let id = fn(x) { x }; a()`,
+		`let outer = fn() { let a = fn() { id(1) + len(id("s")) }; let id = fn(x) { x }; a() }; outer()`,
+	}
+
+	for _, input := range tests {
+		if _, _, errs := check(t, input); len(errs) > 0 {
+			t.Errorf("Unexpected errors for %q: %v", input, errs)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package typecheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Static type of a Monkey value
+type Type interface {
+	String() string
+}
+
+/*** Basic Types ***/
+
+type Basic struct {
+	Name string
+}
+
+func (b *Basic) String() string { return b.Name }
+
+var (
+	Int    = &Basic{Name: "int"}
+	Bool   = &Basic{Name: "bool"}
+	String = &Basic{Name: "string"}
+	Null   = &Basic{Name: "null"}
+
+	// Value of a dynamic spot, such as the result of a builtin, which is compatible with every type
+	Any = &Basic{Name: "any"}
+
+	// Value of a block which never completes as it returns or throws, compatible with every type
+	Never = &Basic{Name: "never"}
+)
+
+/*** Compound Types ***/
+
+type Array struct {
+	Elem Type
+}
+
+func (a *Array) String() string { return "[" + a.Elem.String() + "]" }
+
+type Hash struct {
+	Key   Type
+	Value Type
+}
+
+func (h *Hash) String() string { return "{" + h.Key.String() + ": " + h.Value.String() + "}" }
+
+type Function struct {
+	Params []Type
+	Result Type
+}
+
+func (f *Function) String() string {
+	params := make([]string, len(f.Params))
+	for idx, param := range f.Params {
+		params[idx] = param.String()
+	}
+
+	return fmt.Sprintf("fn(%s) -> %s", strings.Join(params, ", "), f.Result)
+}
+
+// Value of any one of Types, from a spot where the value is only known at runtime like the branches of an if
+type Union struct {
+	Types []Type
+}
+
+func (u *Union) String() string {
+	names := make([]string, len(u.Types))
+	for idx, member := range u.Types {
+		names[idx] = member.String()
+		if _, ok := resolve(member).(*Function); ok {
+			names[idx] = "(" + names[idx] + ")"
+		}
+	}
+
+	return strings.Join(names, " | ")
+}
+
+// Union of types, flattened and without duplicates. A single type is not wrapped
+func newUnion(types ...Type) Type {
+	seen := map[string]bool{}
+	var members []Type
+
+	var add func(t Type)
+	add = func(t Type) {
+		t = resolve(t)
+		if union, ok := t.(*Union); ok {
+			for _, member := range union.Types {
+				add(member)
+			}
+			return
+		}
+
+		if t == Never || seen[t.String()] {
+			return
+		}
+		seen[t.String()] = true
+		members = append(members, t)
+	}
+
+	for _, t := range types {
+		add(t)
+	}
+
+	switch len(members) {
+	case 0:
+		return Never
+	case 1:
+		return members[0]
+	}
+
+	sort.SliceStable(members, func(i, j int) bool { return members[i].String() < members[j].String() })
+	return &Union{Types: members}
+}
+
+/*** Type Variables ***/
+
+// Unknown type to be found by inference. Once bound it stands for the type it is bound to
+type Var struct {
+	id  int
+	ref Type
+
+	addable   bool // Operand of +, so an int or a string
+	equatable bool // Operand of == or !=, so anything but a string
+}
+
+func (v *Var) String() string {
+	if v.ref != nil {
+		return v.ref.String()
+	}
+
+	return fmt.Sprintf("t%d", v.id)
+}
+
+// Follow bound variables to the type they stand for
+func resolve(t Type) Type {
+	for {
+		v, ok := t.(*Var)
+		if !ok || v.ref == nil {
+			return t
+		}
+		t = v.ref
+	}
+}
+
+// Type whose variables may be instantiated differently at each use, like the type of a function bound by a let
+type scheme struct {
+	vars []*Var
+	t    Type
+}
@@ -0,0 +1,192 @@
+package typecheck
+
+// Previous state of a variable changed by unification, restored when a trial unification fails
+type trailEntry struct {
+	v         *Var
+	ref       Type
+	addable   bool
+	equatable bool
+}
+
+func (c *checker) record(v *Var) {
+	c.trail = append(c.trail, trailEntry{v: v, ref: v.ref, addable: v.addable, equatable: v.equatable})
+}
+
+// Unify a and b, leaving every variable as it was when they do not unify
+func (c *checker) unify(a, b Type) bool {
+	mark := len(c.trail)
+	if c.unifyTrail(a, b) {
+		return true
+	}
+
+	for len(c.trail) > mark {
+		entry := c.trail[len(c.trail)-1]
+		entry.v.ref, entry.v.addable, entry.v.equatable = entry.ref, entry.addable, entry.equatable
+		c.trail = c.trail[:len(c.trail)-1]
+	}
+
+	return false
+}
+
+func (c *checker) unifyTrail(a, b Type) bool {
+	a, b = resolve(a), resolve(b)
+	if a == b || a == Any || b == Any || a == Never || b == Never {
+		return true
+	}
+
+	if v, ok := a.(*Var); ok {
+		return c.bind(v, b)
+	}
+	if v, ok := b.(*Var); ok {
+		return c.bind(v, a)
+	}
+
+	// A union is compatible with the types of any of its members
+	if union, ok := a.(*Union); ok {
+		for _, member := range union.Types {
+			if c.unify(member, b) {
+				return true
+			}
+		}
+		return false
+	}
+	if _, ok := b.(*Union); ok {
+		return c.unifyTrail(b, a)
+	}
+
+	switch a := a.(type) {
+	case *Array:
+		if b, ok := b.(*Array); ok {
+			return c.unifyTrail(a.Elem, b.Elem)
+		}
+
+	case *Hash:
+		if b, ok := b.(*Hash); ok {
+			return c.unifyTrail(a.Key, b.Key) && c.unifyTrail(a.Value, b.Value)
+		}
+
+	case *Function:
+		b, ok := b.(*Function)
+		if !ok || len(a.Params) != len(b.Params) {
+			return false
+		}
+
+		for idx := range a.Params {
+			if !c.unifyTrail(a.Params[idx], b.Params[idx]) {
+				return false
+			}
+		}
+		return c.unifyTrail(a.Result, b.Result)
+	}
+
+	return false
+}
+
+// Bind the unbound variable v to t, as long as t satisfies the constraints of v
+func (c *checker) bind(v *Var, t Type) bool {
+	if other, ok := t.(*Var); ok {
+		c.record(other)
+		other.addable = other.addable || v.addable
+		other.equatable = other.equatable || v.equatable
+
+		c.record(v)
+		v.ref = other
+		return true
+	}
+
+	if occurs(v, t) {
+		return false
+	}
+
+	if v.addable && !c.addable(t) || v.equatable && !c.equatable(t) {
+		return false
+	}
+
+	c.record(v)
+	v.ref = t
+	return true
+}
+
+// Whether t may be an operand of +
+func (c *checker) addable(t Type) bool {
+	switch t := resolve(t).(type) {
+	case *Var:
+		c.record(t)
+		t.addable = true
+		return true
+
+	case *Union:
+		for _, member := range t.Types {
+			if c.addable(member) {
+				return true
+			}
+		}
+		return false
+	}
+
+	t = resolve(t)
+	return t == Int || t == String || t == Any || t == Never
+}
+
+// Whether t may be an operand of == and !=, which strings do not support
+func (c *checker) equatable(t Type) bool {
+	switch t := resolve(t).(type) {
+	case *Var:
+		c.record(t)
+		t.equatable = true
+		return true
+
+	case *Union:
+		for _, member := range t.Types {
+			if c.equatable(member) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return resolve(t) != String
+}
+
+// Whether the variable v appears within t, binding v to t would make an infinite type
+func occurs(v *Var, t Type) bool {
+	switch t := resolve(t).(type) {
+	case *Var:
+		return t == v
+	case *Array:
+		return occurs(v, t.Elem)
+	case *Hash:
+		return occurs(v, t.Key) || occurs(v, t.Value)
+	case *Union:
+		for _, member := range t.Types {
+			if occurs(v, member) {
+				return true
+			}
+		}
+	case *Function:
+		for _, param := range t.Params {
+			if occurs(v, param) {
+				return true
+			}
+		}
+		return occurs(v, t.Result)
+	}
+
+	return false
+}
+
+// Type of a spot which may hold a value of a or b: their unification when they unify, otherwise their union
+func (c *checker) join(a, b Type) Type {
+	if resolve(a) == Never {
+		return b
+	}
+	if resolve(b) == Never {
+		return a
+	}
+
+	if c.unify(a, b) {
+		return a
+	}
+
+	return newUnion(a, b)
+}